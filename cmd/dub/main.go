@@ -0,0 +1,122 @@
+// Command dub runs the dubbing pipeline on a local video file.
+//
+//	dub -to de [-from en] [-ref VOICE.wav] [-fake] IN.mp4 OUT.mp4
+//
+// The voice is cloned from -ref, or from the video's own audio. Transcription
+// and translation use the providers configured in the environment (see the
+// README); -fake swaps them and the voice for offline stand-ins, so only
+// ffmpeg is needed: the transcript is STT_FAKE_TEXT (or a placeholder) as a
+// single segment, translations are tagged rather than translated and speech
+// is a tone.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/dubbing"
+	"github.com/henrik392/youtube-voice-go/internal/stt"
+	"github.com/henrik392/youtube-voice-go/internal/zonos"
+)
+
+func main() {
+	to := flag.String("to", "", "language to dub into, e.g. de")
+	from := flag.String("from", "", "language spoken in the video, detected if empty")
+	ref := flag.String("ref", "", "reference audio for the voice, the video's own audio if empty")
+	fake := flag.Bool("fake", false, "use offline fakes instead of the configured providers")
+	maxTempo := flag.Float64("max-tempo", dubbing.DefaultMaxTempo, "largest speed-up used to fit a segment")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: dub -to LANG [-from LANG] [-ref VOICE.wav] [-fake] IN OUT")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 || *to == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(context.Background(), flag.Arg(0), flag.Arg(1), *from, *to, *ref, *fake, *maxTempo); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, in, out, from, to, ref string, fake bool, maxTempo float64) error {
+	workDir, err := os.MkdirTemp("", "dub_*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	pipeline := &dubbing.Pipeline{Media: dubbing.FFmpeg{}, MaxTempo: maxTempo}
+	var voice dubbing.Synthesizer
+	if fake {
+		text := os.Getenv("STT_FAKE_TEXT")
+		if text == "" {
+			text = "This is a placeholder transcript."
+		}
+		pipeline.Transcriber = &stt.Fake{Text: text}
+		pipeline.Translator = &dubbing.FakeTranslator{}
+		voice = &dubbing.FakeSynthesizer{}
+	} else {
+		if pipeline.Transcriber, err = stt.Default(); err != nil {
+			return err
+		}
+		if pipeline.Translator, err = dubbing.DefaultTranslator(); err != nil {
+			return err
+		}
+		if voice, err = cloneVoice(ctx, in, ref, workDir); err != nil {
+			return err
+		}
+	}
+
+	pipeline.Progress = func(p dubbing.Progress) {
+		if p.Total > 0 {
+			fmt.Fprintf(os.Stderr, "%s %d/%d\n", p.Stage, p.Done, p.Total)
+		} else {
+			fmt.Fprintln(os.Stderr, p.Stage)
+		}
+	}
+
+	result, err := pipeline.Run(ctx, dubbing.Job{
+		VideoPath:      in,
+		OutputPath:     out,
+		WorkDir:        workDir,
+		SourceLanguage: from,
+		TargetLanguage: to,
+		Voice:          voice,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The track only lives in the work dir
+	result.TrackPath = ""
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// cloneVoice uploads the reference to fal and speaks with it
+func cloneVoice(ctx context.Context, in, ref, workDir string) (dubbing.Synthesizer, error) {
+	if ref == "" {
+		ref = filepath.Join(workDir, "reference"+audio.CanonicalExt)
+		if err := audio.Normalize(ctx, in, ref); err != nil {
+			return nil, err
+		}
+	}
+
+	client := zonos.NewClient(os.Getenv("FAL_KEY"))
+	reference, err := client.UploadReference(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return dubbing.SynthesizerFunc(func(ctx context.Context, text string) ([]byte, error) {
+		return client.VoiceCloneWithReference(ctx, text, *reference)
+	}), nil
+}
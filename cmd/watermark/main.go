@@ -0,0 +1,93 @@
+// Command watermark checks audio files for the watermark embedded in
+// generated speech, or embeds one by hand.
+//
+//	watermark detect FILE...
+//	watermark embed -id GENERATION_ID IN.wav OUT.wav
+//
+// detect accepts anything ffmpeg can decode and exits with status 1 if any
+// file carries no watermark. embed takes 16-bit PCM WAV at 44.1 kHz.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/watermark"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "detect":
+		os.Exit(detect(os.Args[2:]))
+	case "embed":
+		os.Exit(embed(os.Args[2:]))
+	default:
+		usage()
+	}
+}
+
+func detect(paths []string) int {
+	if len(paths) == 0 {
+		usage()
+	}
+
+	status := 0
+	for _, path := range paths {
+		samples, err := audio.DecodeSamples(context.Background(), path, watermark.SampleRate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return 2
+		}
+		result := watermark.Detect(samples)
+		fmt.Printf("%s: %s\n", path, result)
+		if !result.Found {
+			status = 1
+		}
+	}
+	return status
+}
+
+func embed(args []string) int {
+	flags := flag.NewFlagSet("embed", flag.ExitOnError)
+	id := flags.String("id", "", "generation ID to embed (a UUID)")
+	strength := flags.Float64("strength", watermark.DefaultStrength, "relative change to bin magnitudes")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		usage()
+	}
+
+	payload, err := watermark.PayloadFromID(*id)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	marked, err := watermark.EmbedWAV(data, payload, *strength)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flags.Arg(0), err)
+		return 2
+	}
+	if err := os.WriteFile(flags.Arg(1), marked, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Printf("%s: embedded %s\n", flags.Arg(1), payload)
+	return 0
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: watermark detect FILE...")
+	fmt.Fprintln(os.Stderr, "       watermark embed -id GENERATION_ID IN.wav OUT.wav")
+	os.Exit(2)
+}
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/henrik392/youtube-voice-go/internal/cookies"
+	"github.com/henrik392/youtube-voice-go/internal/youtube"
+)
+
+const maxCookiesSize = 1 << 20 // 1MB
+
+// CookieStatusHandler lists the cookie jar of every platform with expiry warnings
+func CookieStatusHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, cookies.Default().Statuses(youtube.Platforms))
+}
+
+// UploadCookiesHandler validates and stores a Netscape cookie file for one platform.
+// The file is sent either as the raw request body or as the "cookies" form file.
+func UploadCookiesHandler(w http.ResponseWriter, r *http.Request) {
+	platform := chi.URLParam(r, "platform")
+	if !slices.Contains(youtube.Platforms, platform) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown platform: " + platform})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCookiesSize)
+
+	var data []byte
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, ferr := r.FormFile("cookies")
+		if ferr != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "no cookies file provided"})
+			return
+		}
+		defer file.Close()
+		data, err = io.ReadAll(file)
+	} else {
+		data, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "cookies file too large"})
+		return
+	}
+
+	status, err := cookies.Default().Set(platform, data)
+	if err != nil {
+		log.Printf("Rejected %s cookies: %v", platform, err)
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// DeleteCookiesHandler removes an uploaded cookie file, falling back to the environment
+func DeleteCookiesHandler(w http.ResponseWriter, r *http.Request) {
+	platform := chi.URLParam(r, "platform")
+	if !slices.Contains(youtube.Platforms, platform) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown platform: " + platform})
+		return
+	}
+
+	if err := cookies.Default().Delete(platform); err != nil {
+		log.Printf("Failed to delete %s cookies: %v", platform, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to delete cookies"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+// drawWaveform draws waveform peaks, as served by /generations/{id}/peaks.json
+// and /references/{id}/peaks.json, onto a canvas at its displayed size.
+// options.progress (seconds) colours the part already played, and
+// options.ranges ([start, end] pairs in seconds) shades parts of the audio.
+window.drawWaveform = function (canvas, peaks, options) {
+	options = options || {};
+	const ratio = window.devicePixelRatio || 1;
+	const width = canvas.clientWidth;
+	const height = canvas.clientHeight;
+	canvas.width = width * ratio;
+	canvas.height = height * ratio;
+	const ctx = canvas.getContext('2d');
+	ctx.scale(ratio, ratio);
+	ctx.clearRect(0, 0, width, height);
+	if (!peaks || peaks.max.length === 0 || !peaks.duration) return;
+
+	const toX = seconds => seconds / peaks.duration * width;
+	ctx.fillStyle = 'rgba(99, 102, 241, 0.15)';
+	(options.ranges || []).forEach(([start, end]) => {
+		ctx.fillRect(toX(start), 0, toX(end) - toX(start), height);
+	});
+
+	// Each pixel column shows the extremes of the buckets under it
+	const played = toX(options.progress || 0);
+	const middle = height / 2;
+	const buckets = peaks.max.length;
+	for (let x = 0; x < width; x++) {
+		const from = Math.floor(x / width * buckets);
+		const to = Math.max(from + 1, Math.floor((x + 1) / width * buckets));
+		let low = 0, high = 0;
+		for (let i = from; i < to && i < buckets; i++) {
+			low = Math.min(low, peaks.min[i]);
+			high = Math.max(high, peaks.max[i]);
+		}
+		ctx.fillStyle = x < played ? '#4f46e5' : '#a5b4fc';
+		ctx.fillRect(x, middle - high * middle, 1, Math.max(1, (high - low) * middle));
+	}
+};
+
+// rangePicker is the Alpine state of the RangePicker component: ranges of a
+// recording, picked by dragging across its waveform, that together last at
+// most maxSeconds. It starts with the first maxSeconds picked. A click
+// without dragging dispatches a seek event with the time clicked.
+window.rangePicker = function (maxSeconds) {
+	return {
+		maxSeconds: maxSeconds,
+		peaks: null,
+		ranges: [],
+		drag: null,
+
+		async load(url) {
+			this.peaks = null;
+			this.ranges = [];
+			if (url) {
+				try {
+					const response = await fetch(url);
+					if (response.ok) this.peaks = await response.json();
+				} catch (err) {}
+			}
+			this.reset();
+		},
+
+		reset() {
+			this.ranges = this.peaks ? [[0, Math.min(this.maxSeconds, this.peaks.duration)]] : [];
+			// The canvas is only shown, and so sized, once there are peaks
+			this.$nextTick(() => this.draw());
+		},
+
+		get total() {
+			return this.ranges.reduce((sum, [start, end]) => sum + end - start, 0);
+		},
+
+		// value is what is submitted as reference-ranges
+		get value() {
+			return this.ranges.map(([start, end]) => start.toFixed(2) + '-' + end.toFixed(2)).join(',');
+		},
+
+		secondsAt(event) {
+			const canvas = this.$refs.wave;
+			const fraction = Math.min(Math.max(event.offsetX / canvas.clientWidth, 0), 1);
+			return fraction * this.peaks.duration;
+		},
+
+		start(event) {
+			if (!this.peaks) return;
+			event.target.setPointerCapture(event.pointerId);
+			const at = this.secondsAt(event);
+			this.drag = { from: at, to: at };
+		},
+
+		move(event) {
+			if (!this.drag) return;
+			this.drag.to = this.secondsAt(event);
+			this.draw();
+		},
+
+		end() {
+			if (!this.drag) return;
+			const from = Math.min(this.drag.from, this.drag.to);
+			const to = Math.max(this.drag.from, this.drag.to);
+			this.drag = null;
+			if (to - from < 0.5) {
+				this.$dispatch('seek', from);
+			} else {
+				this.add(from, to);
+			}
+			this.draw();
+		},
+
+		// add merges a new range with any it overlaps, trimming it to what is
+		// left of maxSeconds
+		add(from, to) {
+			const kept = [];
+			for (const [start, end] of this.ranges) {
+				if (end < from || start > to) {
+					kept.push([start, end]);
+				} else {
+					from = Math.min(from, start);
+					to = Math.max(to, end);
+				}
+			}
+			const left = this.maxSeconds - kept.reduce((sum, [start, end]) => sum + end - start, 0);
+			if (left < 0.5) return;
+			kept.push([from, Math.min(to, from + left)]);
+			this.ranges = kept.sort((a, b) => a[0] - b[0]);
+		},
+
+		remove(index) {
+			this.ranges.splice(index, 1);
+			this.draw();
+		},
+
+		draw() {
+			const ranges = this.ranges.slice();
+			if (this.drag) ranges.push([Math.min(this.drag.from, this.drag.to), Math.max(this.drag.from, this.drag.to)]);
+			drawWaveform(this.$refs.wave, this.peaks, { ranges: ranges });
+		},
+	};
+};
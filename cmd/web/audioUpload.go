@@ -0,0 +1,197 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+)
+
+const (
+	downloadsDir  = "downloads"
+	maxUploadSize = 50 << 20 // 50MB
+)
+
+// storeUploadedAudio checks an uploaded file by its content rather than its
+// name, then converts it to the canonical WAV intermediate as
+// downloads/<fileID>.wav. Problems with the file come back as *audio.RejectionError.
+func storeUploadedAudio(ctx context.Context, fileID string, file io.Reader) (string, error) {
+	header := make([]byte, audio.SniffLen)
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", uploadReadError(err)
+	}
+	header = header[:n]
+
+	// Reject obvious non-audio before writing anything to disk
+	if audio.Sniff(header) == audio.FormatUnknown {
+		return "", audio.Reject(audio.ReasonUnknownFormat, "The file is not a recognised audio format. Please upload MP3, WAV, M4A, OGG, or FLAC files")
+	}
+
+	if err := os.MkdirAll(downloadsDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create downloads directory: %w", err)
+	}
+
+	rawPath := filepath.Join(downloadsDir, fileID+".upload")
+	defer os.Remove(rawPath)
+
+	dst, err := os.Create(rawPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	_, err = io.Copy(dst, io.MultiReader(bytes.NewReader(header), file))
+	dst.Close()
+	if err != nil {
+		return "", uploadReadError(err)
+	}
+
+	info, err := audio.Validate(ctx, rawPath, header, audio.UploadLimits)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Accepted upload %s: %s/%s, %s, %d ch, %d Hz", fileID, info.Format, info.Codec, info.Duration, info.Channels, info.SampleRate)
+
+	outputPath := filepath.Join(downloadsDir, fileID+audio.CanonicalExt)
+	if err := audio.Normalize(ctx, rawPath, outputPath); err != nil {
+		os.Remove(outputPath)
+		return "", err
+	}
+
+	return outputPath, nil
+}
+
+// storeReference stores an uploaded or recorded reference like
+// storeUploadedAudio, then applies any requested enhancement and runs the
+// quality gate. A reference that fails the gate is removed again and the
+// report comes back alongside the *audio.RejectionError.
+func storeReference(ctx context.Context, fileID string, file io.Reader, enhance audio.Enhancement) (string, *audio.Report, error) {
+	path, err := storeUploadedAudio(ctx, fileID, file)
+	if err != nil {
+		return "", nil, err
+	}
+
+	report, err := checkReferenceQuality(ctx, fileID, path, enhance)
+	if err != nil {
+		os.Remove(path)
+		return "", report, err
+	}
+	return path, report, nil
+}
+
+// checkReferenceQuality enhances path in place if asked to, measures the
+// result and saves the report next to it for /api/audio-quality.
+func checkReferenceQuality(ctx context.Context, fileID, path string, enhance audio.Enhancement) (*audio.Report, error) {
+	if enhance.Enabled() {
+		enhancedPath := filepath.Join(downloadsDir, fileID+".enhanced"+audio.CanonicalExt)
+		if err := audio.Enhance(ctx, path, enhancedPath, enhance); err != nil {
+			os.Remove(enhancedPath)
+			return nil, err
+		}
+		if err := os.Rename(enhancedPath, path); err != nil {
+			return nil, fmt.Errorf("failed to replace reference with enhanced audio: %w", err)
+		}
+	}
+
+	analysis, err := audio.AnalyzeFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	report := audio.Assess(*analysis, audio.ReferenceQuality)
+	log.Printf("Quality of %s: speech %.1f dBFS, noise floor %.1f dBFS, SNR %.1f dB, %.1f%% silent, %.2f%% clipped, passed=%t",
+		fileID, analysis.SpeechLevel, analysis.NoiseFloor, analysis.SNR, analysis.SilenceRatio*100, analysis.ClippedRatio*100, report.Passed)
+
+	if rejection := report.Rejection(); rejection != nil {
+		return report, rejection
+	}
+
+	data, err := json.Marshal(report)
+	if err == nil {
+		err = os.WriteFile(qualityReportPath(fileID), data, 0644)
+	}
+	if err != nil {
+		log.Printf("Failed to save quality report for %s: %v", fileID, err)
+	}
+	return report, nil
+}
+
+func qualityReportPath(fileID string) string {
+	return filepath.Join(downloadsDir, fileID+".quality.json")
+}
+
+// enhancementFrom reads the auto-gain and noise-reduction switches from
+// form values, query parameters or upload metadata.
+func enhancementFrom(get func(string) string) audio.Enhancement {
+	return audio.Enhancement{
+		AutoGain:       formBool(get("auto-gain")),
+		NoiseReduction: formBool(get("noise-reduction")),
+	}
+}
+
+// formBool accepts the ways a checkbox or flag is usually sent
+func formBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "1", "true", "on", "yes":
+		return true
+	}
+	return false
+}
+
+// uploadReadError turns a body that exceeded http.MaxBytesReader into a rejection
+func uploadReadError(err error) error {
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		return audio.Reject(audio.ReasonTooLarge, "The file is larger than %d MB", maxBytes.Limit>>20)
+	}
+	return fmt.Errorf("failed to read upload: %w", err)
+}
+
+// rejectionStatus maps a rejection reason to the HTTP status returned to the client
+func rejectionStatus(reason audio.Reason) int {
+	switch reason {
+	case audio.ReasonTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case audio.ReasonUnknownFormat, audio.ReasonUnsupportedCodec:
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// writeUploadError answers an upload with a typed rejection or a generic server error
+func writeUploadError(w http.ResponseWriter, err error) {
+	writeReferenceError(w, nil, err)
+}
+
+// writeReferenceError is writeUploadError with the quality report, if the gate got that far
+func writeReferenceError(w http.ResponseWriter, report *audio.Report, err error) {
+	if rejection, ok := audio.AsRejection(err); ok {
+		response := map[string]interface{}{
+			"success": false,
+			"reason":  rejection.Reason,
+			"error":   rejection.Detail,
+		}
+		if report != nil {
+			response["quality"] = report
+		}
+		writeJSON(w, rejectionStatus(rejection.Reason), response)
+		return
+	}
+	if status := retryStatus(w, err, 0); status == http.StatusServiceUnavailable {
+		http.Error(w, "Server busy, please retry", status)
+		return
+	}
+
+	log.Printf("Failed to store upload: %v", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+		"success": false,
+		"error":   "Server error",
+	})
+}
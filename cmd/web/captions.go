@@ -0,0 +1,201 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/captions"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/storage"
+)
+
+const (
+	// captionsParam marks generations that have captions, and is the form
+	// field asking for them
+	captionsParam = "captions"
+
+	// captionAnalysisRate is the rate audio is decoded at to find the speech in it
+	captionAnalysisRate = 16000
+
+	// wordTimingsParam records where a generation's word timings came from:
+	// the provider, or estimated from the audio
+	wordTimingsParam     = "word_timings"
+	wordTimingsProvider  = "provider"
+	wordTimingsEstimated = "estimated"
+)
+
+var captionFormats = []string{captions.FormatSRT, captions.FormatVTT}
+
+// captionsPath is where captions of the audio at audioPath are kept: next to it
+func captionsPath(audioPath, format string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + "." + format
+}
+
+// wordsPath is where the word timings of the audio at audioPath are kept
+func wordsPath(audioPath string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".words.json"
+}
+
+// alignGeneration saves gen's word timings next to its audio, and its
+// captions if it asked for them. words are the provider's timings; without
+// them they are estimated from the chunks of speech in the audio. Timings
+// are best effort, so failures are logged and nil words returned.
+func alignGeneration(ctx context.Context, gen *database.Generation, words []captions.Word) []captions.Word {
+	source := wordTimingsProvider
+	if words == nil {
+		samples, err := audio.DecodeSamples(ctx, gen.AudioPath, captionAnalysisRate)
+		if err != nil {
+			log.Printf("Failed to analyse generation %s for word timings: %v", gen.ID, err)
+			delete(gen.Params, captionsParam)
+			return nil
+		}
+		words = captions.Estimate(gen.Text, captions.SpeechSpans(samples, captionAnalysisRate))
+		source = wordTimingsEstimated
+	}
+
+	data, err := json.Marshal(words)
+	if err == nil {
+		err = os.WriteFile(wordsPath(gen.AudioPath), data, 0644)
+	}
+	if err != nil {
+		log.Printf("Failed to write word timings for generation %s: %v", gen.ID, err)
+	} else {
+		gen.Params[wordTimingsParam] = source
+	}
+
+	if gen.Params[captionsParam] == "true" {
+		if err := writeCaptions(gen, words); err != nil {
+			log.Printf("Failed to write captions for generation %s: %v", gen.ID, err)
+			delete(gen.Params, captionsParam)
+		}
+	}
+	return words
+}
+
+// retimeWords moves provider word timings of audio rendered with the effects
+// from to where they fall once it is rendered with to instead. The provider's
+// own audio was rendered with no effects.
+func retimeWords(words []captions.Word, from, to audio.Effects) []captions.Word {
+	if words == nil {
+		return nil
+	}
+	return captions.Scale(words, from.Speed()/to.Speed())
+}
+
+// writeCaptions writes SRT and VTT captions for gen next to its audio
+func writeCaptions(gen *database.Generation, words []captions.Word) error {
+	cues := captions.Build(words, captions.DefaultRules)
+	for _, format := range captionFormats {
+		data, err := captions.Format(cues, format)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(captionsPath(gen.AudioPath, format), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeTimings deletes whatever captions and word timings exist for the
+// audio at audioPath
+func removeTimings(audioPath string) {
+	for _, format := range captionFormats {
+		os.Remove(captionsPath(audioPath, format))
+	}
+	os.Remove(wordsPath(audioPath))
+}
+
+// captionsURL is where the player loads a generation's captions from, or
+// empty if it has none
+func captionsURL(gen *database.Generation) string {
+	if gen.Params[captionsParam] != "true" {
+		return ""
+	}
+	return "/generations/" + gen.ID + "/captions." + captions.FormatVTT
+}
+
+// GenerationCaptionsHandler serves a generation's captions as SRT or VTT,
+// as a download with ?download=1
+func GenerationCaptionsHandler(w http.ResponseWriter, r *http.Request) {
+	format := chi.URLParam(r, "format")
+	contentType, ok := captions.ContentTypes[format]
+	if !ok {
+		http.Error(w, "Unknown caption format", http.StatusNotFound)
+		return
+	}
+
+	gen, err := loadGeneration(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+	file, info, err := storage.Default().Open(captionsPath(gen.AudioPath, format))
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "This generation has no captions", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to open captions of %s: %v", gen.ID, err)
+		http.Error(w, "Failed to open captions", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	name := fmt.Sprintf("speech_%s.%s", gen.ID, format)
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	if r.URL.Query().Get("download") != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	}
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// wordsView is the JSON form of a generation's word timings
+type wordsView struct {
+	Source string          `json:"source"`
+	Words  []captions.Word `json:"words"`
+}
+
+// GetGenerationWordsHandler serves a generation's word timings as JSON
+func GetGenerationWordsHandler(w http.ResponseWriter, r *http.Request) {
+	gen, err := loadGeneration(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+	words, err := readWords(gen)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "This generation has no word timings", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to read word timings of %s: %v", gen.ID, err)
+		http.Error(w, "Failed to read word timings", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, wordsView{Source: gen.Params[wordTimingsParam], Words: words})
+}
+
+// readWords loads the word timings saved for gen
+func readWords(gen *database.Generation) ([]captions.Word, error) {
+	file, _, err := storage.Default().Open(wordsPath(gen.AudioPath))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []captions.Word
+	if err := json.NewDecoder(file).Decode(&words); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
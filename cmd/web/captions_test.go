@@ -0,0 +1,54 @@
+package web
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/captions"
+)
+
+// alignment is shaped like ElevenLabs' with-timestamps response for "Hi there"
+func alignment() ([]string, []float64, []float64) {
+	characters := strings.Split("Hi there", "")
+	starts := []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8}
+	ends := []float64{0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+	return characters, starts, ends
+}
+
+func TestProviderWordsFollowTempo(t *testing.T) {
+	words := captions.FromCharacters(alignment())
+	want := []captions.Word{{Text: "Hi", Start: 0.1, End: 0.3}, {Text: "there", Start: 0.4, End: 0.9}}
+	assertWords(t, "provider", words, want)
+
+	// Generated at double speed, as finishGeneration does
+	fast := retimeWords(words, audio.Effects{}, audio.Effects{Tempo: 2})
+	assertWords(t, "tempo 2", fast, []captions.Word{{Text: "Hi", Start: 0.05, End: 0.15}, {Text: "there", Start: 0.2, End: 0.45}})
+
+	// Re-rendered at half speed from the saved timings, as the effects handler does
+	slow := retimeWords(fast, audio.Effects{Tempo: 2}, audio.Effects{Tempo: 0.5})
+	assertWords(t, "tempo 0.5", slow, []captions.Word{{Text: "Hi", Start: 0.2, End: 0.6}, {Text: "there", Start: 0.8, End: 1.8}})
+
+	// Effects without a tempo leave the timings alone
+	plain := retimeWords(slow, audio.Effects{Tempo: 0.5}, audio.Effects{Pitch: 3, Reverb: 0.5})
+	assertWords(t, "no tempo", plain, want)
+}
+
+func TestRetimeWordsWithoutProviderTimings(t *testing.T) {
+	if words := retimeWords(nil, audio.Effects{}, audio.Effects{Tempo: 1.5}); words != nil {
+		t.Errorf("retimeWords(nil) = %v, want nil so the timings are estimated", words)
+	}
+}
+
+func assertWords(t *testing.T, name string, got, want []captions.Word) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d words %v, want %v", name, len(got), got, want)
+	}
+	for i := range want {
+		if got[i].Text != want[i].Text || math.Abs(got[i].Start-want[i].Start) > 1e-9 || math.Abs(got[i].End-want[i].End) > 1e-9 {
+			t.Errorf("%s: word %d = %+v, want %+v", name, i, got[i], want[i])
+		}
+	}
+}
@@ -6,15 +6,17 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
 
-	"github.com/henrik392/youtube-voice-go/cmd/web/components"
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/henrik392/youtube-voice-go/cmd/web/components"
+	"github.com/henrik392/youtube-voice-go/internal/platformstatus"
+	"github.com/henrik392/youtube-voice-go/internal/youtube"
 )
 
 // URLInputHandler serves the URL input component
 func URLInputHandler(w http.ResponseWriter, r *http.Request) {
-	component := components.URLInput(false, "")
+	component := components.URLInput(false, "", platformstatus.Default().Statuses(youtube.Platforms))
 	err := component.Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -24,7 +26,8 @@ func URLInputHandler(w http.ResponseWriter, r *http.Request) {
 
 // FileUploadHandler serves the file upload component
 func FileUploadHandler(w http.ResponseWriter, r *http.Request) {
-	component := components.FileUpload()
+	// Performances to convert are used whole, references can be cropped
+	component := components.FileUpload(r.URL.Query().Get("for") != "performance")
 	err := component.Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -42,127 +45,96 @@ func MicrophoneHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// UploadAudioHandler handles audio file uploads
+// UploadAudioHandler handles audio file uploads. The file is validated by its
+// content and stored in the canonical format; rejections carry a typed reason.
 func UploadAudioHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Parse multipart form with max memory of 50MB
-	err := r.ParseMultipartForm(50 << 20) // 50MB
-	if err != nil {
-		http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
-		return
-	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 
-	// Get file from form
-	file, header, err := r.FormFile("audio-file")
+	// Stream the multipart body instead of buffering it with ParseMultipartForm
+	reader, err := r.MultipartReader()
 	if err != nil {
-		http.Error(w, "No file provided", http.StatusBadRequest)
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "Expected a multipart form"})
 		return
 	}
-	defer file.Close()
 
-	// Validate file type
-	contentType := header.Header.Get("Content-Type")
-	validTypes := []string{
-		"audio/mpeg", "audio/mp3", "audio/wav", "audio/wave",
-		"audio/mp4", "audio/m4a", "audio/ogg", "audio/flac",
-	}
-	
-	isValid := false
-	for _, validType := range validTypes {
-		if strings.Contains(contentType, validType) || strings.Contains(strings.ToLower(header.Filename), strings.TrimPrefix(validType, "audio/")) {
-			isValid = true
-			break
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			if err == io.EOF {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "No file provided"})
+			} else {
+				writeUploadError(w, uploadReadError(err))
+			}
+			return
+		}
+		if part.FormName() != "audio-file" {
+			part.Close()
+			continue
 		}
-	}
-
-	if !isValid {
-		http.Error(w, "Invalid file type. Please upload MP3, WAV, M4A, OGG, or FLAC files", http.StatusBadRequest)
-		return
-	}
-
-	// Generate unique filename
-	fileID := uuid.New().String()
-	ext := filepath.Ext(header.Filename)
-	filename := fileID + ext
-
-	// Ensure downloads directory exists
-	downloadsDir := "downloads"
-	if err := os.MkdirAll(downloadsDir, 0755); err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
-		log.Printf("Failed to create downloads directory: %v", err)
-		return
-	}
 
-	// Create file
-	filePath := filepath.Join(downloadsDir, filename)
-	dst, err := os.Create(filePath)
-	if err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
-		log.Printf("Failed to create file: %v", err)
-		return
-	}
-	defer dst.Close()
+		fileID := uuid.New().String()
+		filePath, report, err := storeReference(r.Context(), fileID, part, enhancementFrom(r.URL.Query().Get))
+		part.Close()
+		if err != nil {
+			writeReferenceError(w, report, err)
+			return
+		}
 
-	// Copy file data
-	_, err = io.Copy(dst, file)
-	if err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
-		log.Printf("Failed to save file: %v", err)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success":  true,
+			"fileId":   fileID,
+			"filename": filepath.Base(filePath),
+			"quality":  report,
+		})
 		return
 	}
-
-	// Return success response with file ID
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"success": true, "fileId": "` + fileID + `", "filename": "` + filename + `"}`))
 }
 
-// SaveRecordingHandler handles microphone recordings
+// SaveRecordingHandler handles microphone recordings. The recording goes
+// through the same validation as uploads plus the quality gate, so the user
+// hears about a bad recording before generating with it.
 func SaveRecordingHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Read the recording data from request body
-	recordingData, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Failed to read recording data", http.StatusBadRequest)
-		return
-	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 
-	if len(recordingData) == 0 {
-		http.Error(w, "No recording data provided", http.StatusBadRequest)
+	recordingID := uuid.New().String()
+	filePath, report, err := storeReference(r.Context(), recordingID, r.Body, enhancementFrom(r.URL.Query().Get))
+	if err != nil {
+		writeReferenceError(w, report, err)
 		return
 	}
 
-	// Generate unique filename
-	recordingID := uuid.New().String()
-	filename := recordingID + ".webm"
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"recordingId": recordingID,
+		"filename":    filepath.Base(filePath),
+		"quality":     report,
+	})
+}
 
-	// Ensure downloads directory exists
-	downloadsDir := "downloads"
-	if err := os.MkdirAll(downloadsDir, 0755); err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
-		log.Printf("Failed to create downloads directory: %v", err)
+// QualityReportHandler returns the quality gate report of a stored reference
+func QualityReportHandler(w http.ResponseWriter, r *http.Request) {
+	fileID := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(fileID); err != nil {
+		http.Error(w, "Invalid file ID", http.StatusBadRequest)
 		return
 	}
 
-	// Save recording to file
-	filePath := filepath.Join(downloadsDir, filename)
-	err = os.WriteFile(filePath, recordingData, 0644)
+	data, err := os.ReadFile(qualityReportPath(fileID))
 	if err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
-		log.Printf("Failed to save recording: %v", err)
+		http.Error(w, "Quality report not found", http.StatusNotFound)
 		return
 	}
 
-	// Return success response
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"success": true, "recordingId": "` + recordingID + `", "filename": "` + filename + `"}`))
-}
\ No newline at end of file
+	w.Write(data)
+}
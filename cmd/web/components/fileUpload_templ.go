@@ -8,7 +8,9 @@ package components
 import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
-func FileUpload() templ.Component {
+// FileUpload uploads an audio file. A reference also gets a picker for the
+// parts the voice is cloned from; a performance to convert is used whole.
+func FileUpload(reference bool) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -29,7 +31,25 @@ func FileUpload() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div id=\"file-upload\" class=\"mt-4\"><label class=\"block pl-4 text-sm font-bold leading-6 text-gray-900 mb-2\">Upload Audio File</label><div class=\"mt-2\"><div id=\"drop-zone\" class=\"relative flex justify-center rounded-lg border border-dashed border-gray-300 px-6 py-10 hover:border-indigo-400 transition-colors duration-200\"><div class=\"text-center\"><svg class=\"mx-auto h-12 w-12 text-gray-400\" stroke=\"currentColor\" fill=\"none\" viewBox=\"0 0 48 48\"><path d=\"M28 8H12a4 4 0 00-4 4v20m32-12v8m0 0v8a4 4 0 01-4 4H12a4 4 0 01-4-4v-4m32-4l-3.172-3.172a4 4 0 00-5.656 0L28 28M8 32l9.172-9.172a4 4 0 015.656 0L28 28m0 0l4 4m4-24h8m-4-4v8m-12 4h.02\" stroke-width=\"2\" stroke-linecap=\"round\" stroke-linejoin=\"round\"></path></svg><div class=\"mt-4 flex text-sm leading-6 text-gray-600\"><label for=\"file-upload-input\" class=\"relative cursor-pointer rounded-md bg-white font-semibold text-indigo-600 focus-within:outline-none focus-within:ring-2 focus-within:ring-indigo-600 focus-within:ring-offset-2 hover:text-indigo-500\"><span>Upload a file</span> <input id=\"file-upload-input\" name=\"audio-file\" type=\"file\" class=\"sr-only\" accept=\"audio/*,.mp3,.wav,.m4a,.ogg,.flac\" onchange=\"handleFileSelect(this)\"></label><p class=\"pl-1\">or drag and drop</p></div><p class=\"text-xs leading-5 text-gray-600\">MP3, WAV, M4A, OGG, FLAC up to 50MB</p></div></div></div><!-- File Preview Area --><div id=\"file-preview\" class=\"hidden mt-4 p-4 bg-gray-50 border border-gray-200 rounded-lg\"><div class=\"flex items-center justify-between\"><div class=\"flex items-center space-x-3\"><div class=\"flex-shrink-0\"><svg class=\"h-8 w-8 text-indigo-600\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M9 19V6l12-3v13M9 19c0 1.105-1.343 2-3 2s-3-.895-3-2 1.343-2 3-2 3 .895 3 2zm12-3c0 1.105-1.343 2-3 2s-3-.895-3-2 1.343-2 3-2 3 .895 3 2zM9 10l12-3\"></path></svg></div><div class=\"min-w-0 flex-1\"><p class=\"text-sm font-medium text-gray-900\" id=\"file-name\">File name</p><p class=\"text-sm text-gray-500\" id=\"file-size\">Size</p></div><div class=\"flex-shrink-0\"><button type=\"button\" onclick=\"removeFile()\" class=\"rounded-md bg-white text-sm font-medium text-gray-700 hover:text-gray-900 focus:outline-none focus:ring-2 focus:ring-indigo-500\"><svg class=\"h-5 w-5 text-gray-400 hover:text-gray-500\" fill=\"currentColor\" viewBox=\"0 0 20 20\"><path fill-rule=\"evenodd\" d=\"M4.293 4.293a1 1 0 011.414 0L10 8.586l4.293-4.293a1 1 0 111.414 1.414L11.414 10l4.293 4.293a1 1 0 01-1.414 1.414L10 11.414l-4.293 4.293a1 1 0 01-1.414-1.414L8.586 10 4.293 5.707a1 1 0 010-1.414z\" clip-rule=\"evenodd\"></path></svg></button></div></div></div><!-- Audio Player Preview --><div class=\"mt-3\"><audio id=\"audio-preview\" controls class=\"w-full h-8\"><source id=\"audio-source\" src=\"\" type=\"\"> Your browser does not support the audio element.</audio></div></div><!-- Upload Progress --><div id=\"upload-progress\" class=\"hidden mt-4\"><div class=\"flex items-center justify-between text-sm text-gray-600 mb-1\"><span>Uploading...</span> <span id=\"progress-percent\">0%</span></div><div class=\"w-full bg-gray-200 rounded-full h-2\"><div id=\"progress-bar\" class=\"bg-indigo-600 h-2 rounded-full transition-all duration-300\" style=\"width: 0%\"></div></div></div><!-- Error Message --><div id=\"file-error\" class=\"hidden mt-4 p-3 bg-red-50 border border-red-200 rounded-md\"><div class=\"flex items-center\"><svg class=\"w-5 h-5 text-red-400 mr-2\" fill=\"currentColor\" viewBox=\"0 0 20 20\"><path fill-rule=\"evenodd\" d=\"M10 18a8 8 0 100-16 8 8 0 000 16zM8.707 7.293a1 1 0 00-1.414 1.414L8.586 10l-1.293 1.293a1 1 0 101.414 1.414L10 11.414l1.293 1.293a1 1 0 001.414-1.414L11.414 10l1.293-1.293a1 1 0 00-1.414-1.414L10 8.586 8.707 7.293z\" clip-rule=\"evenodd\"></path></svg> <span class=\"text-red-800 text-sm font-medium\" id=\"error-message\">Error message</span></div></div></div><script>\n\t\tlet selectedFile = null;\n\n\t\tfunction handleFileSelect(input) {\n\t\t\tconst file = input.files[0];\n\t\t\tif (!file) return;\n\n\t\t\t// Validate file\n\t\t\tif (!validateFile(file)) return;\n\n\t\t\tselectedFile = file;\n\t\t\tshowFilePreview(file);\n\t\t\thideError();\n\t\t\tnotifyValidationSystem();\n\t\t}\n\n\t\tfunction validateFile(file) {\n\t\t\t// Check file size (50MB max)\n\t\t\tconst maxSize = 50 * 1024 * 1024; // 50MB\n\t\t\tif (file.size > maxSize) {\n\t\t\t\tshowError('File size must be less than 50MB');\n\t\t\t\treturn false;\n\t\t\t}\n\n\t\t\t// Check file type\n\t\t\tconst allowedTypes = ['audio/mpeg', 'audio/wav', 'audio/mp4', 'audio/ogg', 'audio/flac', 'audio/x-m4a'];\n\t\t\tconst allowedExtensions = ['.mp3', '.wav', '.m4a', '.ogg', '.flac'];\n\t\t\t\n\t\t\tconst isValidType = allowedTypes.includes(file.type);\n\t\t\tconst hasValidExtension = allowedExtensions.some(ext => file.name.toLowerCase().endsWith(ext));\n\n\t\t\tif (!isValidType && !hasValidExtension) {\n\t\t\t\tshowError('Please select a valid audio file (MP3, WAV, M4A, OGG, or FLAC)');\n\t\t\t\treturn false;\n\t\t\t}\n\n\t\t\treturn true;\n\t\t}\n\n\t\tfunction showFilePreview(file) {\n\t\t\tconst preview = document.getElementById('file-preview');\n\t\t\tconst fileName = document.getElementById('file-name');\n\t\t\tconst fileSize = document.getElementById('file-size');\n\t\t\tconst audioPreview = document.getElementById('audio-preview');\n\t\t\tconst audioSource = document.getElementById('audio-source');\n\n\t\t\tfileName.textContent = file.name;\n\t\t\tfileSize.textContent = formatFileSize(file.size);\n\t\t\t\n\t\t\t// Create object URL for audio preview\n\t\t\tconst url = URL.createObjectURL(file);\n\t\t\taudioSource.src = url;\n\t\t\taudioSource.type = file.type;\n\t\t\taudioPreview.load();\n\n\t\t\tpreview.classList.remove('hidden');\n\t\t}\n\n\t\tfunction removeFile() {\n\t\t\tselectedFile = null;\n\t\t\tdocument.getElementById('file-upload-input').value = '';\n\t\t\tdocument.getElementById('file-preview').classList.add('hidden');\n\t\t\t\n\t\t\t// Revoke object URL to free memory\n\t\t\tconst audioSource = document.getElementById('audio-source');\n\t\t\tif (audioSource.src) {\n\t\t\t\tURL.revokeObjectURL(audioSource.src);\n\t\t\t\taudioSource.src = '';\n\t\t\t}\n\t\t\tnotifyValidationSystem();\n\t\t}\n\n\t\tfunction formatFileSize(bytes) {\n\t\t\tif (bytes === 0) return '0 Bytes';\n\t\t\tconst k = 1024;\n\t\t\tconst sizes = ['Bytes', 'KB', 'MB', 'GB'];\n\t\t\tconst i = Math.floor(Math.log(bytes) / Math.log(k));\n\t\t\treturn parseFloat((bytes / Math.pow(k, i)).toFixed(2)) + ' ' + sizes[i];\n\t\t}\n\n\t\tfunction showError(message) {\n\t\t\tconst errorDiv = document.getElementById('file-error');\n\t\t\tconst errorMessage = document.getElementById('error-message');\n\t\t\terrorMessage.textContent = message;\n\t\t\terrorDiv.classList.remove('hidden');\n\t\t}\n\n\t\tfunction hideError() {\n\t\t\tdocument.getElementById('file-error').classList.add('hidden');\n\t\t}\n\n\t\t// Drag and drop functionality\n\t\tconst dropZone = document.getElementById('drop-zone');\n\n\t\tdropZone.addEventListener('dragover', function(e) {\n\t\t\te.preventDefault();\n\t\t\tthis.classList.add('border-indigo-400', 'bg-indigo-50');\n\t\t});\n\n\t\tdropZone.addEventListener('dragleave', function(e) {\n\t\t\te.preventDefault();\n\t\t\tthis.classList.remove('border-indigo-400', 'bg-indigo-50');\n\t\t});\n\n\t\tdropZone.addEventListener('drop', function(e) {\n\t\t\te.preventDefault();\n\t\t\tthis.classList.remove('border-indigo-400', 'bg-indigo-50');\n\t\t\t\n\t\t\tconst files = e.dataTransfer.files;\n\t\t\tif (files.length > 0) {\n\t\t\t\tconst file = files[0];\n\t\t\t\tif (validateFile(file)) {\n\t\t\t\t\tselectedFile = file;\n\t\t\t\t\tshowFilePreview(file);\n\t\t\t\t\thideError();\n\t\t\t\t\tnotifyValidationSystem();\n\t\t\t\t\t// Also update the input\n\t\t\t\t\tconst input = document.getElementById('file-upload-input');\n\t\t\t\t\t// Note: We can't directly set input.files, but we store the file in selectedFile\n\t\t\t\t}\n\t\t\t}\n\t\t});\n\n\t\t// Expose selectedFile for form submission\n\t\twindow.getSelectedAudioFile = function() {\n\t\t\treturn selectedFile;\n\t\t};\n\n\t\t// Notify validation system when file state changes\n\t\tfunction notifyValidationSystem() {\n\t\t\t// Check if we have a valid file\n\t\t\tconst isValid = selectedFile !== null;\n\t\t\t\n\t\t\tconsole.log('File validation:', isValid, selectedFile);\n\t\t\t\n\t\t\t// Update Alpine.js store validation state\n\t\t\tif (typeof Alpine !== 'undefined' && Alpine.store('voiceClone')) {\n\t\t\t\tAlpine.store('voiceClone').validateAudioInput(isValid);\n\t\t\t\tconsole.log('Alpine store updated - audioInputValid:', Alpine.store('voiceClone').audioInputValid);\n\t\t\t} else {\n\t\t\t\tconsole.log('Alpine not available');\n\t\t\t}\n\t\t}\n\t</script>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div id=\"file-upload\" class=\"mt-4\"><label class=\"block pl-4 text-sm font-bold leading-6 text-gray-900 mb-2\">Upload Audio File</label><div class=\"mt-2\"><div id=\"drop-zone\" class=\"relative flex justify-center rounded-lg border border-dashed border-gray-300 px-6 py-10 hover:border-indigo-400 transition-colors duration-200\"><div class=\"text-center\"><svg class=\"mx-auto h-12 w-12 text-gray-400\" stroke=\"currentColor\" fill=\"none\" viewBox=\"0 0 48 48\"><path d=\"M28 8H12a4 4 0 00-4 4v20m32-12v8m0 0v8a4 4 0 01-4 4H12a4 4 0 01-4-4v-4m32-4l-3.172-3.172a4 4 0 00-5.656 0L28 28M8 32l9.172-9.172a4 4 0 015.656 0L28 28m0 0l4 4m4-24h8m-4-4v8m-12 4h.02\" stroke-width=\"2\" stroke-linecap=\"round\" stroke-linejoin=\"round\"></path></svg><div class=\"mt-4 flex text-sm leading-6 text-gray-600\"><label for=\"file-upload-input\" class=\"relative cursor-pointer rounded-md bg-white font-semibold text-indigo-600 focus-within:outline-none focus-within:ring-2 focus-within:ring-indigo-600 focus-within:ring-offset-2 hover:text-indigo-500\"><span>Upload a file</span> <input id=\"file-upload-input\" type=\"file\" class=\"sr-only\" accept=\"audio/*,.mp3,.wav,.m4a,.ogg,.flac\" onchange=\"handleFileSelect(this)\"></label><p class=\"pl-1\">or drag and drop</p></div><p class=\"text-xs leading-5 text-gray-600\">MP3, WAV, M4A, OGG, FLAC up to 50MB</p></div></div></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = QualityReport("upload-quality-report").Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<!-- Set once the resumable upload has finished and the server accepted the file --><input type=\"hidden\" id=\"file-id-input\" name=\"file-id\" value=\"\"><!-- File Preview Area --><div id=\"file-preview\" class=\"hidden mt-4 p-4 bg-gray-50 border border-gray-200 rounded-lg\"><div class=\"flex items-center justify-between\"><div class=\"flex items-center space-x-3\"><div class=\"flex-shrink-0\"><svg class=\"h-8 w-8 text-indigo-600\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M9 19V6l12-3v13M9 19c0 1.105-1.343 2-3 2s-3-.895-3-2 1.343-2 3-2 3 .895 3 2zm12-3c0 1.105-1.343 2-3 2s-3-.895-3-2 1.343-2 3-2 3 .895 3 2zM9 10l12-3\"></path></svg></div><div class=\"min-w-0 flex-1\"><p class=\"text-sm font-medium text-gray-900\" id=\"file-name\">File name</p><p class=\"text-sm text-gray-500\" id=\"file-size\">Size</p></div><div class=\"flex-shrink-0\"><button type=\"button\" onclick=\"removeFile()\" class=\"rounded-md bg-white text-sm font-medium text-gray-700 hover:text-gray-900 focus:outline-none focus:ring-2 focus:ring-indigo-500\"><svg class=\"h-5 w-5 text-gray-400 hover:text-gray-500\" fill=\"currentColor\" viewBox=\"0 0 20 20\"><path fill-rule=\"evenodd\" d=\"M4.293 4.293a1 1 0 011.414 0L10 8.586l4.293-4.293a1 1 0 111.414 1.414L11.414 10l4.293 4.293a1 1 0 01-1.414 1.414L10 11.414l-4.293 4.293a1 1 0 01-1.414-1.414L8.586 10 4.293 5.707a1 1 0 010-1.414z\" clip-rule=\"evenodd\"></path></svg></button></div></div></div><!-- Audio Player Preview --><div class=\"mt-3\"><audio id=\"audio-preview\" controls class=\"w-full h-8\"><source id=\"audio-source\" src=\"\" type=\"\"> Your browser does not support the audio element.</audio></div><!-- Drawn by the server once the upload is accepted --><div id=\"upload-visuals\" class=\"hidden mt-3\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if reference {
+			templ_7745c5c3_Err = RangePicker("").Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<details class=\"mt-1 text-sm text-gray-600\"><summary class=\"cursor-pointer\">Spectrogram</summary> <img id=\"upload-spectrogram\" alt=\"Spectrogram of the uploaded audio\" class=\"mt-1 w-full h-32 rounded\"></details></div></div><!-- Upload Progress --><div id=\"upload-progress\" class=\"hidden mt-4\"><div class=\"flex items-center justify-between text-sm text-gray-600 mb-1\"><span id=\"progress-label\">Uploading...</span> <span id=\"progress-percent\">0%</span></div><div class=\"w-full bg-gray-200 rounded-full h-2\"><div id=\"progress-bar\" class=\"bg-indigo-600 h-2 rounded-full transition-all duration-300\" style=\"width: 0%\"></div></div></div><!-- Error Message --><div id=\"file-error\" class=\"hidden mt-4 p-3 bg-red-50 border border-red-200 rounded-md\"><div class=\"flex items-center\"><svg class=\"w-5 h-5 text-red-400 mr-2\" fill=\"currentColor\" viewBox=\"0 0 20 20\"><path fill-rule=\"evenodd\" d=\"M10 18a8 8 0 100-16 8 8 0 000 16zM8.707 7.293a1 1 0 00-1.414 1.414L8.586 10l-1.293 1.293a1 1 0 101.414 1.414L10 11.414l1.293 1.293a1 1 0 001.414-1.414L11.414 10l1.293-1.293a1 1 0 00-1.414-1.414L10 8.586 8.707 7.293z\" clip-rule=\"evenodd\"></path></svg> <span class=\"text-red-800 text-sm font-medium\" id=\"error-message\">Error message</span></div></div></div><script>\n\t\tlet selectedFile = null;\n\t\tlet currentUpload = null;\n\n\t\t// Files are sent in chunks over the resumable upload protocol, so a dropped\n\t\t// connection only costs the current chunk and a reload can pick up where it stopped.\n\t\tconst UPLOAD_CHUNK_SIZE = 2 * 1024 * 1024;\n\t\tconst UPLOAD_MAX_RETRIES = 5;\n\n\t\tfunction handleFileSelect(input) {\n\t\t\tconst file = input.files[0];\n\t\t\tif (!file) return;\n\n\t\t\t// Validate file\n\t\t\tif (!validateFile(file)) return;\n\n\t\t\tselectedFile = file;\n\t\t\tshowFilePreview(file);\n\t\t\thideError();\n\t\t\tstartResumableUpload(file);\n\t\t}\n\n\t\tasync function startResumableUpload(file) {\n\t\t\tcancelUpload();\n\t\t\tsetFileId('');\n\t\t\trenderQualityReport('upload-quality-report', null);\n\n\t\t\tconst upload = { file: file, url: null, cancelled: false };\n\t\t\tcurrentUpload = upload;\n\t\t\tshowProgress('Preparing...', 0);\n\n\t\t\ttry {\n\t\t\t\tconst checksum = await sha256Hex(file);\n\t\t\t\tconst key = 'upload:' + file.name + ':' + file.size + ':' + file.lastModified + ':' + checksum + ':' + Object.keys(enhancementParams()).join('+');\n\n\t\t\t\t// Resume an upload of the same file started before a reload\n\t\t\t\tlet offset = null;\n\t\t\t\tupload.url = localStorage.getItem(key);\n\t\t\t\tif (upload.url) {\n\t\t\t\t\toffset = await fetchOffset(upload.url);\n\t\t\t\t}\n\t\t\t\tif (offset === null) {\n\t\t\t\t\tupload.url = await createUpload(file, checksum);\n\t\t\t\t\tlocalStorage.setItem(key, upload.url);\n\t\t\t\t\toffset = 0;\n\t\t\t\t}\n\n\t\t\t\tconst fileId = await sendChunks(upload, offset);\n\t\t\t\tlocalStorage.removeItem(key);\n\t\t\t\tif (upload.cancelled) return;\n\n\t\t\t\tsetFileId(fileId);\n\t\t\t\thideError();\n\t\t\t\tshowQualityReport(fileId);\n\t\t\t\tshowVisuals(fileId);\n\t\t\t} catch (err) {\n\t\t\t\tif (upload.cancelled) return;\n\t\t\t\tconsole.error('Upload failed:', err);\n\t\t\t\trenderQualityReport('upload-quality-report', err.quality);\n\t\t\t\tshowError(err.message);\n\t\t\t} finally {\n\t\t\t\tif (currentUpload === upload) {\n\t\t\t\t\tcurrentUpload = null;\n\t\t\t\t\thideProgress();\n\t\t\t\t}\n\t\t\t}\n\t\t}\n\n\t\tasync function createUpload(file, checksum) {\n\t\t\tconst metadata = ['filename ' + btoa(unescape(encodeURIComponent(file.name)))];\n\t\t\tif (checksum) metadata.push('sha256 ' + btoa(checksum));\n\t\t\tObject.entries(enhancementParams()).forEach(([key, value]) => metadata.push(key + ' ' + btoa(value)));\n\n\t\t\tconst response = await fetch('/uploads', {\n\t\t\t\tmethod: 'POST',\n\t\t\t\theaders: {\n\t\t\t\t\t'Tus-Resumable': '1.0.0',\n\t\t\t\t\t'Upload-Length': String(file.size),\n\t\t\t\t\t'Upload-Metadata': metadata.join(',')\n\t\t\t\t}\n\t\t\t});\n\t\t\tif (response.status !== 201) {\n\t\t\t\tthrow await uploadError(response);\n\t\t\t}\n\t\t\treturn response.headers.get('Location');\n\t\t}\n\n\t\tasync function fetchOffset(url) {\n\t\t\ttry {\n\t\t\t\tconst response = await fetch(url, { method: 'HEAD', headers: { 'Tus-Resumable': '1.0.0' } });\n\t\t\t\tif (!response.ok) return null;\n\t\t\t\treturn parseInt(response.headers.get('Upload-Offset'), 10);\n\t\t\t} catch (err) {\n\t\t\t\treturn null;\n\t\t\t}\n\t\t}\n\n\t\t// sendChunks PATCHes the file from offset and returns the file ID once the\n\t\t// server has accepted the complete file. The server checks the file in the\n\t\t// background, so once every byte is in an empty PATCH asks how that is going.\n\t\tasync function sendChunks(upload, offset) {\n\t\t\tconst file = upload.file;\n\t\t\tlet retries = 0;\n\n\t\t\twhile (!upload.cancelled) {\n\t\t\t\tshowProgress('Uploading...', Math.round(offset / file.size * 100));\n\n\t\t\t\tconst chunk = file.slice(offset, Math.min(offset + UPLOAD_CHUNK_SIZE, file.size));\n\t\t\t\tconst headers = {\n\t\t\t\t\t'Tus-Resumable': '1.0.0',\n\t\t\t\t\t'Upload-Offset': String(offset),\n\t\t\t\t\t'Content-Type': 'application/offset+octet-stream'\n\t\t\t\t};\n\t\t\t\tconst chunkChecksum = await sha256Base64(chunk);\n\t\t\t\tif (chunkChecksum) headers['Upload-Checksum'] = 'sha256 ' + chunkChecksum;\n\n\t\t\t\tlet response;\n\t\t\t\ttry {\n\t\t\t\t\tresponse = await fetch(upload.url, { method: 'PATCH', headers: headers, body: chunk });\n\t\t\t\t} catch (err) {\n\t\t\t\t\t// Network trouble: back off, then ask the server how much it kept\n\t\t\t\t\tif (++retries > UPLOAD_MAX_RETRIES) throw new Error('Upload interrupted. Please check your connection and select the file again');\n\t\t\t\t\tawait sleep(1000 * Math.pow(2, retries));\n\t\t\t\t\tconst resumed = await fetchOffset(upload.url);\n\t\t\t\t\tif (resumed !== null) offset = resumed;\n\t\t\t\t\tcontinue;\n\t\t\t\t}\n\n\t\t\t\tif (response.status === 204) {\n\t\t\t\t\tretries = 0;\n\t\t\t\t\tconst fileId = response.headers.get('Upload-File-Id');\n\t\t\t\t\tif (fileId) return fileId;\n\t\t\t\t\toffset = parseInt(response.headers.get('Upload-Offset'), 10);\n\t\t\t\t\tif (offset >= file.size) {\n\t\t\t\t\t\t// Every byte is in; the server is still checking the file\n\t\t\t\t\t\tshowProgress('Checking audio...', 100);\n\t\t\t\t\t\tawait sleep(1000);\n\t\t\t\t\t}\n\t\t\t\t\tcontinue;\n\t\t\t\t}\n\n\t\t\t\tif (++retries > UPLOAD_MAX_RETRIES) throw await uploadError(response);\n\n\t\t\t\tswitch (response.status) {\n\t\t\t\tcase 409: {\n\t\t\t\t\tconst resumed = await fetchOffset(upload.url);\n\t\t\t\t\tif (resumed === null) throw new Error('Upload expired. Please select the file again');\n\t\t\t\t\toffset = resumed;\n\t\t\t\t\tbreak;\n\t\t\t\t}\n\t\t\t\tcase 460:\n\t\t\t\t\t// Chunk corrupted in transit, send it again\n\t\t\t\t\tbreak;\n\t\t\t\tcase 503:\n\t\t\t\t\tawait sleep(1000 * (parseInt(response.headers.get('Retry-After'), 10) || 5));\n\t\t\t\t\tbreak;\n\t\t\t\tcase 404:\n\t\t\t\t\tthrow new Error('Upload expired. Please select the file again');\n\t\t\t\tdefault:\n\t\t\t\t\tif (response.status >= 500) {\n\t\t\t\t\t\tawait sleep(1000 * Math.pow(2, retries));\n\t\t\t\t\t\tconst resumedAfterError = await fetchOffset(upload.url);\n\t\t\t\t\t\tif (resumedAfterError !== null) offset = resumedAfterError;\n\t\t\t\t\t\tbreak;\n\t\t\t\t\t}\n\t\t\t\t\tthrow await uploadError(response);\n\t\t\t\t}\n\t\t\t}\n\t\t\treturn null;\n\t\t}\n\n\t\tfunction cancelUpload() {\n\t\t\tif (!currentUpload) return;\n\t\t\tcurrentUpload.cancelled = true;\n\t\t\tif (currentUpload.url) {\n\t\t\t\tfetch(currentUpload.url, { method: 'DELETE', headers: { 'Tus-Resumable': '1.0.0' } }).catch(() => {});\n\t\t\t}\n\t\t\tcurrentUpload = null;\n\t\t\thideProgress();\n\t\t}\n\n\t\t// uploadError turns a failed response into an Error, keeping the quality report if the gate rejected the file\n\t\tasync function uploadError(response) {\n\t\t\ttry {\n\t\t\t\tconst data = await response.clone().json();\n\t\t\t\tif (data.error) {\n\t\t\t\t\tconst err = new Error(data.error);\n\t\t\t\t\terr.quality = data.quality;\n\t\t\t\t\treturn err;\n\t\t\t\t}\n\t\t\t} catch (err) {}\n\t\t\tconst text = await response.text();\n\t\t\treturn new Error(text.trim() || 'Upload failed (' + response.status + ')');\n\t\t}\n\n\t\tasync function showQualityReport(fileId) {\n\t\t\ttry {\n\t\t\t\tconst response = await fetch('/api/audio-quality/' + fileId);\n\t\t\t\tif (response.ok) {\n\t\t\t\t\trenderQualityReport('upload-quality-report', await response.json());\n\t\t\t\t}\n\t\t\t} catch (err) {}\n\t\t}\n\n\t\t// showVisuals hands the accepted upload's waveform to the range picker, if\n\t\t// there is one, and loads its spectrogram\n\t\tfunction showVisuals(fileId) {\n\t\t\tdocument.getElementById('upload-visuals').classList.remove('hidden');\n\t\t\tdocument.querySelector('#upload-visuals .range-picker')?.dispatchEvent(new CustomEvent('reference-peaks', { detail: '/references/' + fileId + '/peaks.json' }));\n\t\t\tdocument.getElementById('upload-spectrogram').src = '/references/' + fileId + '/spectrogram.png';\n\t\t}\n\n\t\t// Clicking the waveform without dragging plays from there\n\t\tdocument.getElementById('upload-visuals').addEventListener('seek', event => {\n\t\t\tconst preview = document.getElementById('audio-preview');\n\t\t\tpreview.currentTime = event.detail;\n\t\t\tpreview.play();\n\t\t});\n\n\t\t// The clean-up switches are applied on the server, so changing one uploads the file again\n\t\tdocument.querySelectorAll('#file-upload input[data-enhancement]').forEach(input => {\n\t\t\tinput.addEventListener('change', () => {\n\t\t\t\tif (selectedFile) startResumableUpload(selectedFile);\n\t\t\t});\n\t\t});\n\n\t\t// Checksums need WebCrypto, which browsers only offer on secure origins; without it the upload goes unchecked\n\t\tasync function sha256Digest(blob) {\n\t\t\tif (!window.crypto || !crypto.subtle) return null;\n\t\t\treturn new Uint8Array(await crypto.subtle.digest('SHA-256', await blob.arrayBuffer()));\n\t\t}\n\n\t\tasync function sha256Hex(blob) {\n\t\t\tconst digest = await sha256Digest(blob);\n\t\t\tif (!digest) return '';\n\t\t\treturn Array.from(digest).map(b => b.toString(16).padStart(2, '0')).join('');\n\t\t}\n\n\t\tasync function sha256Base64(blob) {\n\t\t\tconst digest = await sha256Digest(blob);\n\t\t\tif (!digest) return '';\n\t\t\treturn btoa(String.fromCharCode.apply(null, digest));\n\t\t}\n\n\t\tfunction sleep(ms) {\n\t\t\treturn new Promise(resolve => setTimeout(resolve, ms));\n\t\t}\n\n\t\tfunction showProgress(label, percent) {\n\t\t\tdocument.getElementById('upload-progress').classList.remove('hidden');\n\t\t\tdocument.getElementById('progress-label').textContent = label;\n\t\t\tdocument.getElementById('progress-percent').textContent = percent + '%';\n\t\t\tdocument.getElementById('progress-bar').style.width = percent + '%';\n\t\t}\n\n\t\tfunction hideProgress() {\n\t\t\tdocument.getElementById('upload-progress').classList.add('hidden');\n\t\t}\n\n\t\tfunction setFileId(fileId) {\n\t\t\tdocument.getElementById('file-id-input').value = fileId;\n\t\t\tnotifyValidationSystem();\n\t\t}\n\n\t\tfunction validateFile(file) {\n\t\t\t// Check file size (50MB max)\n\t\t\tconst maxSize = 50 * 1024 * 1024; // 50MB\n\t\t\tif (file.size > maxSize) {\n\t\t\t\tshowError('File size must be less than 50MB');\n\t\t\t\treturn false;\n\t\t\t}\n\n\t\t\t// Check file type\n\t\t\tconst allowedTypes = ['audio/mpeg', 'audio/wav', 'audio/mp4', 'audio/ogg', 'audio/flac', 'audio/x-m4a'];\n\t\t\tconst allowedExtensions = ['.mp3', '.wav', '.m4a', '.ogg', '.flac'];\n\t\t\t\n\t\t\tconst isValidType = allowedTypes.includes(file.type);\n\t\t\tconst hasValidExtension = allowedExtensions.some(ext => file.name.toLowerCase().endsWith(ext));\n\n\t\t\tif (!isValidType && !hasValidExtension) {\n\t\t\t\tshowError('Please select a valid audio file (MP3, WAV, M4A, OGG, or FLAC)');\n\t\t\t\treturn false;\n\t\t\t}\n\n\t\t\treturn true;\n\t\t}\n\n\t\tfunction showFilePreview(file) {\n\t\t\tconst preview = document.getElementById('file-preview');\n\t\t\tconst fileName = document.getElementById('file-name');\n\t\t\tconst fileSize = document.getElementById('file-size');\n\t\t\tconst audioPreview = document.getElementById('audio-preview');\n\t\t\tconst audioSource = document.getElementById('audio-source');\n\n\t\t\tfileName.textContent = file.name;\n\t\t\tfileSize.textContent = formatFileSize(file.size);\n\t\t\t\n\t\t\t// Create object URL for audio preview\n\t\t\tconst url = URL.createObjectURL(file);\n\t\t\taudioSource.src = url;\n\t\t\taudioSource.type = file.type;\n\t\t\taudioPreview.load();\n\n\t\t\tpreview.classList.remove('hidden');\n\t\t}\n\n\t\tfunction removeFile() {\n\t\t\tcancelUpload();\n\t\t\tsetFileId('');\n\t\t\trenderQualityReport('upload-quality-report', null);\n\t\t\tdocument.getElementById('upload-visuals').classList.add('hidden');\n\t\t\tdocument.querySelector('#upload-visuals .range-picker')?.dispatchEvent(new CustomEvent('reference-peaks', { detail: null }));\n\t\t\tdocument.getElementById('upload-spectrogram').removeAttribute('src');\n\t\t\tselectedFile = null;\n\t\t\tdocument.getElementById('file-upload-input').value = '';\n\t\t\tdocument.getElementById('file-preview').classList.add('hidden');\n\t\t\t\n\t\t\t// Revoke object URL to free memory\n\t\t\tconst audioSource = document.getElementById('audio-source');\n\t\t\tif (audioSource.src) {\n\t\t\t\tURL.revokeObjectURL(audioSource.src);\n\t\t\t\taudioSource.src = '';\n\t\t\t}\n\t\t\tnotifyValidationSystem();\n\t\t}\n\n\t\tfunction formatFileSize(bytes) {\n\t\t\tif (bytes === 0) return '0 Bytes';\n\t\t\tconst k = 1024;\n\t\t\tconst sizes = ['Bytes', 'KB', 'MB', 'GB'];\n\t\t\tconst i = Math.floor(Math.log(bytes) / Math.log(k));\n\t\t\treturn parseFloat((bytes / Math.pow(k, i)).toFixed(2)) + ' ' + sizes[i];\n\t\t}\n\n\t\tfunction showError(message) {\n\t\t\tconst errorDiv = document.getElementById('file-error');\n\t\t\tconst errorMessage = document.getElementById('error-message');\n\t\t\terrorMessage.textContent = message;\n\t\t\terrorDiv.classList.remove('hidden');\n\t\t}\n\n\t\tfunction hideError() {\n\t\t\tdocument.getElementById('file-error').classList.add('hidden');\n\t\t}\n\n\t\t// Drag and drop functionality\n\t\tconst dropZone = document.getElementById('drop-zone');\n\n\t\tdropZone.addEventListener('dragover', function(e) {\n\t\t\te.preventDefault();\n\t\t\tthis.classList.add('border-indigo-400', 'bg-indigo-50');\n\t\t});\n\n\t\tdropZone.addEventListener('dragleave', function(e) {\n\t\t\te.preventDefault();\n\t\t\tthis.classList.remove('border-indigo-400', 'bg-indigo-50');\n\t\t});\n\n\t\tdropZone.addEventListener('drop', function(e) {\n\t\t\te.preventDefault();\n\t\t\tthis.classList.remove('border-indigo-400', 'bg-indigo-50');\n\t\t\t\n\t\t\tconst files = e.dataTransfer.files;\n\t\t\tif (files.length > 0) {\n\t\t\t\tconst file = files[0];\n\t\t\t\tif (validateFile(file)) {\n\t\t\t\t\tselectedFile = file;\n\t\t\t\t\tshowFilePreview(file);\n\t\t\t\t\thideError();\n\t\t\t\t\tstartResumableUpload(file);\n\t\t\t\t}\n\t\t\t}\n\t\t});\n\n\t\t// Expose selectedFile for form submission\n\t\twindow.getSelectedAudioFile = function() {\n\t\t\treturn selectedFile;\n\t\t};\n\n\t\t// Notify validation system when file state changes\n\t\tfunction notifyValidationSystem() {\n\t\t\t// The file only counts once the server has accepted the upload\n\t\t\tconst isValid = selectedFile !== null && document.getElementById('file-id-input').value !== '';\n\t\t\t\n\t\t\tconsole.log('File validation:', isValid, selectedFile);\n\t\t\t\n\t\t\t// Update Alpine.js store validation state\n\t\t\tif (typeof Alpine !== 'undefined' && Alpine.store('voiceClone')) {\n\t\t\t\tAlpine.store('voiceClone').validateAudioInput(isValid);\n\t\t\t\tconsole.log('Alpine store updated - audioInputValid:', Alpine.store('voiceClone').audioInputValid);\n\t\t\t} else {\n\t\t\t\tconsole.log('Alpine not available');\n\t\t\t}\n\t\t}\n\t</script>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
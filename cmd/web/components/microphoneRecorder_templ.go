@@ -29,7 +29,15 @@ func MicrophoneRecorder() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div id=\"microphone-recorder\" class=\"mt-4\"><label class=\"block pl-4 text-sm font-bold leading-6 text-gray-900 mb-2\">Record Audio</label><!-- Recording Interface --><div class=\"mt-2 p-6 bg-gradient-to-br from-gray-50 to-gray-100 rounded-lg border border-gray-200\"><div class=\"text-center\"><!-- Recording Status --><div id=\"recording-status\" class=\"mb-4\"><div id=\"status-idle\" class=\"recording-state\"><div class=\"mx-auto w-20 h-20 bg-gradient-to-br from-green-400 to-green-600 rounded-full flex items-center justify-center shadow-lg cursor-pointer hover:shadow-xl transition-all duration-200 hover:scale-105\" onclick=\"startRecording()\"><svg class=\"w-8 h-8 text-white\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M19 11a7 7 0 01-7 7m0 0a7 7 0 01-7-7m7 7v4m0 0H8m4 0h4m-4-8a3 3 0 01-3-3V5a3 3 0 116 0v6a3 3 0 01-3 3z\"></path></svg></div><p class=\"mt-3 text-sm font-medium text-gray-700\">Click to start recording</p><p class=\"text-xs text-gray-500\">Make sure to allow microphone access</p></div><div id=\"status-recording\" class=\"recording-state hidden\"><div class=\"mx-auto w-20 h-20 bg-gradient-to-br from-red-400 to-red-600 rounded-full flex items-center justify-center shadow-lg animate-pulse\"><svg class=\"w-8 h-8 text-white\" fill=\"currentColor\" viewBox=\"0 0 24 24\"><rect x=\"6\" y=\"6\" width=\"12\" height=\"12\" rx=\"2\"></rect></svg></div><p class=\"mt-3 text-sm font-medium text-red-700\">Recording...</p><div class=\"flex items-center justify-center space-x-2 mt-2\"><span id=\"recording-timer\" class=\"text-lg font-mono text-red-600\">00:00</span></div><button type=\"button\" onclick=\"stopRecording()\" class=\"mt-3 px-4 py-2 text-sm font-medium text-red-700 bg-red-100 border border-red-300 rounded-md hover:bg-red-200 transition-colors duration-200\">Stop Recording</button></div><div id=\"status-processing\" class=\"recording-state hidden\"><div class=\"mx-auto w-20 h-20 bg-gradient-to-br from-blue-400 to-blue-600 rounded-full flex items-center justify-center shadow-lg\"><svg class=\"w-8 h-8 text-white animate-spin\" fill=\"none\" viewBox=\"0 0 24 24\"><circle class=\"opacity-25\" cx=\"12\" cy=\"12\" r=\"10\" stroke=\"currentColor\" stroke-width=\"4\"></circle> <path class=\"opacity-75\" fill=\"currentColor\" d=\"M4 12a8 8 0 018-8V0C5.373 0 0 5.373 0 12h4zm2 5.291A7.962 7.962 0 014 12H0c0 3.042 1.135 5.824 3 7.938l3-2.647z\"></path></svg></div><p class=\"mt-3 text-sm font-medium text-blue-700\">Processing audio...</p></div></div><!-- Audio Visualizer --><div id=\"audio-visualizer\" class=\"hidden mb-4\"><div class=\"flex justify-center items-end space-x-1 h-12\"><div class=\"w-1 bg-green-500 rounded-full animate-bounce\" style=\"height: 8px; animation-delay: 0ms;\"></div><div class=\"w-1 bg-green-500 rounded-full animate-bounce\" style=\"height: 16px; animation-delay: 150ms;\"></div><div class=\"w-1 bg-green-500 rounded-full animate-bounce\" style=\"height: 24px; animation-delay: 300ms;\"></div><div class=\"w-1 bg-green-500 rounded-full animate-bounce\" style=\"height: 12px; animation-delay: 450ms;\"></div><div class=\"w-1 bg-green-500 rounded-full animate-bounce\" style=\"height: 32px; animation-delay: 600ms;\"></div><div class=\"w-1 bg-green-500 rounded-full animate-bounce\" style=\"height: 20px; animation-delay: 750ms;\"></div><div class=\"w-1 bg-green-500 rounded-full animate-bounce\" style=\"height: 16px; animation-delay: 900ms;\"></div><div class=\"w-1 bg-green-500 rounded-full animate-bounce\" style=\"height: 8px; animation-delay: 1050ms;\"></div></div></div><!-- Recording Tips --><div class=\"text-xs text-gray-500 space-y-1\"><p>💡 <strong>Tip:</strong> Record at least 10 seconds for best results</p><p>🔇 <strong>Quiet environment</strong> recommended</p><p>📱 <strong>Speak clearly</strong> and at normal volume</p></div></div></div><!-- Recorded Audio Preview --><div id=\"recorded-preview\" class=\"hidden mt-4 p-4 bg-gray-50 border border-gray-200 rounded-lg\"><div class=\"flex items-center justify-between mb-3\"><h4 class=\"text-sm font-medium text-gray-900\">Recorded Audio</h4><div class=\"flex space-x-2\"><button type=\"button\" onclick=\"playRecording()\" class=\"text-sm text-indigo-600 hover:text-indigo-500 font-medium\">Play</button> <button type=\"button\" onclick=\"retryRecording()\" class=\"text-sm text-gray-600 hover:text-gray-500 font-medium\">Re-record</button></div></div><div class=\"bg-white p-3 rounded border\"><audio id=\"recorded-audio\" controls class=\"w-full h-8\">Your browser does not support the audio element.</audio></div><div class=\"mt-2 text-xs text-gray-500\">Duration: <span id=\"audio-duration\">0:00</span> |  Quality: <span id=\"audio-quality\">Good</span></div></div><!-- Error Messages --><div id=\"microphone-error\" class=\"hidden mt-4 p-3 bg-red-50 border border-red-200 rounded-md\"><div class=\"flex items-center\"><svg class=\"w-5 h-5 text-red-400 mr-2\" fill=\"currentColor\" viewBox=\"0 0 20 20\"><path fill-rule=\"evenodd\" d=\"M10 18a8 8 0 100-16 8 8 0 000 16zM8.707 7.293a1 1 0 00-1.414 1.414L8.586 10l-1.293 1.293a1 1 0 101.414 1.414L10 11.414l1.293 1.293a1 1 0 001.414-1.414L11.414 10l1.293-1.293a1 1 0 00-1.414-1.414L10 8.586 8.707 7.293z\" clip-rule=\"evenodd\"></path></svg> <span class=\"text-red-800 text-sm font-medium\" id=\"mic-error-message\">Error message</span></div></div></div><script>\n\t\tlet mediaRecorder = null;\n\t\tlet recordedChunks = [];\n\t\tlet recordingTimer = null;\n\t\tlet startTime = null;\n\t\tlet recordedBlob = null;\n\n\t\tasync function startRecording() {\n\t\t\ttry {\n\t\t\t\thideError();\n\t\t\t\t\n\t\t\t\t// Request microphone access\n\t\t\t\tconst stream = await navigator.mediaDevices.getUserMedia({ \n\t\t\t\t\taudio: {\n\t\t\t\t\t\techoCancellation: true,\n\t\t\t\t\t\tnoiseSuppression: true,\n\t\t\t\t\t\tautoGainControl: true\n\t\t\t\t\t}\n\t\t\t\t});\n\n\t\t\t\t// Check if MediaRecorder is supported\n\t\t\t\tif (!MediaRecorder.isTypeSupported('audio/webm')) {\n\t\t\t\t\tthrow new Error('Audio recording not supported in this browser');\n\t\t\t\t}\n\n\t\t\t\trecordedChunks = [];\n\t\t\t\tmediaRecorder = new MediaRecorder(stream, { \n\t\t\t\t\tmimeType: 'audio/webm'\n\t\t\t\t});\n\n\t\t\t\tmediaRecorder.addEventListener('dataavailable', event => {\n\t\t\t\t\tif (event.data.size > 0) {\n\t\t\t\t\t\trecordedChunks.push(event.data);\n\t\t\t\t\t}\n\t\t\t\t});\n\n\t\t\t\tmediaRecorder.addEventListener('stop', () => {\n\t\t\t\t\trecordedBlob = new Blob(recordedChunks, { type: 'audio/webm' });\n\t\t\t\t\tshowRecordedPreview();\n\t\t\t\t\tstream.getTracks().forEach(track => track.stop());\n\t\t\t\t});\n\n\t\t\t\t// Start recording\n\t\t\t\tmediaRecorder.start(1000); // Collect data every second\n\t\t\t\tstartTime = Date.now();\n\t\t\t\t\n\t\t\t\t// Update UI\n\t\t\t\tshowRecordingState();\n\t\t\t\tstartTimer();\n\t\t\t\tshowVisualizer();\n\n\t\t\t} catch (error) {\n\t\t\t\tconsole.error('Recording error:', error);\n\t\t\t\tlet errorMessage = 'Could not access microphone. ';\n\t\t\t\t\n\t\t\t\tif (error.name === 'NotAllowedError') {\n\t\t\t\t\terrorMessage += 'Please allow microphone access and try again.';\n\t\t\t\t} else if (error.name === 'NotFoundError') {\n\t\t\t\t\terrorMessage += 'No microphone found. Please connect a microphone and try again.';\n\t\t\t\t} else {\n\t\t\t\t\terrorMessage += error.message || 'Unknown error occurred.';\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\tshowError(errorMessage);\n\t\t\t}\n\t\t}\n\n\t\tfunction stopRecording(event) {\n\t\t\tif (event) {\n\t\t\t\tevent.preventDefault();\n\t\t\t\tevent.stopPropagation();\n\t\t\t}\n\t\t\t\n\t\t\tif (mediaRecorder && mediaRecorder.state !== 'inactive') {\n\t\t\t\tmediaRecorder.stop();\n\t\t\t\tstopTimer();\n\t\t\t\thideVisualizer();\n\t\t\t\tshowProcessingState();\n\t\t\t}\n\t\t}\n\n\t\tfunction showRecordingState() {\n\t\t\tdocument.getElementById('status-idle').classList.add('hidden');\n\t\t\tdocument.getElementById('status-recording').classList.remove('hidden');\n\t\t\tdocument.getElementById('status-processing').classList.add('hidden');\n\t\t}\n\n\t\tfunction showProcessingState() {\n\t\t\tdocument.getElementById('status-recording').classList.add('hidden');\n\t\t\tdocument.getElementById('status-processing').classList.remove('hidden');\n\t\t}\n\n\t\tfunction showIdleState() {\n\t\t\tdocument.getElementById('status-recording').classList.add('hidden');\n\t\t\tdocument.getElementById('status-processing').classList.add('hidden');\n\t\t\tdocument.getElementById('status-idle').classList.remove('hidden');\n\t\t}\n\n\t\tfunction startTimer() {\n\t\t\tconst timerDisplay = document.getElementById('recording-timer');\n\t\t\trecordingTimer = setInterval(() => {\n\t\t\t\tconst elapsed = Math.floor((Date.now() - startTime) / 1000);\n\t\t\t\tconst minutes = Math.floor(elapsed / 60);\n\t\t\t\tconst seconds = elapsed % 60;\n\t\t\t\ttimerDisplay.textContent = `${minutes.toString().padStart(2, '0')}:${seconds.toString().padStart(2, '0')}`;\n\t\t\t}, 1000);\n\t\t}\n\n\t\tfunction stopTimer() {\n\t\t\tif (recordingTimer) {\n\t\t\t\tclearInterval(recordingTimer);\n\t\t\t\trecordingTimer = null;\n\t\t\t}\n\t\t}\n\n\t\tfunction showVisualizer() {\n\t\t\tdocument.getElementById('audio-visualizer').classList.remove('hidden');\n\t\t}\n\n\t\tfunction hideVisualizer() {\n\t\t\tdocument.getElementById('audio-visualizer').classList.add('hidden');\n\t\t}\n\n\t\tfunction showRecordedPreview() {\n\t\t\tconst preview = document.getElementById('recorded-preview');\n\t\t\tconst audio = document.getElementById('recorded-audio');\n\t\t\t\n\t\t\tconst audioUrl = URL.createObjectURL(recordedBlob);\n\t\t\taudio.src = audioUrl;\n\t\t\t\n\t\t\t// Create or update hidden input for form submission\n\t\t\tlet hiddenInput = document.getElementById('microphone-recording-input');\n\t\t\tif (!hiddenInput) {\n\t\t\t\thiddenInput = document.createElement('input');\n\t\t\t\thiddenInput.type = 'file';\n\t\t\t\thiddenInput.name = 'recording';\n\t\t\t\thiddenInput.id = 'microphone-recording-input';\n\t\t\t\thiddenInput.style.display = 'none';\n\t\t\t\tdocument.getElementById('voice-form').appendChild(hiddenInput);\n\t\t\t}\n\t\t\t\n\t\t\t// Create a File object from the blob\n\t\t\tconst file = new File([recordedBlob], 'recording.webm', { type: 'audio/webm' });\n\t\t\t\n\t\t\t// Create a new FileList-like object\n\t\t\tconst dataTransfer = new DataTransfer();\n\t\t\tdataTransfer.items.add(file);\n\t\t\thiddenInput.files = dataTransfer.files;\n\t\t\t\n\t\t\tnotifyValidationSystem();\n\t\t\t\n\t\t\t// Calculate duration and quality\n\t\t\taudio.addEventListener('loadedmetadata', () => {\n\t\t\t\tconst duration = isNaN(audio.duration) ? 0 : Math.floor(audio.duration);\n\t\t\t\tconst minutes = Math.floor(duration / 60);\n\t\t\t\tconst seconds = duration % 60;\n\t\t\t\t\n\t\t\t\tif (duration > 0) {\n\t\t\t\t\tdocument.getElementById('audio-duration').textContent = `${minutes}:${seconds.toString().padStart(2, '0')}`;\n\t\t\t\t\t\n\t\t\t\t\t// Simple quality assessment based on duration\n\t\t\t\t\tlet quality = 'Good';\n\t\t\t\t\tif (duration < 5) quality = 'Too short';\n\t\t\t\t\telse if (duration < 10) quality = 'Fair';\n\t\t\t\t\telse if (duration > 60) quality = 'Very long';\n\t\t\t\t\t\n\t\t\t\t\tdocument.getElementById('audio-quality').textContent = quality;\n\t\t\t\t} else {\n\t\t\t\t\t// Fallback for when duration isn't available immediately\n\t\t\t\t\tdocument.getElementById('audio-duration').textContent = 'Processing...';\n\t\t\t\t\tdocument.getElementById('audio-quality').textContent = 'Analyzing...';\n\t\t\t\t\t\n\t\t\t\t\t// Try again after a short delay\n\t\t\t\t\tsetTimeout(() => {\n\t\t\t\t\t\tconst newDuration = isNaN(audio.duration) ? 0 : Math.floor(audio.duration);\n\t\t\t\t\t\tif (newDuration > 0) {\n\t\t\t\t\t\t\tconst newMinutes = Math.floor(newDuration / 60);\n\t\t\t\t\t\t\tconst newSeconds = newDuration % 60;\n\t\t\t\t\t\t\tdocument.getElementById('audio-duration').textContent = `${newMinutes}:${newSeconds.toString().padStart(2, '0')}`;\n\t\t\t\t\t\t\t\n\t\t\t\t\t\t\tlet quality = 'Good';\n\t\t\t\t\t\t\tif (newDuration < 5) quality = 'Too short';\n\t\t\t\t\t\t\telse if (newDuration < 10) quality = 'Fair';\n\t\t\t\t\t\t\telse if (newDuration > 60) quality = 'Very long';\n\t\t\t\t\t\t\t\n\t\t\t\t\t\t\tdocument.getElementById('audio-quality').textContent = quality;\n\t\t\t\t\t\t} else {\n\t\t\t\t\t\t\t// Final fallback\n\t\t\t\t\t\t\tdocument.getElementById('audio-duration').textContent = 'Unknown';\n\t\t\t\t\t\t\tdocument.getElementById('audio-quality').textContent = 'Ready';\n\t\t\t\t\t\t}\n\t\t\t\t\t}, 500);\n\t\t\t\t}\n\t\t\t});\n\n\t\t\tpreview.classList.remove('hidden');\n\t\t\tshowIdleState();\n\t\t}\n\n\t\tfunction playRecording(event) {\n\t\t\tif (event) {\n\t\t\t\tevent.preventDefault();\n\t\t\t\tevent.stopPropagation();\n\t\t\t}\n\t\t\t\n\t\t\tconst audio = document.getElementById('recorded-audio');\n\t\t\tif (audio.paused) {\n\t\t\t\taudio.play();\n\t\t\t} else {\n\t\t\t\taudio.pause();\n\t\t\t}\n\t\t}\n\n\t\tfunction retryRecording(event) {\n\t\t\tif (event) {\n\t\t\t\tevent.preventDefault();\n\t\t\t\tevent.stopPropagation();\n\t\t\t}\n\t\t\t\n\t\t\t// Clean up previous recording\n\t\t\tconst audio = document.getElementById('recorded-audio');\n\t\t\tif (audio.src) {\n\t\t\t\tURL.revokeObjectURL(audio.src);\n\t\t\t\taudio.src = '';\n\t\t\t}\n\t\t\t\n\t\t\t// Remove hidden input\n\t\t\tconst hiddenInput = document.getElementById('microphone-recording-input');\n\t\t\tif (hiddenInput) {\n\t\t\t\thiddenInput.remove();\n\t\t\t}\n\t\t\t\n\t\t\trecordedBlob = null;\n\t\t\trecordedChunks = [];\n\t\t\t\n\t\t\t// Hide preview and show idle state\n\t\t\tdocument.getElementById('recorded-preview').classList.add('hidden');\n\t\t\tshowIdleState();\n\t\t\tnotifyValidationSystem();\n\t\t}\n\n\t\tfunction showError(message) {\n\t\t\tconst errorDiv = document.getElementById('microphone-error');\n\t\t\tconst errorMessage = document.getElementById('mic-error-message');\n\t\t\terrorMessage.textContent = message;\n\t\t\terrorDiv.classList.remove('hidden');\n\t\t\tshowIdleState();\n\t\t}\n\n\t\tfunction hideError() {\n\t\t\tdocument.getElementById('microphone-error').classList.add('hidden');\n\t\t}\n\n\t\t// Expose recorded blob for form submission\n\t\twindow.getRecordedAudio = function() {\n\t\t\treturn recordedBlob;\n\t\t};\n\n\t\t// Notify validation system when recording state changes\n\t\tfunction notifyValidationSystem() {\n\t\t\t// Check if we have a valid recording\n\t\t\tconst isValid = recordedBlob !== null && recordedBlob.size > 0;\n\t\t\t\n\t\t\t// Update Alpine.js store validation state\n\t\t\tif (typeof Alpine !== 'undefined' && Alpine.store('voiceClone')) {\n\t\t\t\tAlpine.store('voiceClone').validateAudioInput(isValid);\n\t\t\t}\n\t\t}\n\n\t\t// Check microphone permission status on load\n\t\tdocument.addEventListener('DOMContentLoaded', async function() {\n\t\t\ttry {\n\t\t\t\tconst permissionStatus = await navigator.permissions.query({ name: 'microphone' });\n\t\t\t\tif (permissionStatus.state === 'denied') {\n\t\t\t\t\tshowError('Microphone access denied. Please enable microphone access in your browser settings.');\n\t\t\t\t}\n\t\t\t} catch (error) {\n\t\t\t\t// Permissions API not supported, ignore\n\t\t\t}\n\t\t});\n\t</script>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div id=\"microphone-recorder\" class=\"mt-4\"><label class=\"block pl-4 text-sm font-bold leading-6 text-gray-900 mb-2\">Record Audio</label><!-- Recording Interface --><div class=\"mt-2 p-6 bg-gradient-to-br from-gray-50 to-gray-100 rounded-lg border border-gray-200\"><div class=\"text-center\"><!-- Recording Status --><div id=\"recording-status\" class=\"mb-4\"><div id=\"status-idle\" class=\"recording-state\"><div class=\"mx-auto w-20 h-20 bg-gradient-to-br from-green-400 to-green-600 rounded-full flex items-center justify-center shadow-lg cursor-pointer hover:shadow-xl transition-all duration-200 hover:scale-105\" onclick=\"startRecording()\"><svg class=\"w-8 h-8 text-white\" fill=\"none\" stroke=\"currentColor\" viewBox=\"0 0 24 24\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M19 11a7 7 0 01-7 7m0 0a7 7 0 01-7-7m7 7v4m0 0H8m4 0h4m-4-8a3 3 0 01-3-3V5a3 3 0 116 0v6a3 3 0 01-3 3z\"></path></svg></div><p class=\"mt-3 text-sm font-medium text-gray-700\">Click to start recording</p><p class=\"text-xs text-gray-500\">Make sure to allow microphone access</p></div><div id=\"status-recording\" class=\"recording-state hidden\"><div class=\"mx-auto w-20 h-20 bg-gradient-to-br from-red-400 to-red-600 rounded-full flex items-center justify-center shadow-lg animate-pulse\"><svg class=\"w-8 h-8 text-white\" fill=\"currentColor\" viewBox=\"0 0 24 24\"><rect x=\"6\" y=\"6\" width=\"12\" height=\"12\" rx=\"2\"></rect></svg></div><p class=\"mt-3 text-sm font-medium text-red-700\">Recording...</p><div class=\"flex items-center justify-center space-x-2 mt-2\"><span id=\"recording-timer\" class=\"text-lg font-mono text-red-600\">00:00</span></div><button type=\"button\" onclick=\"stopRecording()\" class=\"mt-3 px-4 py-2 text-sm font-medium text-red-700 bg-red-100 border border-red-300 rounded-md hover:bg-red-200 transition-colors duration-200\">Stop Recording</button></div><div id=\"status-processing\" class=\"recording-state hidden\"><div class=\"mx-auto w-20 h-20 bg-gradient-to-br from-blue-400 to-blue-600 rounded-full flex items-center justify-center shadow-lg\"><svg class=\"w-8 h-8 text-white animate-spin\" fill=\"none\" viewBox=\"0 0 24 24\"><circle class=\"opacity-25\" cx=\"12\" cy=\"12\" r=\"10\" stroke=\"currentColor\" stroke-width=\"4\"></circle> <path class=\"opacity-75\" fill=\"currentColor\" d=\"M4 12a8 8 0 018-8V0C5.373 0 0 5.373 0 12h4zm2 5.291A7.962 7.962 0 014 12H0c0 3.042 1.135 5.824 3 7.938l3-2.647z\"></path></svg></div><p class=\"mt-3 text-sm font-medium text-blue-700\">Processing audio...</p></div></div><!-- Live level meter, driven by the server's readings of each streamed chunk --><div id=\"audio-visualizer\" class=\"hidden mb-4\"><div class=\"mx-auto w-64 h-3 bg-gray-200 rounded-full overflow-hidden\"><div id=\"level-meter-bar\" class=\"h-3 bg-green-500 rounded-full transition-all duration-100\" style=\"width: 0%\"></div></div><p id=\"level-meter-hint\" class=\"mt-1 text-xs text-gray-500\">Start speaking</p></div><!-- Recording Tips --><div class=\"text-xs text-gray-500 space-y-1\"><p>💡 <strong>Tip:</strong> Record at least 10 seconds for best results</p><p>🔇 <strong>Quiet environment</strong> recommended</p><p>📱 <strong>Speak clearly</strong> and at normal volume</p></div></div></div><!-- Offered when a streamed recording was interrupted by a reload or crash --><div id=\"unfinished-recording\" class=\"hidden mt-4 p-3 bg-amber-50 border border-amber-200 rounded-md\"><p class=\"text-sm text-amber-800\">An unfinished recording from earlier was saved on the server.</p><div class=\"mt-2 flex space-x-3\"><button type=\"button\" onclick=\"recoverRecording(false)\" class=\"text-sm font-medium text-indigo-600 hover:text-indigo-500\">Use it</button> <button type=\"button\" onclick=\"recoverRecording(true)\" class=\"text-sm font-medium text-gray-600 hover:text-gray-500\">Discard</button></div></div><!-- Recorded Audio Preview --><div id=\"recorded-preview\" class=\"hidden mt-4 p-4 bg-gray-50 border border-gray-200 rounded-lg\"><div class=\"flex items-center justify-between mb-3\"><h4 class=\"text-sm font-medium text-gray-900\">Recorded Audio</h4><div class=\"flex space-x-2\"><button type=\"button\" onclick=\"playRecording()\" class=\"text-sm text-indigo-600 hover:text-indigo-500 font-medium\">Play</button> <button type=\"button\" onclick=\"retryRecording()\" class=\"text-sm text-gray-600 hover:text-gray-500 font-medium\">Re-record</button></div></div><div class=\"bg-white p-3 rounded border\"><audio id=\"recorded-audio\" controls class=\"w-full h-8\">Your browser does not support the audio element.</audio></div><div class=\"mt-2 text-xs text-gray-500\">Duration: <span id=\"audio-duration\">0:00</span> |  Quality: <span id=\"audio-quality\">Analyzing...</span></div></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = QualityReport("recording-quality-report").Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<!-- Set once the server has accepted the recording --><input type=\"hidden\" id=\"recording-id-input\" name=\"recording-id\" value=\"\"><!-- Error Messages --><div id=\"microphone-error\" class=\"hidden mt-4 p-3 bg-red-50 border border-red-200 rounded-md\"><div class=\"flex items-center\"><svg class=\"w-5 h-5 text-red-400 mr-2\" fill=\"currentColor\" viewBox=\"0 0 20 20\"><path fill-rule=\"evenodd\" d=\"M10 18a8 8 0 100-16 8 8 0 000 16zM8.707 7.293a1 1 0 00-1.414 1.414L8.586 10l-1.293 1.293a1 1 0 101.414 1.414L10 11.414l1.293 1.293a1 1 0 001.414-1.414L11.414 10l1.293-1.293a1 1 0 00-1.414-1.414L10 8.586 8.707 7.293z\" clip-rule=\"evenodd\"></path></svg> <span class=\"text-red-800 text-sm font-medium\" id=\"mic-error-message\">Error message</span></div></div></div><script>\n\t\tlet recordingTimer = null;\n\t\tlet startTime = null;\n\t\tlet recordedBlob = null;\n\n\t\t// Audio is captured as raw PCM and streamed to the server while recording,\n\t\t// so nothing is lost if the page dies and no single request carries the whole clip.\n\t\t// The server writes each chunk to disk and answers with a level reading for the meter.\n\t\tconst STREAM_CHUNK_SECONDS = 0.1;\n\t\tconst STREAM_MAX_RECONNECTS = 5;\n\t\tconst UNFINISHED_RECORDING_KEY = 'unfinishedRecording';\n\t\tconst CAPTURE_WORKLET = `\n\t\t\tclass PCMCapture extends AudioWorkletProcessor {\n\t\t\t\tprocess(inputs) {\n\t\t\t\t\tconst channel = inputs[0][0];\n\t\t\t\t\tif (channel) this.port.postMessage(channel.slice(0));\n\t\t\t\t\treturn true;\n\t\t\t\t}\n\t\t\t}\n\t\t\tregisterProcessor('pcm-capture', PCMCapture);\n\t\t`;\n\n\t\tlet micStream = null;\n\t\tlet audioContext = null;\n\t\tlet captureNode = null;\n\t\tlet recordingSocket = null;\n\t\tlet streamRecordingId = null;\n\t\tlet pendingChunks = [];\n\t\tlet localChunks = [];\n\t\tlet sampleBatch = [];\n\t\tlet sampleBatchLength = 0;\n\t\tlet capturing = false;\n\t\tlet finishing = false;\n\t\tlet reconnects = 0;\n\n\t\tasync function startRecording() {\n\t\t\ttry {\n\t\t\t\thideError();\n\t\t\t\t\n\t\t\t\t// Request microphone access\n\t\t\t\tmicStream = await navigator.mediaDevices.getUserMedia({ \n\t\t\t\t\taudio: {\n\t\t\t\t\t\techoCancellation: true,\n\t\t\t\t\t\tnoiseSuppression: true,\n\t\t\t\t\t\tautoGainControl: true\n\t\t\t\t\t}\n\t\t\t\t});\n\n\t\t\t\tif (typeof AudioWorkletNode === 'undefined' || typeof WebSocket === 'undefined') {\n\t\t\t\t\tthrow new Error('Audio recording not supported in this browser');\n\t\t\t\t}\n\n\t\t\t\taudioContext = new AudioContext();\n\t\t\t\tif (audioContext.sampleRate < 16000 || audioContext.sampleRate > 48000) {\n\t\t\t\t\tthrow new Error('Your microphone runs at ' + audioContext.sampleRate + ' Hz, which is not supported');\n\t\t\t\t}\n\n\t\t\t\tconst workletUrl = URL.createObjectURL(new Blob([CAPTURE_WORKLET], { type: 'application/javascript' }));\n\t\t\t\tawait audioContext.audioWorklet.addModule(workletUrl);\n\t\t\t\tURL.revokeObjectURL(workletUrl);\n\n\t\t\t\tconst source = audioContext.createMediaStreamSource(micStream);\n\t\t\t\tcaptureNode = new AudioWorkletNode(audioContext, 'pcm-capture');\n\t\t\t\tcaptureNode.port.onmessage = event => captureSamples(event.data);\n\t\t\t\tsource.connect(captureNode);\n\t\t\t\t// The node outputs silence; connecting it keeps the graph pulling audio through it\n\t\t\t\tcaptureNode.connect(audioContext.destination);\n\n\t\t\t\tstreamRecordingId = null;\n\t\t\t\tpendingChunks = [];\n\t\t\t\tlocalChunks = [];\n\t\t\t\tsampleBatch = [];\n\t\t\t\tsampleBatchLength = 0;\n\t\t\t\treconnects = 0;\n\t\t\t\tfinishing = false;\n\t\t\t\tcapturing = true;\n\n\t\t\t\tawait openRecordingStream({ type: 'start', sampleRate: audioContext.sampleRate });\n\n\t\t\t\tstartTime = Date.now();\n\t\t\t\t\n\t\t\t\t// Update UI\n\t\t\t\tshowRecordingState();\n\t\t\t\tstartTimer();\n\t\t\t\tshowVisualizer();\n\n\t\t\t} catch (error) {\n\t\t\t\tconsole.error('Recording error:', error);\n\t\t\t\treleaseMicrophone();\n\t\t\t\tcapturing = false;\n\t\t\t\tlet errorMessage = 'Could not access microphone. ';\n\t\t\t\t\n\t\t\t\tif (error.name === 'NotAllowedError') {\n\t\t\t\t\terrorMessage += 'Please allow microphone access and try again.';\n\t\t\t\t} else if (error.name === 'NotFoundError') {\n\t\t\t\t\terrorMessage += 'No microphone found. Please connect a microphone and try again.';\n\t\t\t\t} else {\n\t\t\t\t\terrorMessage += error.message || 'Unknown error occurred.';\n\t\t\t\t}\n\t\t\t\t\n\t\t\t\tshowError(errorMessage);\n\t\t\t}\n\t\t}\n\n\t\tfunction stopRecording(event) {\n\t\t\tif (event) {\n\t\t\t\tevent.preventDefault();\n\t\t\t\tevent.stopPropagation();\n\t\t\t}\n\t\t\t\n\t\t\tif (!capturing) return;\n\n\t\t\tcapturing = false;\n\t\t\tflushSampleBatch();\n\t\t\treleaseMicrophone();\n\t\t\tstopTimer();\n\t\t\thideVisualizer();\n\t\t\tshowProcessingState();\n\n\t\t\trecordedBlob = encodeWAV(localChunks, audioContext.sampleRate);\n\t\t\tshowRecordedPreview();\n\t\t\tfinishRecordingStream();\n\t\t}\n\n\t\tfunction releaseMicrophone() {\n\t\t\tif (micStream) micStream.getTracks().forEach(track => track.stop());\n\t\t\tif (captureNode) captureNode.disconnect();\n\t\t\tif (audioContext && audioContext.state !== 'closed') audioContext.close();\n\t\t\tmicStream = null;\n\t\t\tcaptureNode = null;\n\t\t}\n\n\t\t// captureSamples converts the worklet's float samples to 16-bit PCM and\n\t\t// sends them in chunks of STREAM_CHUNK_SECONDS\n\t\tfunction captureSamples(samples) {\n\t\t\tif (!capturing) return;\n\n\t\t\tconst pcm = new Int16Array(samples.length);\n\t\t\tfor (let i = 0; i < samples.length; i++) {\n\t\t\t\tconst s = Math.max(-1, Math.min(1, samples[i]));\n\t\t\t\tpcm[i] = s < 0 ? s * 0x8000 : s * 0x7FFF;\n\t\t\t}\n\t\t\tsampleBatch.push(pcm);\n\t\t\tsampleBatchLength += pcm.length;\n\n\t\t\tif (sampleBatchLength >= audioContext.sampleRate * STREAM_CHUNK_SECONDS) {\n\t\t\t\tflushSampleBatch();\n\t\t\t}\n\t\t}\n\n\t\tfunction flushSampleBatch() {\n\t\t\tif (sampleBatchLength === 0) return;\n\n\t\t\tconst chunk = new Int16Array(sampleBatchLength);\n\t\t\tlet offset = 0;\n\t\t\tsampleBatch.forEach(part => {\n\t\t\t\tchunk.set(part, offset);\n\t\t\t\toffset += part.length;\n\t\t\t});\n\t\t\tsampleBatch = [];\n\t\t\tsampleBatchLength = 0;\n\n\t\t\tlocalChunks.push(chunk);\n\t\t\tsendChunk(chunk.buffer);\n\t\t}\n\n\t\tfunction sendChunk(buffer) {\n\t\t\tif (recordingSocket && recordingSocket.readyState === WebSocket.OPEN && streamRecordingId) {\n\t\t\t\trecordingSocket.send(buffer);\n\t\t\t} else {\n\t\t\t\tpendingChunks.push(buffer);\n\t\t\t}\n\t\t}\n\n\t\t// openRecordingStream connects and sends hello (\"start\" or \"resume\"),\n\t\t// resolving once the server has the recording open\n\t\tfunction openRecordingStream(hello) {\n\t\t\treturn new Promise((resolve, reject) => {\n\t\t\t\tconst protocol = location.protocol === 'https:' ? 'wss:' : 'ws:';\n\t\t\t\tconst socket = new WebSocket(protocol + '//' + location.host + '/ws/recording');\n\t\t\t\tsocket.binaryType = 'arraybuffer';\n\t\t\t\trecordingSocket = socket;\n\t\t\t\tlet started = false;\n\n\t\t\t\tsocket.onopen = () => socket.send(JSON.stringify(hello));\n\n\t\t\t\tsocket.onmessage = event => {\n\t\t\t\t\tconst message = JSON.parse(event.data);\n\t\t\t\t\tswitch (message.type) {\n\t\t\t\t\tcase 'started':\n\t\t\t\t\t\tstarted = true;\n\t\t\t\t\t\treconnects = 0;\n\t\t\t\t\t\tstreamRecordingId = message.recordingId;\n\t\t\t\t\t\tlocalStorage.setItem(UNFINISHED_RECORDING_KEY, streamRecordingId);\n\t\t\t\t\t\tpendingChunks.splice(0).forEach(buffer => socket.send(buffer));\n\t\t\t\t\t\tresolve();\n\t\t\t\t\t\tbreak;\n\t\t\t\t\tcase 'level':\n\t\t\t\t\t\tupdateLevelMeter(message);\n\t\t\t\t\t\tbreak;\n\t\t\t\t\tcase 'finished':\n\t\t\t\t\t\tlocalStorage.removeItem(UNFINISHED_RECORDING_KEY);\n\t\t\t\t\t\tshowSaveResult(true, message);\n\t\t\t\t\t\tbreak;\n\t\t\t\t\tcase 'error':\n\t\t\t\t\t\tif (!started) {\n\t\t\t\t\t\t\treject(new Error(message.error));\n\t\t\t\t\t\t} else if (message.reason === 'too_long') {\n\t\t\t\t\t\t\tshowError(message.error);\n\t\t\t\t\t\t\tstopRecording();\n\t\t\t\t\t\t} else {\n\t\t\t\t\t\t\tif (message.reason) localStorage.removeItem(UNFINISHED_RECORDING_KEY);\n\t\t\t\t\t\t\tshowSaveResult(false, message);\n\t\t\t\t\t\t}\n\t\t\t\t\t\tbreak;\n\t\t\t\t\t}\n\t\t\t\t};\n\n\t\t\t\tsocket.onclose = () => {\n\t\t\t\t\tif (recordingSocket !== socket) return;\n\t\t\t\t\trecordingSocket = null;\n\t\t\t\t\tif (!started) {\n\t\t\t\t\t\treject(new Error('Could not connect to the server'));\n\t\t\t\t\t} else if (capturing) {\n\t\t\t\t\t\treconnectRecordingStream();\n\t\t\t\t\t}\n\t\t\t\t};\n\t\t\t});\n\t\t}\n\n\t\t// Keep capturing through a dropped connection; chunks queue up until the stream is resumed\n\t\tasync function reconnectRecordingStream() {\n\t\t\twhile (capturing && reconnects < STREAM_MAX_RECONNECTS) {\n\t\t\t\treconnects++;\n\t\t\t\tawait new Promise(resolve => setTimeout(resolve, 1000 * reconnects));\n\t\t\t\ttry {\n\t\t\t\t\tawait openRecordingStream({ type: 'resume', recordingId: streamRecordingId });\n\t\t\t\t\treturn;\n\t\t\t\t} catch (err) {\n\t\t\t\t\tconsole.warn('Reconnecting recording stream failed:', err);\n\t\t\t\t}\n\t\t\t}\n\t\t\tif (capturing) {\n\t\t\t\tshowError('Lost connection to the server. Your recording so far will still be used.');\n\t\t\t\tstopRecording();\n\t\t\t}\n\t\t}\n\n\t\tasync function finishRecordingStream() {\n\t\t\tconst finish = Object.assign({ type: 'finish' }, enhancementFlags());\n\t\t\ttry {\n\t\t\t\tif (!recordingSocket || recordingSocket.readyState !== WebSocket.OPEN) {\n\t\t\t\t\tawait openRecordingStream({ type: 'resume', recordingId: streamRecordingId });\n\t\t\t\t}\n\t\t\t\tfinishing = true;\n\t\t\t\trecordingSocket.send(JSON.stringify(finish));\n\t\t\t} catch (err) {\n\t\t\t\t// Streaming failed altogether; fall back to uploading the local copy\n\t\t\t\tconsole.warn('Finishing recording stream failed, uploading instead:', err);\n\t\t\t\tsaveRecording();\n\t\t\t}\n\t\t}\n\n\t\tfunction enhancementFlags() {\n\t\t\tconst params = enhancementParams();\n\t\t\treturn { autoGain: !!params['auto-gain'], noiseReduction: !!params['noise-reduction'] };\n\t\t}\n\n\t\tfunction updateLevelMeter(level) {\n\t\t\tconst bar = document.getElementById('level-meter-bar');\n\t\t\tconst hint = document.getElementById('level-meter-hint');\n\t\t\tconst percent = Math.max(0, Math.min(100, (level.rms + 60) / 60 * 100));\n\t\t\tbar.style.width = percent + '%';\n\n\t\t\tbar.classList.remove('bg-green-500', 'bg-amber-400', 'bg-red-500');\n\t\t\tif (level.clipping) {\n\t\t\t\tbar.classList.add('bg-red-500');\n\t\t\t\thint.textContent = 'Too loud: move back from the microphone';\n\t\t\t} else if (level.rms < -45) {\n\t\t\t\tbar.classList.add('bg-amber-400');\n\t\t\t\thint.textContent = level.elapsed < 1 ? 'Start speaking' : 'Quiet: speak up or move closer';\n\t\t\t} else {\n\t\t\t\tbar.classList.add('bg-green-500');\n\t\t\t\thint.textContent = 'Good level';\n\t\t\t}\n\t\t}\n\n\t\t// encodeWAV wraps the captured PCM for local playback\n\t\tfunction encodeWAV(chunks, sampleRate) {\n\t\t\tconst dataLength = chunks.reduce((total, chunk) => total + chunk.byteLength, 0);\n\t\t\tconst header = new DataView(new ArrayBuffer(44));\n\t\t\tconst writeString = (offset, text) => { for (let i = 0; i < text.length; i++) header.setUint8(offset + i, text.charCodeAt(i)); };\n\t\t\twriteString(0, 'RIFF');\n\t\t\theader.setUint32(4, 36 + dataLength, true);\n\t\t\twriteString(8, 'WAVE');\n\t\t\twriteString(12, 'fmt ');\n\t\t\theader.setUint32(16, 16, true);\n\t\t\theader.setUint16(20, 1, true);\n\t\t\theader.setUint16(22, 1, true);\n\t\t\theader.setUint32(24, sampleRate, true);\n\t\t\theader.setUint32(28, sampleRate * 2, true);\n\t\t\theader.setUint16(32, 2, true);\n\t\t\theader.setUint16(34, 16, true);\n\t\t\twriteString(36, 'data');\n\t\t\theader.setUint32(40, dataLength, true);\n\t\t\treturn new Blob([header.buffer].concat(chunks), { type: 'audio/wav' });\n\t\t}\n\n\t\t// A recording streamed before the page was closed or reloaded can still be used\n\t\tfunction checkUnfinishedRecording() {\n\t\t\tif (localStorage.getItem(UNFINISHED_RECORDING_KEY)) {\n\t\t\t\tdocument.getElementById('unfinished-recording').classList.remove('hidden');\n\t\t\t}\n\t\t}\n\n\t\tasync function recoverRecording(discard) {\n\t\t\tdocument.getElementById('unfinished-recording').classList.add('hidden');\n\t\t\tconst recordingId = localStorage.getItem(UNFINISHED_RECORDING_KEY);\n\t\t\tlocalStorage.removeItem(UNFINISHED_RECORDING_KEY);\n\t\t\tif (!recordingId) return;\n\n\t\t\ttry {\n\t\t\t\tif (!discard) showProcessingState();\n\t\t\t\tawait openRecordingStream({ type: 'resume', recordingId: recordingId });\n\t\t\t\tif (discard) {\n\t\t\t\t\trecordingSocket.send(JSON.stringify({ type: 'cancel' }));\n\t\t\t\t\treturn;\n\t\t\t\t}\n\t\t\t\tfinishing = true;\n\t\t\t\trecordingSocket.send(JSON.stringify(Object.assign({ type: 'finish' }, enhancementFlags())));\n\t\t\t} catch (err) {\n\t\t\t\tif (!discard) showError('The unfinished recording could not be recovered: ' + err.message);\n\t\t\t}\n\t\t}\n\n\t\tfunction showRecordingState() {\n\t\t\tdocument.getElementById('status-idle').classList.add('hidden');\n\t\t\tdocument.getElementById('status-recording').classList.remove('hidden');\n\t\t\tdocument.getElementById('status-processing').classList.add('hidden');\n\t\t}\n\n\t\tfunction showProcessingState() {\n\t\t\tdocument.getElementById('status-idle').classList.add('hidden');\n\t\t\tdocument.getElementById('status-recording').classList.add('hidden');\n\t\t\tdocument.getElementById('status-processing').classList.remove('hidden');\n\t\t}\n\n\t\tfunction showIdleState() {\n\t\t\tdocument.getElementById('status-recording').classList.add('hidden');\n\t\t\tdocument.getElementById('status-processing').classList.add('hidden');\n\t\t\tdocument.getElementById('status-idle').classList.remove('hidden');\n\t\t}\n\n\t\tfunction startTimer() {\n\t\t\tconst timerDisplay = document.getElementById('recording-timer');\n\t\t\trecordingTimer = setInterval(() => {\n\t\t\t\tconst elapsed = Math.floor((Date.now() - startTime) / 1000);\n\t\t\t\tconst minutes = Math.floor(elapsed / 60);\n\t\t\t\tconst seconds = elapsed % 60;\n\t\t\t\ttimerDisplay.textContent = `${minutes.toString().padStart(2, '0')}:${seconds.toString().padStart(2, '0')}`;\n\t\t\t}, 1000);\n\t\t}\n\n\t\tfunction stopTimer() {\n\t\t\tif (recordingTimer) {\n\t\t\t\tclearInterval(recordingTimer);\n\t\t\t\trecordingTimer = null;\n\t\t\t}\n\t\t}\n\n\t\tfunction showVisualizer() {\n\t\t\tdocument.getElementById('audio-visualizer').classList.remove('hidden');\n\t\t}\n\n\t\tfunction hideVisualizer() {\n\t\t\tdocument.getElementById('audio-visualizer').classList.add('hidden');\n\t\t}\n\n\t\tfunction showRecordedPreview() {\n\t\t\tconst preview = document.getElementById('recorded-preview');\n\t\t\tconst audio = document.getElementById('recorded-audio');\n\t\t\t\n\t\t\tconst audioUrl = URL.createObjectURL(recordedBlob);\n\t\t\taudio.src = audioUrl;\n\t\t\taudio.parentElement.classList.remove('hidden');\n\n\t\t\tsetRecordingId('');\n\t\t\tdocument.getElementById('audio-quality').textContent = 'Analyzing...';\n\t\t\trenderQualityReport('recording-quality-report', null);\n\t\t\tpreview.classList.remove('hidden');\n\t\t}\n\n\t\t// saveRecording sends the take to the server, which checks its quality\n\t\t// before it can be used; problems are shown so the user can re-record.\n\t\tasync function saveRecording() {\n\t\t\tif (!recordedBlob) return;\n\n\t\t\tsetRecordingId('');\n\t\t\tshowProcessingState();\n\t\t\thideError();\n\t\t\tdocument.getElementById('audio-quality').textContent = 'Analyzing...';\n\t\t\trenderQualityReport('recording-quality-report', null);\n\n\t\t\ttry {\n\t\t\t\tconst params = new URLSearchParams(enhancementParams());\n\t\t\t\tconst response = await fetch('/save-recording?' + params.toString(), {\n\t\t\t\t\tmethod: 'POST',\n\t\t\t\t\theaders: { 'Content-Type': recordedBlob.type || 'audio/webm' },\n\t\t\t\t\tbody: recordedBlob\n\t\t\t\t});\n\n\t\t\t\tlet data = {};\n\t\t\t\ttry {\n\t\t\t\t\tdata = await response.json();\n\t\t\t\t} catch (err) {}\n\n\t\t\t\tshowSaveResult(response.ok && data.success, data);\n\t\t\t} catch (error) {\n\t\t\t\tconsole.error('Saving recording failed:', error);\n\t\t\t\tdocument.getElementById('audio-quality').textContent = 'Not saved';\n\t\t\t\tshowError('Could not save the recording. Please check your connection and try again.');\n\t\t\t}\n\t\t}\n\n\t\t// showSaveResult shows the quality gate's verdict, from the stream or from /save-recording\n\t\tfunction showSaveResult(ok, data) {\n\t\t\tfinishing = false;\n\t\t\tshowIdleState();\n\n\t\t\trenderQualityReport('recording-quality-report', data.quality);\n\t\t\tif (data.quality) {\n\t\t\t\tconst duration = Math.round(data.quality.analysis.duration);\n\t\t\t\tdocument.getElementById('audio-duration').textContent = Math.floor(duration / 60) + ':' + (duration % 60).toString().padStart(2, '0');\n\t\t\t}\n\t\t\tdocument.getElementById('recorded-preview').classList.remove('hidden');\n\n\t\t\tif (!ok) {\n\t\t\t\tdocument.getElementById('audio-quality').textContent = 'Please record again';\n\t\t\t\tshowError(data.error || 'Could not save the recording. Please try again.');\n\t\t\t\treturn;\n\t\t\t}\n\n\t\t\tconst warnings = data.quality.issues.length;\n\t\t\tdocument.getElementById('audio-quality').textContent = warnings === 0 ? 'Good' : 'Usable, see suggestions';\n\t\t\tsetRecordingId(data.recordingId);\n\n\t\t\t// A recovered recording has no local copy, and the stored one isn't\n\t\t\t// served back, so there is nothing to play\n\t\t\tif (!recordedBlob) {\n\t\t\t\tdocument.getElementById('recorded-audio').parentElement.classList.add('hidden');\n\t\t\t}\n\t\t}\n\n\t\tfunction setRecordingId(recordingId) {\n\t\t\tdocument.getElementById('recording-id-input').value = recordingId;\n\t\t\tnotifyValidationSystem();\n\t\t}\n\n\t\t// Changing a clean-up switch re-processes the current take from the local copy\n\t\tdocument.querySelectorAll('#microphone-recorder input[data-enhancement]').forEach(input => {\n\t\t\tinput.addEventListener('change', () => {\n\t\t\t\tif (!capturing && !finishing) saveRecording();\n\t\t\t});\n\t\t});\n\n\t\tcheckUnfinishedRecording();\n\n\t\tfunction playRecording(event) {\n\t\t\tif (event) {\n\t\t\t\tevent.preventDefault();\n\t\t\t\tevent.stopPropagation();\n\t\t\t}\n\t\t\t\n\t\t\tconst audio = document.getElementById('recorded-audio');\n\t\t\tif (audio.paused) {\n\t\t\t\taudio.play();\n\t\t\t} else {\n\t\t\t\taudio.pause();\n\t\t\t}\n\t\t}\n\n\t\tfunction retryRecording(event) {\n\t\t\tif (event) {\n\t\t\t\tevent.preventDefault();\n\t\t\t\tevent.stopPropagation();\n\t\t\t}\n\t\t\t\n\t\t\t// Clean up previous recording\n\t\t\tconst audio = document.getElementById('recorded-audio');\n\t\t\tif (audio.src) {\n\t\t\t\tURL.revokeObjectURL(audio.src);\n\t\t\t\taudio.src = '';\n\t\t\t}\n\t\t\t\n\t\t\tsetRecordingId('');\n\t\t\trenderQualityReport('recording-quality-report', null);\n\t\t\t\n\t\t\trecordedBlob = null;\n\t\t\tlocalChunks = [];\n\t\t\t\n\t\t\t// Hide preview and show idle state\n\t\t\tdocument.getElementById('recorded-preview').classList.add('hidden');\n\t\t\tshowIdleState();\n\t\t\tnotifyValidationSystem();\n\t\t}\n\n\t\tfunction showError(message) {\n\t\t\tconst errorDiv = document.getElementById('microphone-error');\n\t\t\tconst errorMessage = document.getElementById('mic-error-message');\n\t\t\terrorMessage.textContent = message;\n\t\t\terrorDiv.classList.remove('hidden');\n\t\t\tshowIdleState();\n\t\t}\n\n\t\tfunction hideError() {\n\t\t\tdocument.getElementById('microphone-error').classList.add('hidden');\n\t\t}\n\n\t\t// Expose recorded blob for form submission\n\t\twindow.getRecordedAudio = function() {\n\t\t\treturn recordedBlob;\n\t\t};\n\n\t\t// Notify validation system when recording state changes\n\t\tfunction notifyValidationSystem() {\n\t\t\t// The recording only counts once it has passed the server's quality gate\n\t\t\tconst isValid = document.getElementById('recording-id-input').value !== '';\n\t\t\t\n\t\t\t// Update Alpine.js store validation state\n\t\t\tif (typeof Alpine !== 'undefined' && Alpine.store('voiceClone')) {\n\t\t\t\tAlpine.store('voiceClone').validateAudioInput(isValid);\n\t\t\t}\n\t\t}\n\n\t\t// Check microphone permission status on load\n\t\tdocument.addEventListener('DOMContentLoaded', async function() {\n\t\t\ttry {\n\t\t\t\tconst permissionStatus = await navigator.permissions.query({ name: 'microphone' });\n\t\t\t\tif (permissionStatus.state === 'denied') {\n\t\t\t\t\tshowError('Microphone access denied. Please enable microphone access in your browser settings.');\n\t\t\t\t}\n\t\t\t} catch (error) {\n\t\t\t\t// Permissions API not supported, ignore\n\t\t\t}\n\t\t});\n\t</script>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
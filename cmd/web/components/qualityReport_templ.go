@@ -0,0 +1,55 @@
+// Code generated by templ - DO NOT EDIT.
+
+// templ: version: v0.3.924
+package components
+
+//lint:file-ignore SA4006 This context is only used if a nested component is present.
+
+import "github.com/a-h/templ"
+import templruntime "github.com/a-h/templ/runtime"
+
+// QualityReport is the container the quality gate's feedback is rendered
+// into, along with the switches for the optional clean-up filters.
+func QualityReport(id string) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var1 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var1 == nil {
+			templ_7745c5c3_Var1 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"mt-3 flex flex-wrap gap-4 text-sm text-gray-700\"><label class=\"inline-flex items-center space-x-2\"><input type=\"checkbox\" name=\"auto-gain\" class=\"rounded border-gray-300 text-indigo-600 focus:ring-indigo-500\" data-enhancement> <span>Auto-level volume</span></label> <label class=\"inline-flex items-center space-x-2\"><input type=\"checkbox\" name=\"noise-reduction\" class=\"rounded border-gray-300 text-indigo-600 focus:ring-indigo-500\" data-enhancement> <span>Reduce background noise</span></label></div><div id=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var2 string
+		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(id)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `cmd/web/components/qualityReport.templ`, Line: 16, Col: 13}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\" class=\"hidden mt-3 space-y-2\"></div><script>\n\t\t// enhancementParams returns the clean-up switches that are on, as sent to the server\n\t\twindow.enhancementParams = function() {\n\t\t\tconst params = {};\n\t\t\tdocument.querySelectorAll('input[data-enhancement]').forEach(input => {\n\t\t\t\tif (input.checked) params[input.name] = 'on';\n\t\t\t});\n\t\t\treturn params;\n\t\t};\n\n\t\t// renderQualityReport lists the quality gate's findings, errors first\n\t\twindow.renderQualityReport = function(containerId, report) {\n\t\t\tconst container = document.getElementById(containerId);\n\t\t\tif (!container) return;\n\t\t\tcontainer.innerHTML = '';\n\t\t\tif (!report) {\n\t\t\t\tcontainer.classList.add('hidden');\n\t\t\t\treturn;\n\t\t\t}\n\n\t\t\tconst issues = report.issues.slice().sort((a, b) => (a.severity === 'error' ? 0 : 1) - (b.severity === 'error' ? 0 : 1));\n\t\t\tif (issues.length === 0) {\n\t\t\t\tissues.push({ severity: 'ok', message: 'Sounds good: clear speech and little background noise' });\n\t\t\t}\n\n\t\t\tconst styles = {\n\t\t\t\terror: 'bg-red-50 border-red-200 text-red-800',\n\t\t\t\twarning: 'bg-amber-50 border-amber-200 text-amber-800',\n\t\t\t\tok: 'bg-green-50 border-green-200 text-green-800'\n\t\t\t};\n\t\t\tissues.forEach(issue => {\n\t\t\t\tconst item = document.createElement('div');\n\t\t\t\titem.className = 'p-2 text-sm border rounded-md ' + styles[issue.severity];\n\t\t\t\titem.textContent = issue.message;\n\t\t\t\tcontainer.appendChild(item);\n\t\t\t});\n\n\t\t\tconst a = report.analysis;\n\t\t\tconst details = document.createElement('p');\n\t\t\tdetails.className = 'text-xs text-gray-500';\n\t\t\tdetails.textContent = a.duration.toFixed(1) + 's · speech ' + a.speech_level.toFixed(0) + ' dBFS · SNR ' + a.snr.toFixed(0) + ' dB · ' + Math.round(a.silence_ratio * 100) + '% silence';\n\t\t\tcontainer.appendChild(details);\n\n\t\t\tcontainer.classList.remove('hidden');\n\t\t};\n\t</script>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+var _ = templruntime.GeneratedTemplate
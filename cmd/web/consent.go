@@ -0,0 +1,284 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/henrik392/youtube-voice-go/cmd/web/components"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/consent"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/provenance"
+	"github.com/henrik392/youtube-voice-go/internal/stt"
+)
+
+// consentExpiry is how long a consent link can be used. It is single use, so
+// it only needs to last until the owner gets round to opening it.
+const consentExpiry = 24 * time.Hour
+
+// errConsentRequired explains why generation was refused in consent mode
+var errConsentRequired = errors.New("only saved voices whose owner has verified consent can be used. Save this voice, then request consent under Saved Voice")
+
+// consentRequired reports whether CONSENT_REQUIRED is on. In that mode voices
+// can only be cloned from profiles with a verified consent recording.
+func consentRequired() bool {
+	return formBool(os.Getenv("CONSENT_REQUIRED"))
+}
+
+// checkConsent returns errConsentRequired if consent mode is on and profile
+// has no verified consent. A nil profile stands for a reference that isn't a
+// saved voice, which is never consented. A verified consent recording becomes
+// the profile's reference, so a consented profile only ever clones the voice
+// that read the phrase, whoever's voice it was saved from.
+func checkConsent(profile *database.VoiceProfile) error {
+	if consentRequired() && (profile == nil || profile.ConsentID == "") {
+		return errConsentRequired
+	}
+	return nil
+}
+
+// CreateConsentHandler hands out a link to the consent page for a profile,
+// with a fresh phrase for the voice's owner to read
+func CreateConsentHandler(w http.ResponseWriter, r *http.Request) {
+	profile, err := loadVoiceProfile(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("Failed to load voice profile: %v", err)
+		renderConsentLink(w, r, "", time.Time{}, "The voice could not be found")
+		return
+	}
+
+	phrase, err := consent.NewPhrase()
+	if err != nil {
+		log.Printf("Failed to create consent phrase: %v", err)
+		renderConsentLink(w, r, "", time.Time{}, "Failed to create the link")
+		return
+	}
+
+	c := &database.Consent{
+		ID:        uuid.New().String(),
+		ProfileID: profile.ID,
+		Phrase:    phrase,
+		ExpiresAt: time.Now().Add(consentExpiry),
+	}
+	if err := database.New().CreateConsent(r.Context(), c); err != nil {
+		log.Printf("Failed to create consent: %v", err)
+		renderConsentLink(w, r, "", time.Time{}, "Failed to create the link")
+		return
+	}
+
+	log.Printf("Requested consent %s for voice profile %s", c.ID, profile.ID)
+	renderConsentLink(w, r, publicBaseURL(r)+"/consent/"+c.ID, c.ExpiresAt, "")
+}
+
+// ConsentPageHandler shows the voice's owner the phrase to record
+func ConsentPageHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := loadConsent(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if !errors.Is(err, database.ErrNotFound) {
+			log.Printf("Failed to load consent: %v", err)
+		}
+		w.WriteHeader(http.StatusNotFound)
+		renderConsentPage(w, r, ConsentPageData{Unavailable: "This consent link does not exist."})
+		return
+	}
+
+	data := ConsentPageData{Consent: c}
+	switch {
+	case c.Status != database.ConsentPending:
+		data.Unavailable = "This consent link has already been used."
+	case c.Expired():
+		w.WriteHeader(http.StatusGone)
+		data.Unavailable = "This consent link has expired. Please ask for a new one."
+	default:
+		if profile, err := database.New().GetVoiceProfile(r.Context(), c.ProfileID); err == nil {
+			data.ProfileName = profile.Name
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	renderConsentPage(w, r, data)
+}
+
+// VerifyConsentHandler checks a recording of a consent phrase, sent like a
+// microphone reference (recording-id, or the recording itself). A phrase can
+// be checked once; after a mismatch the owner asks for a new link.
+func VerifyConsentHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+	c, err := loadConsent(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("Failed to load consent: %v", err)
+		renderConsentResult(w, r, nil, "This consent link does not exist")
+		return
+	}
+	switch {
+	case c.Status != database.ConsentPending:
+		renderConsentResult(w, r, nil, "This consent link has already been used")
+		return
+	case c.Expired():
+		renderConsentResult(w, r, nil, "This consent link has expired, please ask for a new one")
+		return
+	}
+
+	recordingFile, err := handleMicrophoneInput(r)
+	if err != nil {
+		renderComponent(w, r, retryStatus(w, err, http.StatusOK), ConsentResult(nil, fmt.Sprintf("Failed to process the recording: %v", err)))
+		return
+	}
+
+	if err := verifyConsent(r.Context(), c, recordingFile); err != nil {
+		log.Printf("Failed to verify consent %s: %v", c.ID, err)
+		// The phrase stays pending, so the same recording can be sent again
+		renderComponent(w, r, retryStatus(w, err, http.StatusOK), ConsentResult(nil, "Consent could not be checked right now, please try again"))
+		return
+	}
+	log.Printf("Consent %s for voice profile %s: %s (score %.2f)", c.ID, c.ProfileID, c.Status, c.Score)
+
+	if c.Status == database.ConsentVerified {
+		if profile, err := database.New().GetVoiceProfile(r.Context(), c.ProfileID); err == nil {
+			// The preview is made again from the consent recording
+			go prepareVoiceProfile(*profile)
+		}
+	}
+	renderConsentResult(w, r, c, "")
+}
+
+// ListConsentsHandler returns every consent attempt for a profile as JSON
+func ListConsentsHandler(w http.ResponseWriter, r *http.Request) {
+	profile, err := loadVoiceProfile(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	consents, err := database.New().ListConsents(r.Context(), profile.ID)
+	if err != nil {
+		log.Printf("Failed to list consents: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list consents"})
+		return
+	}
+	writeJSON(w, http.StatusOK, consents)
+}
+
+// verifyConsent transcribes recordingFile, scores it against the phrase and
+// completes c either way. The recording is kept next to the profile as a
+// record of what was said and, once verified, replaces the profile's
+// reference: reading the phrase only proves consent for the reader's own
+// voice, so that is the voice the profile clones from then on.
+func verifyConsent(ctx context.Context, c *database.Consent, recordingFile string) error {
+	transcriber, err := stt.Default()
+	if err != nil {
+		return err
+	}
+	if err := scoreConsent(ctx, transcriber, c, recordingFile); err != nil {
+		return err
+	}
+
+	var previous *database.VoiceProfile
+	if c.Status == database.ConsentVerified {
+		if previous, err = database.New().GetVoiceProfile(ctx, c.ProfileID); err != nil {
+			return err
+		}
+	}
+
+	c.RecordingPath = consentRecordingPath(c.ProfileID, c.ID)
+	if err := os.Rename(recordingFile, c.RecordingPath); err != nil {
+		log.Printf("Failed to keep consent recording %s: %v", recordingFile, err)
+		c.RecordingPath = recordingFile
+	}
+
+	if err := database.New().CompleteConsent(ctx, c); err != nil {
+		if c.RecordingPath != recordingFile {
+			os.Rename(c.RecordingPath, recordingFile)
+		}
+		return err
+	}
+	if previous != nil {
+		removeReplacedReference(ctx, previous)
+	}
+	return nil
+}
+
+// removeReplacedReference removes what profile was made of before its
+// consent recording replaced the reference: the reference and clips, the
+// preview and the voice cloned on ElevenLabs
+func removeReplacedReference(ctx context.Context, profile *database.VoiceProfile) {
+	os.Remove(profile.ReferencePath)
+	removeProfileClips(profile.ID)
+	if profile.SamplePath != "" {
+		os.Remove(profile.SamplePath)
+		os.Remove(profile.SamplePath + provenance.SidecarExt)
+	}
+	if voiceID := profile.ProviderVoiceIDs[elevenLabsProvider]; voiceID != "" {
+		removeElevenLabsVoice(ctx, voiceID)
+	}
+}
+
+// scoreConsent transcribes recordingFile and verifies or rejects c by how
+// well the transcript matches its phrase
+func scoreConsent(ctx context.Context, transcriber stt.Transcriber, c *database.Consent, recordingFile string) error {
+	transcript, err := transcriber.Transcribe(ctx, recordingFile)
+	if err != nil {
+		return err
+	}
+
+	c.Transcript = transcript.Text
+	score, ok := consent.Match(c.Phrase, transcript.Text)
+	c.Score = score
+	c.Status = database.ConsentRejected
+	if ok {
+		c.Status = database.ConsentVerified
+	}
+	return nil
+}
+
+// consentRecordingPath keeps consent recordings with the profile's other
+// files, so they are removed together
+func consentRecordingPath(profileID, consentID string) string {
+	return filepath.Join(profilesDir, profileID+".consent-"+consentID+audio.CanonicalExt)
+}
+
+func loadConsent(ctx context.Context, id string) (*database.Consent, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, database.ErrNotFound
+	}
+	return database.New().GetConsent(ctx, id)
+}
+
+func renderConsentLink(w http.ResponseWriter, r *http.Request, link string, expiresAt time.Time, errorMessage string) {
+	if err := components.ConsentLink(link, expiresAt, errorMessage).Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Error rendering ConsentLink component: %v", err)
+	}
+}
+
+func renderConsentResult(w http.ResponseWriter, r *http.Request, c *database.Consent, errorMessage string) {
+	if err := ConsentResult(c, errorMessage).Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Error rendering ConsentResult: %v", err)
+	}
+}
+
+func renderConsentPage(w http.ResponseWriter, r *http.Request, data ConsentPageData) {
+	if err := ConsentPage(data).Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Error rendering ConsentPage: %v", err)
+	}
+}
+
+// ConsentPageData is what the consent page shows. Unavailable replaces the
+// recorder when the link can't be used.
+type ConsentPageData struct {
+	Consent     *database.Consent
+	ProfileName string
+	Unavailable string
+}
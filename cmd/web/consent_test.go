@@ -0,0 +1,54 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/henrik392/youtube-voice-go/internal/consent"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/stt"
+)
+
+func TestScoreConsent(t *testing.T) {
+	phrase := consent.Statement + " My code is cobalt lantern orchid saddle."
+	tests := []struct {
+		name   string
+		heard  string
+		status string
+	}{
+		{"read correctly", "I own this voice and I agree to it being cloned. My code is cobalt, lantern, orchid, saddle.", database.ConsentVerified},
+		{"small slips", "I own this voice and agree to it being cloned, my code is cobalt lantern orchid saddle", database.ConsentVerified},
+		{"wrong code", "I own this voice and I agree to it being cloned. My code is cobalt lantern orchid paddle.", database.ConsentRejected},
+		{"something else", "Hello, this is a recording from a video.", database.ConsentRejected},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transcriber := &stt.Fake{Text: test.heard}
+			c := &database.Consent{Phrase: phrase, Status: database.ConsentPending}
+			if err := scoreConsent(context.Background(), transcriber, c, "recording.wav"); err != nil {
+				t.Fatalf("scoreConsent: %v", err)
+			}
+			if c.Status != test.status {
+				t.Errorf("status = %s (score %.2f), want %s", c.Status, c.Score, test.status)
+			}
+			if c.Transcript != test.heard {
+				t.Errorf("transcript = %q, want what was heard", c.Transcript)
+			}
+			if len(transcriber.Paths) != 1 || transcriber.Paths[0] != "recording.wav" {
+				t.Errorf("transcribed %v, want the recording", transcriber.Paths)
+			}
+		})
+	}
+}
+
+func TestScoreConsentTranscriberFails(t *testing.T) {
+	failure := errors.New("provider down")
+	c := &database.Consent{Phrase: consent.Statement, Status: database.ConsentPending}
+	if err := scoreConsent(context.Background(), &stt.Fake{Err: failure}, c, "recording.wav"); !errors.Is(err, failure) {
+		t.Fatalf("scoreConsent = %v, want the transcriber's error", err)
+	}
+	if c.Status != database.ConsentPending {
+		t.Errorf("status = %s, want the consent left pending to try again", c.Status)
+	}
+}
@@ -0,0 +1,198 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/henrik392/youtube-voice-go/cmd/web/components"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/elevenlabs"
+)
+
+const (
+	// conversionSourceType is the SourceType of speech converted from a
+	// performance rather than generated from text
+	conversionSourceType = "conversion"
+
+	// maxPerformanceSeconds is the longest performance that can be converted
+	maxPerformanceSeconds = 300
+
+	// conversionTimeout bounds the provider call; converting takes about as
+	// long as the performance
+	conversionTimeout = 3 * time.Minute
+	// conversionJobTimeout also covers preparing the voice and saving the result
+	conversionJobTimeout = conversionTimeout + 2*time.Minute
+
+	// conversionJobRetention is how long a finished conversion waits for the
+	// page to collect it
+	conversionJobRetention = time.Hour
+)
+
+// conversionJob is a conversion running in the background, as it takes
+// longer than the server's WriteTimeout. Like dubs, jobs are only kept in
+// memory; a finished conversion stays in the history after a restart.
+type conversionJob struct {
+	jobState
+	playback components.Playback
+	recorded bool
+	err      error
+}
+
+var conversionJobs = newJobRegistry[*conversionJob](conversionJobRetention)
+
+func (j *conversionJob) finish(playback components.Playback, recorded bool, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.markFinished()
+	j.playback = playback
+	j.recorded = recorded
+	j.err = err
+}
+
+// ConvertPageHandler serves the speech-to-speech page, where a recorded or
+// uploaded performance is re-voiced in a saved voice
+func ConvertPageHandler(w http.ResponseWriter, r *http.Request) {
+	profiles, err := database.New().ListVoiceProfiles(r.Context())
+	errorMessage := ""
+	if err != nil {
+		log.Printf("Failed to list voice profiles: %v", err)
+		errorMessage = "Saved voices are unavailable right now"
+	} else if os.Getenv("ELEVENLABS_API_KEY") == "" {
+		errorMessage = "Speech-to-speech is not configured on this server"
+	}
+
+	if err := ConvertPage(profiles, errorMessage, consentRequired()).Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Error rendering ConvertPage: %v", err)
+	}
+}
+
+// ConvertSpeechHandler re-voices the performance in the form (input-mode
+// file or microphone, as for a reference) in the saved voice profile-id
+// with ElevenLabs speech-to-speech, keeping its delivery and timing. It
+// answers with a placeholder that polls until the result replaces it, which
+// is recorded in the history like generated speech.
+func ConvertSpeechHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+	apiKey := os.Getenv("ELEVENLABS_API_KEY")
+	if apiKey == "" {
+		serveError(w, r, "Speech-to-speech is not configured on this server")
+		return
+	}
+
+	profile, err := loadVoiceProfile(r.Context(), r.FormValue("profile-id"))
+	if err != nil {
+		log.Printf("Failed to load voice profile: %v", err)
+		serveError(w, r, "The selected voice could not be found")
+		return
+	}
+	if err := checkConsent(profile); err != nil {
+		serveError(w, r, "This voice's owner has not consented to it being cloned yet")
+		return
+	}
+
+	mode := r.FormValue("input-mode")
+	if mode != "file" && mode != "microphone" {
+		serveError(w, r, "Invalid performance input mode")
+		return
+	}
+	performance, err := resolveReference(r, mode)
+	if err != nil {
+		serveError(w, r, fmt.Sprintf("Failed to process the performance: %v", err))
+		return
+	}
+	if info, err := audio.Probe(r.Context(), performance); err != nil {
+		serveError(w, r, "Failed to read the performance")
+		return
+	} else if info.Duration.Seconds() > maxPerformanceSeconds {
+		serveError(w, r, fmt.Sprintf("The performance must be %d seconds or less", maxPerformanceSeconds))
+		return
+	}
+
+	gen := newGeneration("", conversionSourceType, referenceSource(r, mode, performance))
+	gen.Provider = elevenLabsProvider
+	gen.Params["profile_id"] = profile.ID
+	gen.Params["profile_name"] = profile.Name
+	if formBool(r.FormValue(captionsParam)) {
+		gen.Params[captionsParam] = "true"
+	}
+
+	job := &conversionJob{}
+	conversionJobs.add(gen.ID, job)
+
+	log.Printf("Converting %s into voice profile %s as %s", performance, profile.ID, gen.ID)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), conversionJobTimeout)
+		defer cancel()
+
+		playback, recorded, err := runConversion(ctx, apiKey, gen, profile, performance)
+		if err != nil {
+			log.Printf("Failed to convert %s: %v", gen.ID, err)
+		}
+		job.finish(playback, recorded, err)
+	}()
+
+	renderConversion(w, r, gen.ID, job)
+}
+
+// runConversion prepares the voice, converts the performance and saves the
+// result. Errors are worded for the user.
+func runConversion(ctx context.Context, apiKey string, gen *database.Generation, profile *database.VoiceProfile, performance string) (components.Playback, bool, error) {
+	client := elevenlabs.NewClient(apiKey)
+	client.Timeout = conversionTimeout
+	var audioData []byte
+	err := withElevenLabsVoice(ctx, profile, func(voiceID string) error {
+		var err error
+		audioData, err = client.SpeechToSpeech(ctx, voiceID, performance)
+		return err
+	})
+	if err != nil {
+		return components.Playback{}, false, fmt.Errorf("Failed to convert speech: %w", err)
+	}
+
+	log.Printf("Converted speech successfully! Audio data size: %d bytes", len(audioData))
+	return saveGeneration(ctx, gen, audioData, nil)
+}
+
+// ConversionStatusHandler serves the placeholder of a running conversion, or
+// its player once it is done
+func ConversionStatusHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	job := conversionJobs.lookup(id)
+	if job == nil {
+		serveError(w, r, "This conversion is no longer running")
+		return
+	}
+	renderConversion(w, r, id, job)
+}
+
+func renderConversion(w http.ResponseWriter, r *http.Request, id string, job *conversionJob) {
+	job.mu.Lock()
+	finished, playback, recorded, err := job.finished, job.playback, job.recorded, job.err
+	job.mu.Unlock()
+
+	if !finished {
+		if err := ConversionProgress(id).Render(r.Context(), w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	if err != nil {
+		serveError(w, r, err.Error())
+		return
+	}
+	if recorded {
+		// Lets the history list refresh itself
+		w.Header().Set("HX-Trigger", "generationSaved")
+	}
+	if err := components.AudioPlayer(playback, "").Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,299 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/henrik392/youtube-voice-go/cmd/web/components"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/dubbing"
+	"github.com/henrik392/youtube-voice-go/internal/workerpool"
+	"github.com/henrik392/youtube-voice-go/internal/youtube"
+	"github.com/henrik392/youtube-voice-go/internal/zonos"
+)
+
+const (
+	// dubTimeout bounds a whole dub, from download to mux
+	dubTimeout = 20 * time.Minute
+
+	dubSourceType = "dub"
+	// dubVideoParam is the Params key for the dubbed video's path
+	dubVideoParam = "dub_video"
+
+	stageDownloading = "downloading"
+
+	// dubJobRetention is how long a finished job's progress stays around
+	dubJobRetention = 24 * time.Hour
+)
+
+// dubPool keeps dubs, each of which makes a provider call per segment, from
+// piling up. Callers don't wait on it, so it may queue for a long time.
+var dubPool = sync.OnceValue(func() *workerpool.Pool {
+	return workerpool.NewFromEnv("dubbing", "DUBBING", 1, 4, 15*time.Minute)
+})
+
+var languageCode = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,4})?$`)
+
+// dubJob is a dub running in the background. Jobs are only kept in memory;
+// after a restart the finished video is still served, its progress isn't.
+type dubJob struct {
+	jobState
+	status components.DubStatus
+	result *dubbing.Result
+}
+
+var dubJobs = newJobRegistry[*dubJob](dubJobRetention)
+
+func (j *dubJob) setProgress(progress dubbing.Progress) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Stage = progress.Stage
+	j.status.Done = progress.Done
+	j.status.Total = progress.Total
+}
+
+func (j *dubJob) finish(result *dubbing.Result, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.markFinished()
+	j.status.Finished = true
+	j.result = result
+	if err != nil {
+		j.status.Error = "Dubbing failed: " + err.Error()
+	}
+}
+
+func (j *dubJob) snapshot() (components.DubStatus, *dubbing.Result) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status, j.result
+}
+
+// DubFormHandler serves the dubbing form with the saved voices to pick from
+func DubFormHandler(w http.ResponseWriter, r *http.Request) {
+	// Without the database the speaker's own voice is still on offer
+	profiles, err := database.New().ListVoiceProfiles(r.Context())
+	if err != nil {
+		log.Printf("Failed to list voice profiles: %v", err)
+	}
+	if err := components.DubForm(profiles).Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// DubVideoHandler starts dubbing a video into another language and answers
+// with a progress component that polls until the video is ready. The voice
+// is a saved profile or, by default, the speaker's own, cloned from the video.
+func DubVideoHandler(w http.ResponseWriter, r *http.Request) {
+	videoURL := r.FormValue("url")
+	videoID := youtube.ExtractVideoID(videoURL)
+	if videoID == "" {
+		renderDubStatus(w, r, components.DubStatus{Error: "Invalid video URL"})
+		return
+	}
+
+	target := r.FormValue("target-language")
+	source := r.FormValue("source-language")
+	if !languageCode.MatchString(target) || (source != "" && !languageCode.MatchString(source)) {
+		renderDubStatus(w, r, components.DubStatus{Error: "Invalid language"})
+		return
+	}
+
+	var profile *database.VoiceProfile
+	if profileID := r.FormValue("profile-id"); profileID != "" {
+		var err error
+		if profile, err = loadVoiceProfile(r.Context(), profileID); err != nil {
+			log.Printf("Failed to load voice profile: %v", err)
+			renderDubStatus(w, r, components.DubStatus{Error: "The selected voice could not be found"})
+			return
+		}
+	}
+	if err := checkConsent(profile); err != nil {
+		renderDubStatus(w, r, components.DubStatus{Error: "Consent mode is on: " + err.Error()})
+		return
+	}
+
+	pipeline, err := dubbing.NewPipeline()
+	if err != nil {
+		renderDubStatus(w, r, components.DubStatus{Error: "Dubbing is not available: " + err.Error()})
+		return
+	}
+
+	gen := newGeneration("", dubSourceType, videoURL)
+	gen.Params["target_language"] = target
+	if profile != nil {
+		gen.Params["profile_name"] = profile.Name
+	}
+
+	job := &dubJob{status: components.DubStatus{ID: gen.ID}}
+	dubJobs.add(gen.ID, job)
+
+	log.Printf("Dubbing %s (ID: %s) into %s as %s", videoURL, videoID, target, gen.ID)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), dubTimeout)
+		defer cancel()
+
+		result, err := runDub(ctx, job, pipeline, gen, profile, videoID, source)
+		if err != nil {
+			log.Printf("Failed to dub %s: %v", gen.ID, err)
+		}
+		job.finish(result, err)
+	}()
+
+	status, _ := job.snapshot()
+	renderDubStatus(w, r, status)
+}
+
+// runDub downloads the video, sets up the voice, runs the pipeline and
+// records the new track in the history
+func runDub(ctx context.Context, job *dubJob, pipeline *dubbing.Pipeline, gen *database.Generation, profile *database.VoiceProfile, videoID, source string) (*dubbing.Result, error) {
+	release, err := dubPool().Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("the server is busy, try again later (%w)", err)
+	}
+	defer release()
+
+	job.setProgress(dubbing.Progress{Stage: stageDownloading})
+	videoPath, err := youtube.NewProcessor(downloadsDir).DownloadVideo(ctx, gen.Source, videoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download video: %w", err)
+	}
+
+	workDir, err := os.MkdirTemp("", "dub_*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	voice, err := dubVoice(ctx, gen, profile, videoPath, workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline.Progress = job.setProgress
+	gen.AudioPath = filepath.Join(downloadsDir, fmt.Sprintf("speech_%s.wav", gen.ID))
+	result, err := pipeline.Run(ctx, dubbing.Job{
+		VideoPath:      videoPath,
+		OutputPath:     dubVideoPath(gen.ID),
+		WorkDir:        workDir,
+		SourceLanguage: source,
+		TargetLanguage: gen.Params["target_language"],
+		Voice:          voice,
+		TrackPath:      gen.AudioPath,
+		// The track is generated speech, so it is watermarked and tagged like any other
+		SaveTrack: func(ctx context.Context, path string, data []byte) error {
+			return writeGeneratedAudio(ctx, path, gen.ID, zonosProvider, data)
+		},
+	})
+	if err != nil {
+		os.Remove(gen.AudioPath)
+		return nil, err
+	}
+
+	translations := make([]string, len(result.Segments))
+	for i, segment := range result.Segments {
+		translations[i] = segment.Translation
+	}
+	gen.Text = strings.Join(translations, " ")
+	gen.Duration = result.Duration
+	gen.Params[dubVideoParam] = result.OutputPath
+	if result.SourceLanguage != "" {
+		gen.Params["source_language"] = result.SourceLanguage
+	}
+	if err := database.New().CreateGeneration(ctx, gen); err != nil {
+		log.Printf("Failed to record dub %s: %v", gen.ID, err)
+	}
+	return result, nil
+}
+
+// dubVoice speaks in the saved voice, or clones the speaker from the video
+func dubVoice(ctx context.Context, gen *database.Generation, profile *database.VoiceProfile, videoPath, workDir string) (dubbing.Synthesizer, error) {
+	client := zonos.NewClient(os.Getenv("FAL_KEY"))
+	if profile != nil {
+		return dubbing.SynthesizerFunc(func(ctx context.Context, text string) ([]byte, error) {
+			return cloneFromProfile(ctx, client, profile, text)
+		}), nil
+	}
+
+	referencePath := filepath.Join(workDir, "reference"+audio.CanonicalExt)
+	if err := audio.Normalize(ctx, videoPath, referencePath); err != nil {
+		return nil, err
+	}
+	ref, err := client.UploadReference(ctx, referencePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare the speaker's voice: %w", err)
+	}
+	gen.Params[referenceURLParam] = ref.URL
+	return dubbing.SynthesizerFunc(func(ctx context.Context, text string) ([]byte, error) {
+		return client.VoiceCloneWithReference(ctx, text, *ref)
+	}), nil
+}
+
+// DubStatusHandler serves the progress component of a running dub
+func DubStatusHandler(w http.ResponseWriter, r *http.Request) {
+	job := dubJobs.lookup(chi.URLParam(r, "id"))
+	if job == nil {
+		renderDubStatus(w, r, components.DubStatus{Error: "This dub is no longer running"})
+		return
+	}
+	status, _ := job.snapshot()
+	renderDubStatus(w, r, status)
+}
+
+// dubView is the JSON form of a dub
+type dubView struct {
+	components.DubStatus
+	Result *dubbing.Result `json:"result,omitempty"`
+}
+
+// GetDubHandler reports a dub's progress and, once done, its segments as JSON
+func GetDubHandler(w http.ResponseWriter, r *http.Request) {
+	job := dubJobs.lookup(chi.URLParam(r, "id"))
+	if job == nil {
+		http.Error(w, "Dub not found", http.StatusNotFound)
+		return
+	}
+	status, result := job.snapshot()
+	writeJSON(w, http.StatusOK, dubView{DubStatus: status, Result: result})
+}
+
+// DubVideoFileHandler serves a dubbed video. It reads the file by ID, so
+// videos outlive the in-memory job.
+func DubVideoFileHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+	path := dubVideoPath(id)
+	if _, err := os.Stat(path); err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+
+	if r.URL.Query().Get("download") != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="dub_%s.mp4"`, id))
+	}
+	http.ServeFile(w, r, path)
+}
+
+func dubVideoPath(id string) string {
+	return filepath.Join(downloadsDir, fmt.Sprintf("dub_%s.mp4", id))
+}
+
+func renderDubStatus(w http.ResponseWriter, r *http.Request, status components.DubStatus) {
+	if err := components.DubProgress(status).Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
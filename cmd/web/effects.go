@@ -0,0 +1,209 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/henrik392/youtube-voice-go/cmd/web/components"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/captions"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+)
+
+// effectsParam is the Params key for a generation's effects chain, as JSON
+const effectsParam = "effects"
+
+// effectsFromForm reads the effect-* form fields. Blank fields leave their
+// effect off.
+func effectsFromForm(r *http.Request) (audio.Effects, error) {
+	var effects audio.Effects
+	numbers := map[string]*float64{
+		"effect-tempo":    &effects.Tempo,
+		"effect-pitch":    &effects.Pitch,
+		"effect-reverb":   &effects.Reverb,
+		"effect-loudness": &effects.Loudness,
+		"effect-fade-in":  &effects.FadeIn,
+		"effect-fade-out": &effects.FadeOut,
+	}
+	if err := formFloats(r, numbers); err != nil {
+		return effects, err
+	}
+	effects.EQ = r.FormValue("effect-eq")
+	effects.Compress = formBool(r.FormValue("effect-compress"))
+	return effects, effects.Validate()
+}
+
+// formFloats parses the number in each of fields into its value. Blank
+// fields leave their value as it is.
+func formFloats(r *http.Request, fields map[string]*float64) error {
+	for field, value := range fields {
+		text := strings.TrimSpace(r.FormValue(field))
+		if text == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("%s is not a number", field)
+		}
+		*value = parsed
+	}
+	return nil
+}
+
+// setEffects records the effects chain gen is rendered with
+func setEffects(gen *database.Generation, effects audio.Effects) {
+	if !effects.Enabled() {
+		delete(gen.Params, effectsParam)
+		return
+	}
+	data, err := json.Marshal(effects)
+	if err != nil {
+		log.Printf("Failed to encode effects of %s: %v", gen.ID, err)
+		return
+	}
+	gen.Params[effectsParam] = string(data)
+}
+
+// generationEffects is the effects chain gen is rendered with
+func generationEffects(gen *database.Generation) audio.Effects {
+	var effects audio.Effects
+	if data := gen.Params[effectsParam]; data != "" {
+		if err := json.Unmarshal([]byte(data), &effects); err != nil {
+			log.Printf("Failed to decode effects of %s: %v", gen.ID, err)
+		}
+	}
+	return effects
+}
+
+// stemsDir keeps the provider's audio of each generation. It lies outside
+// downloads/, which is served, as the dry audio has no watermark or tags.
+var stemsDir = "stems"
+
+// dryPath is where the audio at audioPath is kept as the provider made it,
+// before effects
+func dryPath(audioPath string) string {
+	ext := filepath.Ext(audioPath)
+	return filepath.Join(stemsDir, strings.TrimSuffix(filepath.Base(audioPath), ext)+".dry"+ext)
+}
+
+// renderEffects keeps the provider's audio as gen's dry stem, so the effects
+// can be changed later, and runs it through gen's effects.
+func renderEffects(ctx context.Context, gen *database.Generation, data []byte) ([]byte, error) {
+	effects := generationEffects(gen)
+	dry := dryPath(gen.AudioPath)
+	err := os.MkdirAll(stemsDir, 0755)
+	if err == nil {
+		err = os.WriteFile(dry, data, 0644)
+	}
+	if err != nil {
+		if effects.Enabled() {
+			return nil, err
+		}
+		// Only changing the effects later needs it
+		log.Printf("Failed to keep the dry audio of %s: %v", gen.ID, err)
+	}
+	if !effects.Enabled() {
+		return data, nil
+	}
+	return applyEffects(ctx, dry, effects)
+}
+
+// applyEffects renders the audio at inputPath through effects
+func applyEffects(ctx context.Context, inputPath string, effects audio.Effects) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "effects_*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "output"+audio.CanonicalExt)
+	if err := audio.ApplyEffects(ctx, inputPath, output, effects); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(output)
+}
+
+// GenerationEffectsHandler re-renders a generation from its dry audio with a
+// new effects chain, without calling the provider again, and plays the
+// result. Music is mixed in again under the new voice.
+func GenerationEffectsHandler(w http.ResponseWriter, r *http.Request) {
+	gen, err := loadGeneration(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("Failed to load generation: %v", err)
+		serveError(w, r, "The generation could not be found")
+		return
+	}
+	if gen.SourceType == dubSourceType {
+		serveError(w, r, "Effects can't be applied to a dubbed video's track")
+		return
+	}
+	effects, err := effectsFromForm(r)
+	if err != nil {
+		serveError(w, r, "Invalid effects: "+err.Error())
+		return
+	}
+
+	// The saved audio is watermarked and tagged already, so only the
+	// provider's own audio can be rendered again
+	dry := dryPath(gen.AudioPath)
+	if _, err := os.Stat(dry); err != nil {
+		log.Printf("No dry audio for %s: %v", gen.ID, err)
+		serveError(w, r, "This generation's original audio wasn't kept, so its effects can't be changed")
+		return
+	}
+
+	var data []byte
+	if effects.Enabled() {
+		data, err = applyEffects(r.Context(), dry, effects)
+	} else {
+		data, err = os.ReadFile(dry)
+	}
+	if err != nil {
+		log.Printf("Failed to apply effects to %s: %v", gen.ID, err)
+		serveError(w, r, "Failed to apply effects: "+err.Error())
+		return
+	}
+	if err := writeGeneratedAudio(r.Context(), gen.AudioPath, gen.ID, gen.Provider, data); err != nil {
+		serveError(w, r, "Failed to save speech to file: "+err.Error())
+		return
+	}
+
+	if info, err := audio.Probe(r.Context(), gen.AudioPath); err == nil {
+		gen.Duration = info.Duration.Seconds()
+	} else {
+		log.Printf("Failed to measure generation %s: %v", gen.ID, err)
+	}
+
+	// Provider timings follow the tempo; estimated ones are estimated again
+	var words []captions.Word
+	if gen.Params[wordTimingsParam] == wordTimingsProvider {
+		if words, err = readWords(gen); err == nil {
+			words = retimeWords(words, generationEffects(gen), effects)
+		}
+	}
+	setEffects(gen, effects)
+	words = alignGeneration(r.Context(), gen, words)
+	mixGeneration(r.Context(), gen)
+
+	if err := database.New().UpdateGeneration(r.Context(), gen); err != nil {
+		log.Printf("Failed to update generation %s: %v", gen.ID, err)
+	}
+	log.Printf("Re-rendered generation %s with new effects", gen.ID)
+	w.Header().Set("HX-Trigger", "generationSaved")
+
+	// The audio URL is unchanged, so make sure the browser loads it again
+	playback := generationPlayback(gen, words)
+	playback.AudioURL += fmt.Sprintf("?v=%d", time.Now().Unix())
+	if err := components.AudioPlayer(playback, "").Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,81 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/henrik392/youtube-voice-go/internal/captions"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/elevenlabs"
+)
+
+// elevenLabsConfigured reports whether saved voices can be used on ElevenLabs
+func elevenLabsConfigured() bool {
+	return os.Getenv("ELEVENLABS_API_KEY") != ""
+}
+
+// elevenLabsVoice is the profile's voice on ElevenLabs. Voices saved before
+// ElevenLabs was configured are cloned there now.
+func elevenLabsVoice(ctx context.Context, profile *database.VoiceProfile) (string, error) {
+	if profile.ProviderVoiceIDs[elevenLabsProvider] == "" {
+		log.Printf("Cloning voice profile %s on ElevenLabs...", profile.ID)
+		if err := registerElevenLabsVoice(ctx, profile); err != nil {
+			return "", err
+		}
+	}
+	return profile.ProviderVoiceIDs[elevenLabsProvider], nil
+}
+
+// withElevenLabsVoice calls call with the profile's voice on ElevenLabs. A
+// voice since removed from the account is forgotten and cloned again, and
+// call retried once with it.
+func withElevenLabsVoice(ctx context.Context, profile *database.VoiceProfile, call func(voiceID string) error) error {
+	voiceID, err := elevenLabsVoice(ctx, profile)
+	if err != nil {
+		return fmt.Errorf("failed to prepare the voice: %w", err)
+	}
+	err = call(voiceID)
+	if !errors.Is(err, elevenlabs.ErrVoiceNotFound) {
+		return err
+	}
+
+	log.Printf("ElevenLabs voice %s of profile %s is gone", voiceID, profile.ID)
+	if err := database.New().SetProviderVoiceID(ctx, profile.ID, elevenLabsProvider, ""); err != nil {
+		return err
+	}
+	profile.ProviderVoiceIDs[elevenLabsProvider] = ""
+
+	voiceID, err = elevenLabsVoice(ctx, profile)
+	if err != nil {
+		return fmt.Errorf("failed to prepare the voice: %w", err)
+	}
+	return call(voiceID)
+}
+
+// speakWithElevenLabs speaks text in the profile's voice on ElevenLabs. Its
+// word timings come back with the audio, so captions need no estimating.
+func speakWithElevenLabs(ctx context.Context, profile *database.VoiceProfile, text string) ([]byte, []captions.Word, error) {
+	client := elevenlabs.NewClient(os.Getenv("ELEVENLABS_API_KEY"))
+	var audioData []byte
+	var alignment *elevenlabs.Alignment
+	err := withElevenLabsVoice(ctx, profile, func(voiceID string) error {
+		var err error
+		audioData, alignment, err = client.TextToSpeechWithTimestamps(ctx, voiceID, text)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Without an alignment the timings are estimated as for other providers
+	var words []captions.Word
+	if alignment != nil {
+		if aligned := captions.FromCharacters(alignment.Characters, alignment.StartTimes, alignment.EndTimes); len(aligned) > 0 {
+			words = aligned
+		}
+	}
+	return audioData, words, nil
+}
@@ -1,17 +1,20 @@
 package web
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 
-	"github.com/google/uuid"
+	"github.com/a-h/templ"
 	"github.com/henrik392/youtube-voice-go/cmd/web/components"
-	"github.com/henrik392/youtube-voice-go/internal/zonos"
+	"github.com/henrik392/youtube-voice-go/internal/workerpool"
 	"github.com/henrik392/youtube-voice-go/internal/youtube"
+	"github.com/henrik392/youtube-voice-go/internal/zonos"
 )
 
 func GenerateVoiceHandler(w http.ResponseWriter, r *http.Request) {
@@ -27,11 +30,16 @@ func GenerateVoiceHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Text:", text)
 	fmt.Println("Youtube ID:", videoID)
 
+	if err := checkConsent(nil); err != nil {
+		serveError(w, r, "Consent mode is on: "+err.Error())
+		return
+	}
+
 	ytProcessor := youtube.NewProcessor("./downloads")
-	audioFile, err := ytProcessor.DownloadAudio(videoURL, videoID)
+	audioFile, err := ytProcessor.DownloadAudio(r.Context(), videoURL, videoID)
 
 	if err != nil {
-		serveError(w, r, "Failed to download audio: "+err.Error())
+		serveRetryableError(w, r, err, "Failed to download audio: "+err.Error())
 		return
 	}
 
@@ -41,43 +49,62 @@ func GenerateVoiceHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Creating Zonos client...")
 	diaClient := zonos.NewClient(os.Getenv("FAL_KEY"))
 
-	// Generate speech using Zonos voice cloning (uploadFile is called internally)
+	// Generate speech using Zonos voice cloning
 	log.Printf("Starting voice cloning with Zonos...")
-	audioData, err := diaClient.VoiceClone(text, audioFile)
+	ref, err := diaClient.UploadReference(r.Context(), audioFile)
+	var audioData []byte
+	if err == nil {
+		audioData, err = diaClient.VoiceCloneWithReference(r.Context(), text, *ref)
+	}
 	if err != nil {
 		log.Printf("Failed to generate speech: %v", err)
-		serveError(w, r, "Failed to generate speech: "+err.Error())
+		serveRetryableError(w, r, err, "Failed to generate speech: "+err.Error())
 		return
 	}
 
 	log.Printf("Generated speech successfully! Audio data size: %d bytes", len(audioData))
 
 	// Save the generated speech
-	uuid := uuid.New()
-	speechFilePath := filepath.Join("./downloads", fmt.Sprintf("%s_speech_%s.wav", videoID, uuid.String()))
-	err = diaClient.SaveAudioFile(audioData, speechFilePath)
-	if err != nil {
-		serveError(w, r, "Failed to save speech to file: "+err.Error())
-		return
-	}
+	gen := newGeneration(text, "url", videoURL)
+	setReference(gen, *ref)
+	gen.AudioPath = filepath.Join("./downloads", fmt.Sprintf("%s_speech_%s.wav", videoID, gen.ID))
+	finishGeneration(w, r, gen, audioData, nil)
+}
 
-	fmt.Println("Saved speech to file:", speechFilePath)
-	fmt.Println("Serving audio...")
+func serveError(w http.ResponseWriter, r *http.Request, errorMessage string) {
+	log.Printf("Error: %v", errorMessage)
+	renderComponent(w, r, http.StatusOK, components.AudioPlayer(components.Playback{}, errorMessage))
+}
 
-	audioURL := fmt.Sprintf("/serve-audio?path=%s", url.QueryEscape(speechFilePath))
+// serveRetryableError is serveError for a failure that may come from a
+// saturated worker pool, which is answered 503 with a Retry-After header
+func serveRetryableError(w http.ResponseWriter, r *http.Request, err error, errorMessage string) {
+	log.Printf("Error: %v", errorMessage)
+	renderComponent(w, r, retryStatus(w, err, http.StatusOK), components.AudioPlayer(components.Playback{}, errorMessage))
+}
 
-	audioPlayer := components.AudioPlayer(audioURL, "")
-	err = audioPlayer.Render(r.Context(), w)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+// renderComponent answers with component and status. It renders into a
+// buffer first, so a failure can still be answered with a 500.
+func renderComponent(w http.ResponseWriter, r *http.Request, status int, component templ.Component) {
+	var buf bytes.Buffer
+	if err := component.Render(r.Context(), &buf); err != nil {
+		log.Printf("Error rendering component: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+	w.WriteHeader(status)
+	buf.WriteTo(w)
 }
 
-func serveError(w http.ResponseWriter, r *http.Request, errorMessage string) {
-	log.Printf("Error: %v", errorMessage)
-	audioPlayer := components.AudioPlayer("", errorMessage)
-
-	if err := audioPlayer.Render(r.Context(), w); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+// retryStatus sets a Retry-After header and returns 503 when err was caused
+// by a saturated worker pool, and returns status otherwise. The caller writes
+// the status along with its answer.
+func retryStatus(w http.ResponseWriter, err error, status int) int {
+	var saturated *workerpool.SaturatedError
+	if !errors.As(err, &saturated) {
+		return status
 	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(saturated.RetryAfter.Seconds())))
+	return http.StatusServiceUnavailable
 }
@@ -1,23 +1,28 @@
 package web
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/google/uuid"
-	"github.com/henrik392/youtube-voice-go/cmd/web/components"
-	"github.com/henrik392/youtube-voice-go/internal/zonos"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/captions"
+	"github.com/henrik392/youtube-voice-go/internal/database"
 	"github.com/henrik392/youtube-voice-go/internal/youtube"
+	"github.com/henrik392/youtube-voice-go/internal/zonos"
 )
 
 func GenerateVoiceEnhancedHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
 	text := r.FormValue("text")
 	audioMode := r.FormValue("audio-mode")
 
@@ -34,66 +39,145 @@ func GenerateVoiceEnhancedHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var audioFile string
-	var err error
-
-	// Handle different input modes
 	switch audioMode {
-	case "url":
-		audioFile, err = handleURLInput(r)
-	case "file":
-		audioFile, err = handleFileInput(r)
-	case "microphone":
-		audioFile, err = handleMicrophoneInput(r)
+	case "url", "file", "microphone", "profile":
 	default:
 		serveError(w, r, "Invalid audio input mode")
 		return
 	}
 
+	// Checked up front so bad values don't cost a provider call
+	effects, err := effectsFromForm(r)
 	if err != nil {
-		serveError(w, r, fmt.Sprintf("Failed to process audio input: %v", err))
+		serveError(w, r, "Invalid effects: "+err.Error())
+		return
+	}
+	mix, musicPath, musicName, err := musicFromForm(r)
+	if err != nil {
+		serveError(w, r, "Invalid background music: "+err.Error())
 		return
 	}
-
-	log.Printf("Processing audio file: %s", audioFile)
 
 	// Create Zonos client
 	log.Printf("Creating Zonos client...")
 	diaClient := zonos.NewClient(os.Getenv("FAL_KEY"))
 
-	// Generate speech using Zonos voice cloning
-	log.Printf("Starting voice cloning with Zonos...")
-	audioData, err := diaClient.VoiceClone(text, audioFile)
-	if err != nil {
-		log.Printf("Failed to generate speech: %v", err)
-		serveError(w, r, "Failed to generate speech: "+err.Error())
-		return
-	}
+	var gen *database.Generation
+	var audioData []byte
+	var words []captions.Word
+	if audioMode == "profile" {
+		profile, err := loadVoiceProfile(r.Context(), r.FormValue("profile-id"))
+		if err != nil {
+			log.Printf("Failed to load voice profile: %v", err)
+			serveError(w, r, "The selected voice could not be found")
+			return
+		}
+		if err := checkConsent(profile); err != nil {
+			serveError(w, r, "This voice's owner has not consented to it being cloned yet")
+			return
+		}
 
-	log.Printf("Generated speech successfully! Audio data size: %d bytes", len(audioData))
+		provider := r.FormValue("provider")
+		switch {
+		case provider == elevenLabsProvider && elevenLabsConfigured():
+			// ElevenLabs reports word timings along with the audio
+			log.Printf("Starting speech with ElevenLabs from profile %s...", profile.ID)
+			audioData, words, err = speakWithElevenLabs(r.Context(), profile, text)
+		case provider == "" || provider == zonosProvider:
+			// Saved voices reuse their processed, already uploaded reference
+			log.Printf("Starting voice cloning with Zonos from profile %s...", profile.ID)
+			audioData, err = cloneFromProfile(r.Context(), diaClient, profile, text)
+		default:
+			serveError(w, r, fmt.Sprintf("The provider %q is not available", provider))
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to generate speech: %v", err)
+			serveRetryableError(w, r, err, "Failed to generate speech: "+err.Error())
+			return
+		}
 
-	// Generate unique filename for the output
-	uuid := uuid.New()
-	speechFilePath := filepath.Join("./downloads", fmt.Sprintf("speech_%s.wav", uuid.String()))
-	err = diaClient.SaveAudioFile(audioData, speechFilePath)
-	if err != nil {
-		serveError(w, r, "Failed to save speech to file: "+err.Error())
-		return
+		gen = newGeneration(text, audioMode, profile.ID)
+		gen.Params["profile_name"] = profile.Name
+		if provider == elevenLabsProvider {
+			gen.Provider = elevenLabsProvider
+		} else {
+			setReference(gen, zonos.Reference{URL: profile.ProviderVoiceIDs[zonosProvider], Text: profile.ReferenceText})
+		}
+	} else {
+		// Consent is recorded against saved voices, so nothing else can be used in consent mode
+		if err := checkConsent(nil); err != nil {
+			serveError(w, r, "Consent mode is on: "+err.Error())
+			return
+		}
+
+		audioFile, err := resolveReference(r, audioMode)
+		if err != nil {
+			serveRetryableError(w, r, err, fmt.Sprintf("Failed to process audio input: %v", err))
+			return
+		}
+
+		log.Printf("Processing audio file: %s", audioFile)
+
+		ranges, err := referenceRangesFromForm(r, audioFile)
+		if err != nil {
+			serveError(w, r, "Invalid reference selection: "+err.Error())
+			return
+		}
+
+		// Generate speech using Zonos voice cloning
+		log.Printf("Starting voice cloning with Zonos...")
+		ref, err := diaClient.UploadReferenceRanges(r.Context(), audioFile, ranges)
+		if err == nil {
+			audioData, err = diaClient.VoiceCloneWithReference(r.Context(), text, *ref)
+		}
+		if err != nil {
+			log.Printf("Failed to generate speech: %v", err)
+			serveRetryableError(w, r, err, "Failed to generate speech: "+err.Error())
+			return
+		}
+
+		gen = newGeneration(text, audioMode, referenceSource(r, audioMode, audioFile))
+		setReference(gen, *ref)
+		setReferenceRanges(gen, ranges)
 	}
 
-	log.Printf("Saved speech to file: %s", speechFilePath)
+	log.Printf("Generated speech successfully! Audio data size: %d bytes", len(audioData))
+	setEffects(gen, effects)
+	setMusic(gen, mix, musicPath, musicName)
+	finishGeneration(w, r, gen, audioData, words)
+}
 
-	audioURL := fmt.Sprintf("/serve-audio?path=%s", url.QueryEscape(speechFilePath))
-	audioPlayer := components.AudioPlayer(audioURL, "")
+// referenceSource identifies where a reference came from for the history:
+// the video URL, or the upload or recording ID it is stored under.
+func referenceSource(r *http.Request, audioMode, audioFile string) string {
+	if audioMode == "url" {
+		return r.FormValue("url")
+	}
+	return strings.TrimSuffix(filepath.Base(audioFile), filepath.Ext(audioFile))
+}
 
-	err = audioPlayer.Render(r.Context(), w)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// resolveReference returns the reference file for a url, file or microphone
+// audio-mode, downloading or storing it first if needed.
+func resolveReference(r *http.Request, audioMode string) (string, error) {
+	switch audioMode {
+	case "url":
+		return handleURLInput(r)
+	case "file":
+		return handleFileInput(r)
+	case "microphone":
+		return handleMicrophoneInput(r)
+	default:
+		return "", fmt.Errorf("invalid audio input mode")
 	}
 }
 
 func handleURLInput(r *http.Request) (string, error) {
-	videoURL := r.FormValue("url")
+	return downloadReference(r.Context(), r.FormValue("url"))
+}
+
+// downloadReference downloads the audio of videoURL, or finds it downloaded
+func downloadReference(ctx context.Context, videoURL string) (string, error) {
 	if videoURL == "" {
 		return "", fmt.Errorf("please provide a video URL")
 	}
@@ -106,9 +190,9 @@ func handleURLInput(r *http.Request) (string, error) {
 	log.Printf("Processing video URL: %s (ID: %s)", videoURL, videoID)
 
 	ytProcessor := youtube.NewProcessor("./downloads")
-	audioFile, err := ytProcessor.DownloadAudio(videoURL, videoID)
+	audioFile, err := ytProcessor.DownloadAudio(ctx, videoURL, videoID)
 	if err != nil {
-		return "", fmt.Errorf("failed to download audio: %v", err)
+		return "", fmt.Errorf("failed to download audio: %w", err)
 	}
 
 	return audioFile, nil
@@ -123,62 +207,37 @@ func handleFileInput(r *http.Request) (string, error) {
 		return handleDirectFileUpload(r)
 	}
 
-	// Construct file path from fileId
-	// We need to find the file in downloads directory
-	files, err := filepath.Glob("downloads/" + fileId + ".*")
-	if err != nil || len(files) == 0 {
+	// Uploads are stored under their ID in the canonical format
+	if _, err := uuid.Parse(fileId); err != nil {
+		return "", fmt.Errorf("invalid file ID")
+	}
+	// A resumable upload may still be being checked
+	if err := awaitUpload(r.Context(), fileId); err != nil {
+		return "", err
+	}
+	filePath := filepath.Join(downloadsDir, fileId+audio.CanonicalExt)
+	if _, err := os.Stat(filePath); err != nil {
 		return "", fmt.Errorf("uploaded file not found")
 	}
 
-	return files[0], nil
+	return filePath, nil
 }
 
 func handleDirectFileUpload(r *http.Request) (string, error) {
-	err := r.ParseMultipartForm(50 << 20) // 50MB
+	err := r.ParseMultipartForm(maxUploadSize)
 	if err != nil {
-		return "", fmt.Errorf("file too large")
+		return "", uploadReadError(err)
 	}
 
-	file, header, err := r.FormFile("audio-file")
+	file, _, err := r.FormFile("audio-file")
 	if err != nil {
 		return "", fmt.Errorf("no file provided")
 	}
 	defer file.Close()
 
-	// Validate file type
-	contentType := header.Header.Get("Content-Type")
-	validTypes := []string{
-		"audio/mpeg", "audio/mp3", "audio/wav", "audio/wave",
-		"audio/mp4", "audio/m4a", "audio/ogg", "audio/flac",
-	}
-	
-	isValid := false
-	for _, validType := range validTypes {
-		if strings.Contains(contentType, validType) || strings.Contains(strings.ToLower(header.Filename), strings.TrimPrefix(validType, "audio/")) {
-			isValid = true
-			break
-		}
-	}
-
-	if !isValid {
-		return "", fmt.Errorf("invalid file type. Please upload MP3, WAV, M4A, OGG, or FLAC files")
-	}
-
-	// Save file
-	fileID := uuid.New().String()
-	ext := filepath.Ext(header.Filename)
-	filename := fileID + ext
-	filePath := filepath.Join("downloads", filename)
-
-	dst, err := os.Create(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to save file")
-	}
-	defer dst.Close()
-
-	_, err = io.Copy(dst, file)
+	filePath, _, err := storeReference(r.Context(), uuid.New().String(), file, enhancementFrom(r.FormValue))
 	if err != nil {
-		return "", fmt.Errorf("failed to save file")
+		return "", err
 	}
 
 	return filePath, nil
@@ -193,8 +252,11 @@ func handleMicrophoneInput(r *http.Request) (string, error) {
 		return handleDirectRecordingUpload(r)
 	}
 
-	// Construct file path from recordingId
-	recordingFile := "downloads/" + recordingId + ".webm"
+	// Recordings are stored under their ID in the canonical format
+	if _, err := uuid.Parse(recordingId); err != nil {
+		return "", fmt.Errorf("invalid recording ID")
+	}
+	recordingFile := filepath.Join(downloadsDir, recordingId+audio.CanonicalExt)
 	if _, err := os.Stat(recordingFile); os.IsNotExist(err) {
 		return "", fmt.Errorf("recorded audio not found")
 	}
@@ -246,14 +308,9 @@ func handleDirectRecordingUpload(r *http.Request) (string, error) {
 		return "", fmt.Errorf("no recording data provided")
 	}
 
-	// Save recording to file
-	recordingID := uuid.New().String()
-	filename := recordingID + ".webm"
-	filePath := filepath.Join("downloads", filename)
-
-	err = os.WriteFile(filePath, recordingData, 0644)
+	filePath, _, err := storeReference(r.Context(), uuid.New().String(), bytes.NewReader(recordingData), enhancementFrom(r.FormValue))
 	if err != nil {
-		return "", fmt.Errorf("failed to save recording")
+		return "", err
 	}
 
 	return filePath, nil
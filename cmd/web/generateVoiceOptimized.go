@@ -7,14 +7,15 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/google/uuid"
-	"github.com/henrik392/youtube-voice-go/cmd/web/components"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/youtube"
 	"github.com/henrik392/youtube-voice-go/internal/zonos"
 )
 
 func GenerateVoiceOptimizedHandler(w http.ResponseWriter, r *http.Request) {
 	text := r.FormValue("text")
 	audioURL := r.FormValue("audio_url")
+	refText := r.FormValue("ref_text")
 	videoID := r.FormValue("video_id")
 
 	log.Printf("Generating voice with pre-processed audio")
@@ -26,36 +27,59 @@ func GenerateVoiceOptimizedHandler(w http.ResponseWriter, r *http.Request) {
 		serveError(w, r, "Missing required parameters: text or audio_url")
 		return
 	}
+	// The ID becomes part of the file name
+	if videoID != "" && !youtube.ValidVideoID(videoID) {
+		serveError(w, r, "Invalid video ID")
+		return
+	}
+
+	if err := checkConsent(nil); err != nil {
+		serveError(w, r, "Consent mode is on: "+err.Error())
+		return
+	}
 
 	// Create Zonos client
 	diaClient := zonos.NewClient(os.Getenv("FAL_KEY"))
 
+	// The pre-processed reference is the start of the video; a picked
+	// selection is cropped and uploaded again
+	ref := zonos.Reference{URL: audioURL, Text: refText}
+	var ranges audio.Ranges
+	if r.FormValue("reference-ranges") != "" {
+		audioFile, err := videoAudioPath(videoID)
+		if err == nil {
+			ranges, err = referenceRangesFromForm(r, audioFile)
+		}
+		if err != nil {
+			serveError(w, r, "Invalid reference selection: "+err.Error())
+			return
+		}
+		if len(ranges) > 0 {
+			uploaded, err := diaClient.UploadReferenceRanges(r.Context(), audioFile, ranges)
+			if err != nil {
+				log.Printf("Failed to prepare reference: %v", err)
+				serveRetryableError(w, r, err, "Failed to prepare audio: "+err.Error())
+				return
+			}
+			ref = *uploaded
+		}
+	}
+
 	// Generate speech using pre-processed audio URL
 	log.Printf("Starting voice cloning with Zonos using pre-processed data...")
-	audioData, err := diaClient.VoiceCloneWithURL(text, audioURL)
+	audioData, err := diaClient.VoiceCloneWithReference(r.Context(), text, ref)
 	if err != nil {
 		log.Printf("Failed to generate speech: %v", err)
-		serveError(w, r, "Failed to generate speech: "+err.Error())
+		serveRetryableError(w, r, err, "Failed to generate speech: "+err.Error())
 		return
 	}
 
 	log.Printf("Generated speech successfully! Audio data size: %d bytes", len(audioData))
 
-	// Save the generated speech
-	uuid := uuid.New()
-	speechFilePath := filepath.Join("./downloads", fmt.Sprintf("%s_speech_%s.wav", videoID, uuid.String()))
-	err = diaClient.SaveAudioFile(audioData, speechFilePath)
-	if err != nil {
-		serveError(w, r, "Failed to save speech to file: "+err.Error())
-		return
-	}
-
-	log.Printf("Saved speech to file: %s", speechFilePath)
-
-	audioURL = fmt.Sprintf("/serve-audio?path=%s", speechFilePath)
-	audioPlayer := components.AudioPlayer(audioURL, "")
-	err = audioPlayer.Render(r.Context(), w)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	}
-}
\ No newline at end of file
+	gen := newGeneration(text, "url", "")
+	gen.Params["video_id"] = videoID
+	setReference(gen, ref)
+	setReferenceRanges(gen, ranges)
+	gen.AudioPath = filepath.Join("./downloads", fmt.Sprintf("%s_speech_%s.wav", videoID, gen.ID))
+	finishGeneration(w, r, gen, audioData, nil)
+}
@@ -0,0 +1,345 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/henrik392/youtube-voice-go/cmd/web/components"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/captions"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/provenance"
+	"github.com/henrik392/youtube-voice-go/internal/storage"
+	"github.com/henrik392/youtube-voice-go/internal/zonos"
+)
+
+const (
+	defaultHistoryPage = 20
+	maxHistoryPage     = 100
+
+	// referenceURLParam and referenceTextParam are the Params keys for the
+	// reference zonos generated from and its transcript
+	referenceURLParam  = "reference_audio_url"
+	referenceTextParam = "reference_text"
+)
+
+// outputParams name files made for one generation alone. A regeneration
+// doesn't share them, or deleting it would delete the original's.
+var outputParams = []string{dubVideoParam}
+
+// newGeneration starts a history entry for speech about to be generated with zonos
+func newGeneration(text, sourceType, source string) *database.Generation {
+	return &database.Generation{
+		ID:         uuid.New().String(),
+		Text:       text,
+		SourceType: sourceType,
+		Source:     source,
+		Provider:   zonosProvider,
+		Params:     map[string]string{},
+	}
+}
+
+// setReference records the reference a generation was made from, so it can
+// be made again
+func setReference(gen *database.Generation, ref zonos.Reference) {
+	gen.Params[referenceURLParam] = ref.URL
+	if ref.Text != "" {
+		gen.Params[referenceTextParam] = ref.Text
+	}
+}
+
+// finishGeneration saves generated speech with saveGeneration and renders
+// the player. words are the provider's word timings, or nil to estimate them.
+func finishGeneration(w http.ResponseWriter, r *http.Request, gen *database.Generation, audioData []byte, words []captions.Word) {
+	// Regenerations keep the param, so they get captions again
+	if formBool(r.FormValue(captionsParam)) {
+		gen.Params[captionsParam] = "true"
+	}
+
+	playback, recorded, err := saveGeneration(r.Context(), gen, audioData, words)
+	if err != nil {
+		serveError(w, r, err.Error())
+		return
+	}
+	if recorded {
+		// Lets the history list refresh itself
+		w.Header().Set("HX-Trigger", "generationSaved")
+	}
+
+	if err := components.AudioPlayer(playback, "").Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// saveGeneration applies gen's effects to generated speech, saves it,
+// captions it and records it in the history, returning what to play and
+// whether it was recorded. The history is best effort: if the database is
+// unavailable the file is still served, it just won't be listed. Errors are
+// worded for the user.
+func saveGeneration(ctx context.Context, gen *database.Generation, audioData []byte, words []captions.Word) (components.Playback, bool, error) {
+	if gen.AudioPath == "" {
+		gen.AudioPath = filepath.Join(downloadsDir, fmt.Sprintf("speech_%s.wav", gen.ID))
+	}
+	audioData, err := renderEffects(ctx, gen, audioData)
+	if err != nil {
+		log.Printf("Failed to apply effects to %s: %v", gen.ID, err)
+		return components.Playback{}, false, fmt.Errorf("Failed to apply effects: %w", err)
+	}
+	words = retimeWords(words, audio.Effects{}, generationEffects(gen))
+	if err := writeGeneratedAudio(ctx, gen.AudioPath, gen.ID, gen.Provider, audioData); err != nil {
+		return components.Playback{}, false, fmt.Errorf("Failed to save speech to file: %w", err)
+	}
+	log.Printf("Saved speech to file: %s", gen.AudioPath)
+
+	if info, err := audio.Probe(ctx, gen.AudioPath); err == nil {
+		gen.Duration = info.Duration.Seconds()
+	} else {
+		log.Printf("Failed to measure generation %s: %v", gen.ID, err)
+	}
+
+	words = alignGeneration(ctx, gen, words)
+	mixGeneration(ctx, gen)
+
+	playback := generationPlayback(gen, words)
+	if err := database.New().CreateGeneration(ctx, gen); err != nil {
+		log.Printf("Failed to record generation %s: %v", gen.ID, err)
+		// Captions and drawings are only served for recorded generations
+		playback.AudioURL, playback.CaptionsURL = serveAudioURL(gen.AudioPath), ""
+		playback.PeaksURL, playback.SpectrogramURL = "", ""
+		if playback.VoiceURL != "" {
+			playback.AudioURL, playback.VoiceURL = serveAudioURL(mixPath(gen.AudioPath)), playback.AudioURL
+		}
+		return playback, false, nil
+	}
+	return playback, true, nil
+}
+
+// serveAudioURL plays generated speech without a history entry
+func serveAudioURL(path string) string {
+	return fmt.Sprintf("/serve-audio?path=%s", url.QueryEscape(path))
+}
+
+// GenerationHistoryHandler serves the history list component
+func GenerationHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	limit, offset := historyPage(r)
+	generations, err := database.New().ListGenerations(r.Context(), limit, offset)
+	errorMessage := ""
+	if err != nil {
+		log.Printf("Failed to list generations: %v", err)
+		errorMessage = "History is unavailable right now"
+	}
+
+	component := components.GenerationHistory(generations, errorMessage)
+	if err := component.Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Error rendering GenerationHistory component: %v", err)
+	}
+}
+
+// ListGenerationsHandler returns a page of the history as JSON, newest first
+func ListGenerationsHandler(w http.ResponseWriter, r *http.Request) {
+	limit, offset := historyPage(r)
+	generations, err := database.New().ListGenerations(r.Context(), limit, offset)
+	if err != nil {
+		log.Printf("Failed to list generations: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list generations"})
+		return
+	}
+	writeJSON(w, http.StatusOK, generations)
+}
+
+// GetGenerationHandler returns one generation as JSON
+func GetGenerationHandler(w http.ResponseWriter, r *http.Request) {
+	gen, err := loadGeneration(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, gen)
+}
+
+// GenerationAudioHandler plays a generation, or downloads it with ?download=1
+func GenerationAudioHandler(w http.ResponseWriter, r *http.Request) {
+	gen, err := loadGeneration(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+	serveGenerationAudio(w, r, gen, false, r.URL.Query().Get("download") != "")
+}
+
+// GenerationMixHandler plays a generation's mix with background music, or
+// downloads it with ?download=1
+func GenerationMixHandler(w http.ResponseWriter, r *http.Request) {
+	gen, err := loadGeneration(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+	if !hasMusic(gen) {
+		http.Error(w, "This generation has no music", http.StatusNotFound)
+		return
+	}
+	serveGenerationAudio(w, r, gen, true, r.URL.Query().Get("download") != "")
+}
+
+// serveGenerationAudio streams a generation's voice, or its mix with music
+// if mixed is set, from storage with range support
+func serveGenerationAudio(w http.ResponseWriter, r *http.Request, gen *database.Generation, mixed, download bool) {
+	path, name := gen.AudioPath, fmt.Sprintf("speech_%s%s", gen.ID, filepath.Ext(gen.AudioPath))
+	if mixed {
+		path, name = mixPath(gen.AudioPath), fmt.Sprintf("mix_%s%s", gen.ID, audio.CanonicalExt)
+	}
+	file, info, err := storage.Default().Open(path)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Audio file not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to open generation %s: %v", gen.ID, err)
+		http.Error(w, "Failed to open audio", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	if download {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	}
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// RegenerateHandler speaks a past generation's text again with the same
+// reference, and adds the result to the history as a new entry. Dubs and
+// conversions weren't spoken from text, so they can't be.
+func RegenerateHandler(w http.ResponseWriter, r *http.Request) {
+	prev, err := loadGeneration(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("Failed to load generation: %v", err)
+		serveError(w, r, "The generation could not be found")
+		return
+	}
+	if prev.SourceType == dubSourceType || prev.SourceType == conversionSourceType {
+		serveError(w, r, "Dubs and converted speech can't be regenerated")
+		return
+	}
+
+	// In consent mode the voice has to still be a consented profile
+	var profile *database.VoiceProfile
+	if prev.SourceType == "profile" {
+		if profile, err = loadVoiceProfile(r.Context(), prev.Source); err != nil && !errors.Is(err, database.ErrNotFound) {
+			log.Printf("Failed to load voice profile: %v", err)
+		}
+	}
+	if err := checkConsent(profile); err != nil {
+		serveError(w, r, "Consent mode is on: "+err.Error())
+		return
+	}
+
+	var audioData []byte
+	var words []captions.Word
+	if prev.Provider == elevenLabsProvider {
+		// ElevenLabs generations are made from a saved voice, not a reference
+		if profile == nil || !elevenLabsConfigured() {
+			serveError(w, r, "This generation's voice is no longer available on ElevenLabs")
+			return
+		}
+		log.Printf("Regenerating %s with ElevenLabs...", prev.ID)
+		audioData, words, err = speakWithElevenLabs(r.Context(), profile, prev.Text)
+	} else {
+		ref := zonos.Reference{URL: prev.Params[referenceURLParam], Text: prev.Params[referenceTextParam]}
+		if ref.URL == "" {
+			serveError(w, r, "This generation has no stored reference to generate from")
+			return
+		}
+
+		log.Printf("Regenerating %s with Zonos...", prev.ID)
+		diaClient := zonos.NewClient(os.Getenv("FAL_KEY"))
+		audioData, err = diaClient.VoiceCloneWithReference(r.Context(), prev.Text, ref)
+	}
+	if err != nil {
+		log.Printf("Failed to generate speech: %v", err)
+		serveRetryableError(w, r, err, "Failed to generate speech: "+err.Error())
+		return
+	}
+
+	gen := newGeneration(prev.Text, prev.SourceType, prev.Source)
+	gen.Provider = prev.Provider
+	for key, value := range prev.Params {
+		if !slices.Contains(outputParams, key) {
+			gen.Params[key] = value
+		}
+	}
+	gen.Params["regenerated_from"] = prev.ID
+	finishGeneration(w, r, gen, audioData, words)
+}
+
+// DeleteGenerationHandler removes a generation and its audio file
+func DeleteGenerationHandler(w http.ResponseWriter, r *http.Request) {
+	gen, err := loadGeneration(r.Context(), chi.URLParam(r, "id"))
+	if err == nil {
+		err = database.New().DeleteGeneration(r.Context(), gen.ID)
+	}
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+
+	os.Remove(gen.AudioPath)
+	os.Remove(gen.AudioPath + provenance.SidecarExt)
+	os.Remove(dryPath(gen.AudioPath))
+	os.Remove(mixPath(gen.AudioPath))
+	os.Remove(mixPath(gen.AudioPath) + provenance.SidecarExt)
+	removeVisuals(gen.AudioPath)
+	removeVisuals(mixPath(gen.AudioPath))
+	removeTimings(gen.AudioPath)
+	removeUploadedMusic(r.Context(), gen)
+	if video := gen.Params[dubVideoParam]; video != "" {
+		os.Remove(video)
+	}
+	log.Printf("Deleted generation %s", gen.ID)
+
+	// HTMX swaps the history entry for this empty body
+	w.WriteHeader(http.StatusOK)
+}
+
+func loadGeneration(ctx context.Context, id string) (*database.Generation, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, database.ErrNotFound
+	}
+	return database.New().GetGeneration(ctx, id)
+}
+
+// historyPage reads ?limit= and ?offset=, clamped to sane values
+func historyPage(r *http.Request) (limit, offset int) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultHistoryPage
+	}
+	if limit > maxHistoryPage {
+		limit = maxHistoryPage
+	}
+	offset, err = strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func writeGenerationError(w http.ResponseWriter, err error) {
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Generation not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("Generation lookup error: %v", err)
+	http.Error(w, "Generation lookup failed", http.StatusInternalServerError)
+}
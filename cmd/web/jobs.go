@@ -0,0 +1,64 @@
+package web
+
+import (
+	"sync"
+	"time"
+)
+
+// jobState is embedded in work that runs in the background while requests
+// poll for it. mu guards it and the embedding job's own fields.
+type jobState struct {
+	mu         sync.Mutex
+	finished   bool
+	finishedAt time.Time
+}
+
+// markFinished records that the job is done; the caller holds mu
+func (s *jobState) markFinished() {
+	s.finished = true
+	s.finishedAt = time.Now()
+}
+
+func (s *jobState) expired(retention time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.finished && time.Since(s.finishedAt) > retention
+}
+
+// jobRegistry keeps background jobs in memory by ID. Jobs that finished more
+// than retention ago are dropped as new ones are added, so nothing survives
+// a restart.
+type jobRegistry[J interface{ expired(time.Duration) bool }] struct {
+	retention time.Duration
+
+	mu   sync.Mutex
+	jobs map[string]J
+}
+
+func newJobRegistry[J interface{ expired(time.Duration) bool }](retention time.Duration) *jobRegistry[J] {
+	return &jobRegistry[J]{retention: retention, jobs: map[string]J{}}
+}
+
+// add registers job under id and reports true, unless a job is already
+// registered there, which is returned instead
+func (r *jobRegistry[J]) add(id string, job J) (J, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.jobs[id]; ok {
+		return old, false
+	}
+	for id, old := range r.jobs {
+		if old.expired(r.retention) {
+			delete(r.jobs, id)
+		}
+	}
+	r.jobs[id] = job
+	return job, true
+}
+
+// lookup returns the job registered under id, or the zero J
+func (r *jobRegistry[J]) lookup(id string) J {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.jobs[id]
+}
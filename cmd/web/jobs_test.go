@@ -0,0 +1,34 @@
+package web
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJobRegistry(t *testing.T) {
+	jobs := newJobRegistry[*conversionJob](time.Hour)
+
+	first := &conversionJob{}
+	if job, added := jobs.add("a", first); !added || job != first {
+		t.Fatalf("add(a) = %p, %v, want %p, true", job, added, first)
+	}
+	if job, added := jobs.add("a", &conversionJob{}); added || job != first {
+		t.Errorf("second add(a) = %p, %v, want the first job, false", job, added)
+	}
+	if jobs.lookup("b") != nil {
+		t.Errorf("lookup(b) found a job that was never added")
+	}
+
+	// A job finished long ago goes once another is added, a running one stays
+	first.markFinished()
+	first.finishedAt = first.finishedAt.Add(-2 * time.Hour)
+	running := &conversionJob{}
+	jobs.add("b", running)
+	jobs.add("c", &conversionJob{})
+	if jobs.lookup("a") != nil {
+		t.Errorf("the expired job is still registered")
+	}
+	if jobs.lookup("b") != running {
+		t.Errorf("the running job was dropped")
+	}
+}
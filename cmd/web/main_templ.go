@@ -43,7 +43,7 @@ func MainPage() templ.Component {
 				}()
 			}
 			ctx = templ.InitializeContext(ctx)
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"px-4 py-8 mx-auto max-w-4xl\"><div class=\"mb-12 text-center\"><h1 class=\"mb-4 text-4xl font-bold tracking-tight text-gray-900 sm:text-6xl\">Clone Any Voice with AI</h1><p class=\"mx-auto max-w-2xl text-xl text-gray-600\">Transform any audio into custom AI-generated speech. Choose your audio source and let our AI clone the voice.</p></div><div class=\"mx-auto max-w-3xl\"><form id=\"voice-form\" hx-post=\"/generate-voice-enhanced\" hx-target=\"#audio-player\" hx-swap=\"outerHTML\" hx-indicator=\"#voice-generation-loading\" enctype=\"multipart/form-data\"><!-- Audio Input Mode Selector -->")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"px-4 py-8 mx-auto max-w-4xl\"><div class=\"mb-12 text-center\"><h1 class=\"mb-4 text-4xl font-bold tracking-tight text-gray-900 sm:text-6xl\">Clone Any Voice with AI</h1><p class=\"mx-auto max-w-2xl text-xl text-gray-600\">Transform any audio into custom AI-generated speech. Choose your audio source and let our AI clone the voice.</p><a href=\"/convert\" class=\"inline-block mt-3 text-sm text-indigo-600 hover:text-indigo-800\">Or speak it yourself and convert it into a saved voice →</a></div><div class=\"mx-auto max-w-3xl\"><form id=\"voice-form\" hx-post=\"/generate-voice-enhanced\" hx-target=\"#audio-player\" hx-swap=\"outerHTML\" hx-indicator=\"#voice-generation-loading\" enctype=\"multipart/form-data\"><!-- Audio Input Mode Selector -->")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -51,7 +51,15 @@ func MainPage() templ.Component {
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<!-- Dynamic Audio Input Container --><div id=\"audio-input-container\" class=\"mb-8\"><!-- This will be populated by JavaScript based on selected mode --></div><!-- Text to Speech Section --><div class=\"text-start\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<!-- Dynamic Audio Input Container --><div id=\"audio-input-container\" class=\"mb-8\"><!-- This will be populated by JavaScript based on selected mode --></div>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = components.SaveVoiceProfile().Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<!-- Text to Speech Section --><div class=\"text-start\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -59,7 +67,7 @@ func MainPage() templ.Component {
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<!-- Tips Section --><div class=\"p-4 mt-6 bg-gradient-to-r from-blue-50 to-indigo-50 rounded-lg border border-blue-200\"><div class=\"flex items-start space-x-3\"><svg class=\"flex-shrink-0 mt-0.5 w-5 h-5 text-blue-500\" fill=\"currentColor\" viewBox=\"0 0 20 20\"><path fill-rule=\"evenodd\" d=\"M18 10a8 8 0 11-16 0 8 8 0 0116 0zm-7-4a1 1 0 11-2 0 1 1 0 012 0zM9 9a1 1 0 000 2v3a1 1 0 001 1h1a1 1 0 100-2v-3a1 1 0 00-1-1H9z\" clip-rule=\"evenodd\"></path></svg><div class=\"text-sm text-blue-800\"><p class=\"mb-2 font-semibold\">Tips for best results:</p><ul class=\"space-y-1 text-blue-700\"><li>• Use at least 10 seconds of clean, clear audio</li><li>• Avoid background noise and music</li><li>• Single speaker works better than multiple voices</li><li>• Generation may take 20-30 seconds</li></ul></div></div></div></div><div class=\"flex flex-col justify-center items-center mt-8 space-y-4 w-full\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<!-- Background music, with the library's tracks --><div hx-get=\"/components/music\" hx-trigger=\"load\" hx-swap=\"outerHTML\"></div><!-- Tips Section --><div class=\"p-4 mt-6 bg-gradient-to-r from-blue-50 to-indigo-50 rounded-lg border border-blue-200\"><div class=\"flex items-start space-x-3\"><svg class=\"flex-shrink-0 mt-0.5 w-5 h-5 text-blue-500\" fill=\"currentColor\" viewBox=\"0 0 20 20\"><path fill-rule=\"evenodd\" d=\"M18 10a8 8 0 11-16 0 8 8 0 0116 0zm-7-4a1 1 0 11-2 0 1 1 0 012 0zM9 9a1 1 0 000 2v3a1 1 0 001 1h1a1 1 0 100-2v-3a1 1 0 00-1-1H9z\" clip-rule=\"evenodd\"></path></svg><div class=\"text-sm text-blue-800\"><p class=\"mb-2 font-semibold\">Tips for best results:</p><ul class=\"space-y-1 text-blue-700\"><li>• Use at least 10 seconds of clean, clear audio</li><li>• Avoid background noise and music</li><li>• Single speaker works better than multiple voices</li><li>• Generation may take 20-30 seconds</li></ul></div></div></div></div><div class=\"flex flex-col justify-center items-center mt-8 space-y-4 w-full\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -67,11 +75,11 @@ func MainPage() templ.Component {
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = components.AudioPlayer("", "").Render(ctx, templ_7745c5c3_Buffer)
+			templ_7745c5c3_Err = components.AudioPlayer(components.Playback{}, "").Render(ctx, templ_7745c5c3_Buffer)
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</div></form></div><!-- Loading Animation -->")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</div></form><!-- Dubbing a whole video into another language --><div hx-get=\"/components/dubbing\" hx-trigger=\"load\" hx-swap=\"outerHTML\"></div><!-- Past generations, refreshed after each new one --><div id=\"generation-history\" hx-get=\"/components/history\" hx-trigger=\"load, generationSaved from:body\" hx-swap=\"innerHTML\"></div></div><!-- Loading Animation -->")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -79,7 +87,7 @@ func MainPage() templ.Component {
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</div><script>\n\t\t\t// Alpine.js Store Setup\n\t\t\tdocument.addEventListener('alpine:init', () => {\n\t\t\t\tAlpine.store('voiceClone', {\n\t\t\t\t\t// State\n\t\t\t\t\taudioMode: 'url',\n\t\t\t\t\ttextValid: false,\n\t\t\t\t\taudioInputValid: false,\n\n\t\t\t\t\t// Computed\n\t\t\t\t\tget isFormValid() {\n\t\t\t\t\t\treturn this.textValid && this.audioInputValid;\n\t\t\t\t\t},\n\n\t\t\t\t\t// Actions\n\t\t\t\t\tsetAudioMode(mode) {\n\t\t\t\t\t\tthis.audioMode = mode;\n\t\t\t\t\t\tthis.audioInputValid = false; // Reset validation when mode changes\n\t\t\t\t\t},\n\n\t\t\t\t\tvalidateText(text) {\n\t\t\t\t\t\tthis.textValid = text.length > 0 && text.length <= 500;\n\t\t\t\t\t},\n\n\t\t\t\t\tvalidateAudioInput(isValid) {\n\t\t\t\t\t\tthis.audioInputValid = isValid;\n\t\t\t\t\t}\n\t\t\t\t});\n\t\t\t});\n\n\t\t\t// Load default URL input on page load\n\t\t\tdocument.addEventListener('DOMContentLoaded', function() {\n\t\t\t\t// Load URL input by default\n\t\t\t\thtmx.ajax('POST', '/validate-url', {target: '#audio-input-container'});\n\t\t\t});\n\n\t\t\t// Add audio mode to request parameters and debug\n\t\t\tdocument.addEventListener('htmx:configRequest', function(evt) {\n\t\t\t\tconst form = evt.detail.elt;\n\t\t\t\tif (form.id === 'voice-form') {\n\t\t\t\t\tconsole.log('HTMX request starting');\n\t\t\t\t\tconst audioMode = Alpine.store('voiceClone').audioMode;\n\t\t\t\t\tevt.detail.parameters['audio-mode'] = audioMode;\n\t\t\t\t}\n\t\t\t});\n\n\t\t\t// Debug HTMX events\n\t\t\tdocument.addEventListener('htmx:beforeRequest', function(evt) {\n\t\t\t\tconsole.log('Before request:', evt.detail);\n\t\t\t});\n\n\t\t\tdocument.addEventListener('htmx:afterRequest', function(evt) {\n\t\t\t\tconsole.log('After request:', evt.detail);\n\t\t\t});\n\t\t</script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</div><script>\n\t\t\t// Alpine.js Store Setup\n\t\t\tdocument.addEventListener('alpine:init', () => {\n\t\t\t\tAlpine.store('voiceClone', {\n\t\t\t\t\t// State\n\t\t\t\t\taudioMode: 'url',\n\t\t\t\t\ttextValid: false,\n\t\t\t\t\taudioInputValid: false,\n\n\t\t\t\t\t// Computed\n\t\t\t\t\tget isFormValid() {\n\t\t\t\t\t\treturn this.textValid && this.audioInputValid;\n\t\t\t\t\t},\n\n\t\t\t\t\t// Actions\n\t\t\t\t\tsetAudioMode(mode) {\n\t\t\t\t\t\tthis.audioMode = mode;\n\t\t\t\t\t\tthis.audioInputValid = false; // Reset validation when mode changes\n\t\t\t\t\t},\n\n\t\t\t\t\tvalidateText(text) {\n\t\t\t\t\t\tthis.textValid = text.length > 0 && text.length <= 500;\n\t\t\t\t\t},\n\n\t\t\t\t\tvalidateAudioInput(isValid) {\n\t\t\t\t\t\tthis.audioInputValid = isValid;\n\t\t\t\t\t},\n\n\t\t\t\t\t// References attached to be merged into one saved voice\n\t\t\t\t\treferences: [],\n\n\t\t\t\t\tattachReference() {\n\t\t\t\t\t\tlet reference = null;\n\t\t\t\t\t\tif (this.audioMode === 'url') {\n\t\t\t\t\t\t\tconst url = document.getElementById('url').value;\n\t\t\t\t\t\t\treference = { mode: 'url', value: url, label: url };\n\t\t\t\t\t\t} else if (this.audioMode === 'file') {\n\t\t\t\t\t\t\treference = { mode: 'file', value: document.getElementById('file-id-input').value, label: document.getElementById('file-name').textContent };\n\t\t\t\t\t\t} else if (this.audioMode === 'microphone') {\n\t\t\t\t\t\t\treference = { mode: 'microphone', value: document.getElementById('recording-id-input').value, label: 'Recording' };\n\t\t\t\t\t\t}\n\t\t\t\t\t\tif (!reference || !reference.value) return;\n\t\t\t\t\t\tif (this.references.some(r => r.mode === reference.mode && r.value === reference.value)) return;\n\t\t\t\t\t\tthis.references.push(reference);\n\t\t\t\t\t},\n\n\t\t\t\t\tremoveReference(index) {\n\t\t\t\t\t\tthis.references.splice(index, 1);\n\t\t\t\t\t}\n\t\t\t\t});\n\t\t\t});\n\n\t\t\t// Load default URL input on page load\n\t\t\tdocument.addEventListener('DOMContentLoaded', function() {\n\t\t\t\t// Load URL input by default\n\t\t\t\thtmx.ajax('POST', '/validate-url', {target: '#audio-input-container'});\n\t\t\t});\n\n\t\t\t// Add audio mode to request parameters and debug\n\t\t\tdocument.addEventListener('htmx:configRequest', function(evt) {\n\t\t\t\tconst form = evt.detail.elt;\n\t\t\t\tif (form.id === 'voice-form') {\n\t\t\t\t\tconsole.log('HTMX request starting');\n\t\t\t\t\tconst audioMode = Alpine.store('voiceClone').audioMode;\n\t\t\t\t\tevt.detail.parameters['audio-mode'] = audioMode;\n\t\t\t\t}\n\t\t\t});\n\n\t\t\t// Still show the error fragment when the server is busy (503 + Retry-After)\n\t\t\tdocument.addEventListener('htmx:beforeSwap', function(evt) {\n\t\t\t\tif (evt.detail.xhr.status === 503) {\n\t\t\t\t\tevt.detail.shouldSwap = true;\n\t\t\t\t\tevt.detail.isError = false;\n\t\t\t\t}\n\t\t\t});\n\n\t\t\t// Debug HTMX events\n\t\t\tdocument.addEventListener('htmx:beforeRequest', function(evt) {\n\t\t\t\tconsole.log('Before request:', evt.detail);\n\t\t\t});\n\n\t\t\tdocument.addEventListener('htmx:afterRequest', function(evt) {\n\t\t\t\tconsole.log('After request:', evt.detail);\n\t\t\t});\n\t\t</script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -0,0 +1,201 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/henrik392/youtube-voice-go/cmd/web/components"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/captions"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/music"
+)
+
+const (
+	// musicParam is the Params key for how a generation is mixed with
+	// music, as JSON; musicPathParam and musicTrackParam are the music's
+	// file and the name it is shown by
+	musicParam      = "music"
+	musicPathParam  = "music_path"
+	musicTrackParam = "music_track"
+)
+
+// MusicFieldsHandler serves the background music fields of the voice form
+func MusicFieldsHandler(w http.ResponseWriter, r *http.Request) {
+	// An unreadable library still leaves uploads
+	tracks, err := music.Default().Tracks()
+	if err != nil {
+		log.Printf("Failed to list music library: %v", err)
+	}
+	if err := components.MusicFields(tracks, audio.DefaultMix).Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// musicFromForm reads the background music fields. music-source is empty for
+// no music, "library" for music-track from the library or "upload" for
+// music-file, which is stored right away. It returns the mix and the music's
+// path and name; the path is empty without music.
+func musicFromForm(r *http.Request) (audio.Mix, string, string, error) {
+	mix := audio.DefaultMix
+	var path, name string
+	switch source := r.FormValue("music-source"); source {
+	case "":
+		return mix, "", "", nil
+	case "library":
+		name = r.FormValue("music-track")
+		var err error
+		if path, err = music.Default().Path(name); err != nil {
+			return mix, "", "", fmt.Errorf("the music track %q is not in the library", name)
+		}
+	case "upload":
+		file, header, err := r.FormFile("music-file")
+		if err != nil {
+			return mix, "", "", errors.New("no music file provided")
+		}
+		defer file.Close()
+		if path, err = storeUploadedAudio(r.Context(), uuid.New().String(), file); err != nil {
+			return mix, "", "", err
+		}
+		name = header.Filename
+	default:
+		return mix, "", "", fmt.Errorf("unknown music source %q", source)
+	}
+
+	numbers := map[string]*float64{
+		"music-volume":   &mix.MusicVolume,
+		"voice-volume":   &mix.VoiceVolume,
+		"music-ducking":  &mix.Ducking,
+		"music-lead-in":  &mix.LeadIn,
+		"music-tail":     &mix.Tail,
+		"music-fade-in":  &mix.FadeIn,
+		"music-fade-out": &mix.FadeOut,
+	}
+	if err := formFloats(r, numbers); err != nil {
+		return mix, "", "", err
+	}
+	mix.Loop = formBool(r.FormValue("music-loop"))
+	return mix, path, name, mix.Validate()
+}
+
+// setMusic records the music gen is mixed over, or that it has none if path
+// is empty
+func setMusic(gen *database.Generation, mix audio.Mix, path, name string) {
+	if path == "" {
+		delete(gen.Params, musicParam)
+		delete(gen.Params, musicPathParam)
+		delete(gen.Params, musicTrackParam)
+		return
+	}
+	data, err := json.Marshal(mix)
+	if err != nil {
+		log.Printf("Failed to encode the music mix of %s: %v", gen.ID, err)
+		return
+	}
+	gen.Params[musicParam] = string(data)
+	gen.Params[musicPathParam] = path
+	gen.Params[musicTrackParam] = name
+}
+
+// generationMusic is how gen is mixed with music, and the music's path
+func generationMusic(gen *database.Generation) (audio.Mix, string) {
+	mix := audio.DefaultMix
+	if err := json.Unmarshal([]byte(gen.Params[musicParam]), &mix); err != nil && gen.Params[musicParam] != "" {
+		log.Printf("Failed to decode the music mix of %s: %v", gen.ID, err)
+	}
+	return mix, gen.Params[musicPathParam]
+}
+
+func hasMusic(gen *database.Generation) bool {
+	return gen.Params[musicPathParam] != ""
+}
+
+// removeUploadedMusic removes the music file uploaded for gen once no other
+// generation is mixed over it; re-generating keeps the same music. Library
+// tracks stay.
+func removeUploadedMusic(ctx context.Context, gen *database.Generation) {
+	_, path := generationMusic(gen)
+	if path == "" || filepath.Dir(filepath.Clean(path)) != downloadsDir {
+		return
+	}
+	count, err := database.New().CountGenerationsWithParam(ctx, musicPathParam, path)
+	if err != nil {
+		log.Printf("Failed to check who else uses the music of %s: %v", gen.ID, err)
+		return
+	}
+	if count == 0 {
+		os.Remove(path)
+	}
+}
+
+// mixPath is where the mix of the voice at audioPath with music is kept
+func mixPath(audioPath string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".mix" + audio.CanonicalExt
+}
+
+// renderMix lays gen's voice over its music. The voice stays as it is, as the
+// dry stem; the mix is watermarked and tagged like the voice. Without music
+// any old mix is removed.
+func renderMix(ctx context.Context, gen *database.Generation) error {
+	mix, musicPath := generationMusic(gen)
+	if musicPath == "" {
+		os.Remove(mixPath(gen.AudioPath))
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "mix_*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "mix"+audio.CanonicalExt)
+	if err := audio.MixMusic(ctx, gen.AudioPath, musicPath, output, mix); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		return err
+	}
+	return writeGeneratedAudio(ctx, mixPath(gen.AudioPath), gen.ID, gen.Provider, data)
+}
+
+// mixGeneration renders gen's mix, dropping the music if that fails so the
+// voice is still delivered
+func mixGeneration(ctx context.Context, gen *database.Generation) {
+	if err := renderMix(ctx, gen); err != nil {
+		log.Printf("Failed to mix music under generation %s: %v", gen.ID, err)
+		setMusic(gen, audio.Mix{}, "", "")
+		os.Remove(mixPath(gen.AudioPath))
+	}
+}
+
+// generationPlayback is what the player plays and draws for a recorded
+// generation: the mix if it has music, with the words moved past the music's
+// lead-in, and the voice stem to download
+func generationPlayback(gen *database.Generation, words []captions.Word) components.Playback {
+	base := "/generations/" + gen.ID
+	playback := components.Playback{
+		AudioURL:       base + "/audio",
+		CaptionsURL:    captionsURL(gen),
+		Words:          words,
+		PeaksURL:       base + "/peaks.json",
+		SpectrogramURL: base + "/spectrogram.png",
+	}
+	if mix, musicPath := generationMusic(gen); musicPath != "" {
+		playback.VoiceURL = playback.AudioURL
+		playback.AudioURL = base + "/mix"
+		playback.Words = captions.Shift(words, mix.LeadIn)
+		playback.PeaksURL += "?mix=1"
+		playback.SpectrogramURL += "?mix=1"
+	}
+	return playback
+}
@@ -0,0 +1,13 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/henrik392/youtube-voice-go/internal/platformstatus"
+	"github.com/henrik392/youtube-voice-go/internal/youtube"
+)
+
+// PlatformStatusHandler reports download availability per platform as JSON
+func PlatformStatusHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, platformstatus.Default().Statuses(youtube.Platforms))
+}
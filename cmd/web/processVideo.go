@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/henrik392/youtube-voice-go/cmd/web/components"
 	"github.com/henrik392/youtube-voice-go/internal/zonos"
@@ -18,15 +19,15 @@ type ProcessVideoResponse struct {
 	Error      string `json:"error,omitempty"`
 }
 
+// ProcessVideoHandler downloads, crops, transcribes and uploads a video's
+// audio ahead of generation. It answers with the ProcessingComplete component,
+// or with a ProcessVideoResponse when the client accepts JSON.
 func ProcessVideoHandler(w http.ResponseWriter, r *http.Request) {
 	videoURL := r.FormValue("url")
 	videoID := youtube.ExtractVideoID(videoURL)
 
-	w.Header().Set("Content-Type", "text/html")
-
 	if videoID == "" {
-		component := components.ProcessingError("Invalid YouTube URL")
-		component.Render(r.Context(), w)
+		renderProcessVideo(w, r, http.StatusBadRequest, ProcessVideoResponse{Error: "Invalid YouTube URL"})
 		return
 	}
 
@@ -34,11 +35,10 @@ func ProcessVideoHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Download audio
 	ytProcessor := youtube.NewProcessor("./downloads")
-	audioFile, err := ytProcessor.DownloadAudio(videoURL, videoID)
+	audioFile, err := ytProcessor.DownloadAudio(r.Context(), videoURL, videoID)
 	if err != nil {
 		log.Printf("Failed to download audio: %v", err)
-		component := components.ProcessingError("Failed to download audio: " + err.Error())
-		component.Render(r.Context(), w)
+		renderProcessVideo(w, r, retryStatus(w, err, http.StatusBadRequest), ProcessVideoResponse{VideoID: videoID, Error: "Failed to download audio: " + err.Error()})
 		return
 	}
 
@@ -47,28 +47,41 @@ func ProcessVideoHandler(w http.ResponseWriter, r *http.Request) {
 	// Create Dia TTS client
 	diaClient := zonos.NewClient(os.Getenv("FAL_KEY"))
 
-	// Crop, upload audio and extract reference text
+	// Crop, transcribe and upload the reference
 	log.Printf("Cropping and uploading audio...")
-	croppedFilePath, err := diaClient.CropAndCompressAudio(audioFile, 30)
+	ref, err := diaClient.UploadReference(r.Context(), audioFile)
 	if err != nil {
-		log.Printf("Failed to crop audio: %v", err)
-		component := components.ProcessingError("Failed to crop audio: " + err.Error())
-		component.Render(r.Context(), w)
+		log.Printf("Failed to prepare reference: %v", err)
+		renderProcessVideo(w, r, retryStatus(w, err, http.StatusBadRequest), ProcessVideoResponse{VideoID: videoID, Error: "Failed to prepare audio: " + err.Error()})
 		return
 	}
-	defer os.Remove(croppedFilePath) // Clean up temp file
 
-	// Upload to S3
-	audioURL, err := diaClient.UploadToS3(croppedFilePath)
-	if err != nil {
-		log.Printf("Failed to upload audio: %v", err)
-		component := components.ProcessingError("Failed to upload audio: " + err.Error())
-		component.Render(r.Context(), w)
+	log.Printf("Video processing complete for %s", videoID)
+
+	renderProcessVideo(w, r, http.StatusOK, ProcessVideoResponse{
+		Success:  true,
+		VideoID:  videoID,
+		AudioURL: ref.URL,
+		RefText:  ref.Text,
+	})
+}
+
+// renderProcessVideo answers with status as JSON, or as HTML for htmx. htmx
+// only swaps in successful answers and the 503 the page lets through, so
+// other errors are shown with a 200.
+func renderProcessVideo(w http.ResponseWriter, r *http.Request, status int, response ProcessVideoResponse) {
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		writeJSON(w, status, response)
 		return
 	}
 
-	log.Printf("Video processing complete for %s", videoID)
-
-	component := components.ProcessingComplete(videoID, audioURL)
-	component.Render(r.Context(), w)
-}
\ No newline at end of file
+	w.Header().Set("Content-Type", "text/html")
+	component := components.ProcessingComplete(response.VideoID, response.AudioURL, response.RefText)
+	if !response.Success {
+		component = components.ProcessingError(response.Error)
+	}
+	if status != http.StatusServiceUnavailable {
+		status = http.StatusOK
+	}
+	renderComponent(w, r, status, component)
+}
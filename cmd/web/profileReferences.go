@@ -0,0 +1,186 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/elevenlabs"
+	"github.com/henrik392/youtube-voice-go/internal/zonos"
+)
+
+const (
+	// maxProfileReferences is how many references can be merged into one voice
+	maxProfileReferences = 10
+
+	// mergedSourceType is the SourceType of profiles merged from several references
+	mergedSourceType = "merged"
+
+	// elevenLabsProvider is the ProviderVoiceIDs key for the voice cloned on
+	// ElevenLabs, if ELEVENLABS_API_KEY is set
+	elevenLabsProvider = "elevenlabs"
+)
+
+// profileReferences resolves every reference a new profile is made from: the
+// ones attached in the form as reference values ("url:<video URL>",
+// "file:<upload ID>" or "microphone:<recording ID>") and the one currently
+// selected in mode, which is optional once others are attached.
+func profileReferences(r *http.Request, mode string) ([]string, error) {
+	var paths []string
+	for _, value := range r.Form["reference"] {
+		path, err := attachedReference(r.Context(), value)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	current, err := resolveReference(r, mode)
+	if err != nil && len(paths) == 0 {
+		return nil, err
+	}
+	if err == nil {
+		paths = append(paths, current)
+	}
+
+	// The current reference is often attached already
+	var unique []string
+	for _, path := range paths {
+		if !slices.Contains(unique, path) {
+			unique = append(unique, path)
+		}
+	}
+	if len(unique) > maxProfileReferences {
+		return nil, fmt.Errorf("a voice can be made from at most %d references", maxProfileReferences)
+	}
+	return unique, nil
+}
+
+// attachedReference resolves one attached reference value to its file
+func attachedReference(ctx context.Context, value string) (string, error) {
+	mode, source, _ := strings.Cut(value, ":")
+	switch mode {
+	case "url":
+		return downloadReference(ctx, source)
+	case "file", "microphone":
+		// Uploads and recordings are stored under their ID in the canonical format
+		if _, err := uuid.Parse(source); err != nil {
+			return "", fmt.Errorf("invalid reference ID")
+		}
+		path := filepath.Join(downloadsDir, source+audio.CanonicalExt)
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("an attached reference was not found")
+		}
+		return path, nil
+	default:
+		return "", fmt.Errorf("invalid reference %q", value)
+	}
+}
+
+// profileClipPath is where the i-th prepared clip of a merged profile is kept
+func profileClipPath(profileID string, i int) string {
+	return filepath.Join(profilesDir, fmt.Sprintf("%s.clip%d%s", profileID, i, audio.CanonicalExt))
+}
+
+// profileClips lists a merged profile's prepared clips in order
+func profileClips(profileID string) []string {
+	var clips []string
+	for i := 0; ; i++ {
+		path := profileClipPath(profileID, i)
+		if _, err := os.Stat(path); err != nil {
+			return clips
+		}
+		clips = append(clips, path)
+	}
+}
+
+func removeProfileClips(profileID string) {
+	for _, clip := range profileClips(profileID) {
+		os.Remove(clip)
+	}
+}
+
+// mergeProfileReferences brings paths to a common loudness, removes their
+// silences and joins them into the profile's reference, each getting a share
+// of what zonos takes. The prepared clips are kept for providers that take
+// several files.
+func mergeProfileReferences(ctx context.Context, profile *database.VoiceProfile, paths []string) error {
+	clips := make([]string, len(paths))
+	for i := range clips {
+		clips[i] = profileClipPath(profile.ID, i)
+	}
+	err := audio.MergeReferences(ctx, paths, clips, profile.ReferencePath, elevenlabs.MaxVoiceFileSeconds, zonos.MaxReferenceSeconds)
+	if err != nil {
+		removeProfileClips(profile.ID)
+	}
+	return err
+}
+
+// registerElevenLabsVoice clones the profile's voice on ElevenLabs, from each
+// of its prepared clips or, for a profile made from one reference, from a
+// clip prepared from it now
+func registerElevenLabsVoice(ctx context.Context, profile *database.VoiceProfile) error {
+	clips := profileClips(profile.ID)
+	if len(clips) == 0 {
+		dir, err := os.MkdirTemp("", "voice_*")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		clip := filepath.Join(dir, "reference"+audio.CanonicalExt)
+		if err := audio.PrepareReference(ctx, profile.ReferencePath, clip, elevenlabs.MaxVoiceFileSeconds); err != nil {
+			return err
+		}
+		clips = []string{clip}
+	}
+
+	inUse, err := elevenLabsVoicesInUse(ctx)
+	if err != nil {
+		return err
+	}
+	voiceID, err := elevenlabs.NewClient(os.Getenv("ELEVENLABS_API_KEY")).AddVoiceWithinLimit(ctx, profile.Name, clips, inUse)
+	if err != nil {
+		return err
+	}
+	if err := database.New().SetProviderVoiceID(ctx, profile.ID, elevenLabsProvider, voiceID); err != nil {
+		// The profile was deleted meanwhile, so its voice goes too
+		removeElevenLabsVoice(ctx, voiceID)
+		return err
+	}
+	if profile.ProviderVoiceIDs == nil {
+		profile.ProviderVoiceIDs = map[string]string{}
+	}
+	profile.ProviderVoiceIDs[elevenLabsProvider] = voiceID
+	return nil
+}
+
+// elevenLabsVoicesInUse is the set of ElevenLabs voices saved profiles are
+// cloned as, which must not be removed to make room for another
+func elevenLabsVoicesInUse(ctx context.Context) (map[string]bool, error) {
+	profiles, err := database.New().ListVoiceProfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inUse := make(map[string]bool, len(profiles))
+	for _, p := range profiles {
+		if voiceID := p.ProviderVoiceIDs[elevenLabsProvider]; voiceID != "" {
+			inUse[voiceID] = true
+		}
+	}
+	return inUse, nil
+}
+
+func removeElevenLabsVoice(ctx context.Context, voiceID string) {
+	if err := elevenlabs.NewClient(os.Getenv("ELEVENLABS_API_KEY")).RemoveVoice(ctx, voiceID); err != nil {
+		log.Printf("Failed to remove ElevenLabs voice %s: %v", voiceID, err)
+	}
+}
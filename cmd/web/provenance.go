@@ -0,0 +1,186 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/provenance"
+)
+
+// writeGeneratedAudio saves generated audio watermarked, with provenance tags
+// embedded and a signed manifest next to it. Watermark and provenance problems
+// are logged rather than failing the generation; the audio is always written.
+func writeGeneratedAudio(ctx context.Context, path, generationID, provider string, data []byte) error {
+	createdAt := time.Now()
+
+	// The watermark changes the samples, so it goes in before the tags and the hash
+	data = watermarkAudio(ctx, data, generationID)
+	tagged, err := provenance.Tag(data, generationID, createdAt)
+	if err != nil {
+		log.Printf("Failed to tag %s with provenance: %v", path, err)
+		tagged = data
+	}
+	if err := os.WriteFile(path, tagged, 0644); err != nil {
+		return err
+	}
+
+	signer, err := provenance.Default()
+	if err == nil {
+		var manifest *provenance.SignedManifest
+		manifest, err = signer.Sign(provenance.NewManifest(generationID, provider, createdAt, tagged))
+		if err == nil {
+			err = provenance.WriteSidecar(path, manifest)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to write provenance manifest for %s: %v", path, err)
+	}
+	return nil
+}
+
+// verifyResult is the answer from /api/provenance/verify
+type verifyResult struct {
+	Verified       bool                       `json:"verified"`
+	Reason         string                     `json:"reason"`
+	SignatureValid bool                       `json:"signature_valid"`
+	HashMatches    bool                       `json:"hash_matches"`
+	SHA256         string                     `json:"sha256"`
+	GenerationID   string                     `json:"generation_id,omitempty"`
+	Manifest       *provenance.SignedManifest `json:"manifest,omitempty"`
+}
+
+// VerifyProvenanceHandler reports whether an uploaded file was produced by
+// this instance. The manifest can be uploaded alongside as "manifest";
+// otherwise it is found through the generation ID embedded in the file's tags.
+func VerifyProvenanceHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Expected a multipart form with a file"})
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "No file provided"})
+		return
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Failed to read file"})
+		return
+	}
+
+	signer, err := provenance.Default()
+	if err != nil {
+		log.Printf("Provenance key unavailable: %v", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "Verification is unavailable"})
+		return
+	}
+
+	result := verifyResult{SHA256: provenance.Hash(data)}
+	result.GenerationID, _ = provenance.ReadGenerationID(data)
+
+	manifest, err := uploadedManifest(r)
+	if err == nil && manifest == nil && result.GenerationID != "" {
+		manifest, err = storedManifest(r, result.GenerationID)
+	}
+	switch {
+	case err != nil:
+		result.Reason = "invalid_manifest"
+	case manifest == nil:
+		result.Reason = "no_manifest"
+	default:
+		result.Manifest = manifest
+		result.GenerationID = manifest.Manifest.GenerationID
+		result.SignatureValid = signer.Verify(manifest)
+		result.HashMatches = manifest.Manifest.SHA256 == result.SHA256
+		result.Verified = result.SignatureValid && result.HashMatches
+
+		switch {
+		case !result.SignatureValid:
+			result.Reason = "bad_signature"
+		case !result.HashMatches:
+			// Signed by us, but the file has been re-encoded or edited since
+			result.Reason = "modified"
+		default:
+			result.Reason = "ok"
+		}
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ProvenanceKeyHandler publishes the public key manifests are signed with
+func ProvenanceKeyHandler(w http.ResponseWriter, r *http.Request) {
+	signer, err := provenance.Default()
+	if err != nil {
+		log.Printf("Provenance key unavailable: %v", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "Provenance key unavailable"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"algorithm":  "ed25519",
+		"key_id":     signer.KeyID,
+		"public_key": signer.PublicKey(),
+	})
+}
+
+// GenerationManifestHandler serves a generation's signed sidecar manifest
+func GenerationManifestHandler(w http.ResponseWriter, r *http.Request) {
+	gen, err := loadGeneration(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+
+	manifest, err := provenance.ReadSidecar(gen.AudioPath)
+	if err != nil {
+		http.Error(w, "Manifest not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, manifest)
+}
+
+// uploadedManifest returns the optional "manifest" part, or nil without one
+func uploadedManifest(r *http.Request) (*provenance.SignedManifest, error) {
+	file, _, err := r.FormFile("manifest")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return provenance.ParseManifest(data)
+}
+
+// storedManifest looks up the sidecar of a generation from this instance,
+// returning nil if there is no such generation
+func storedManifest(r *http.Request, generationID string) (*provenance.SignedManifest, error) {
+	gen, err := loadGeneration(r.Context(), generationID)
+	if err != nil {
+		if !errors.Is(err, database.ErrNotFound) {
+			log.Printf("Failed to look up generation %s for verification: %v", generationID, err)
+		}
+		return nil, nil
+	}
+
+	manifest, err := provenance.ReadSidecar(gen.AudioPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return manifest, err
+}
@@ -0,0 +1,222 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/recording"
+	"github.com/henrik392/youtube-voice-go/internal/workerpool"
+)
+
+// maxRecordingChunk bounds a single binary message; a second of 48 kHz PCM is 96 KB
+const maxRecordingChunk = 1 << 20
+
+// recordingMessage is a control message from the browser. Audio itself
+// arrives as binary messages of 16-bit little-endian mono PCM.
+//
+//	{"type":"start","sampleRate":48000}          begin a new recording
+//	{"type":"resume","recordingId":"<id>"}       append to an unfinished one
+//	{"type":"finish","autoGain":true,...}        store it and run the quality gate
+//	{"type":"cancel"}                            throw it away
+//
+// The server answers with "started", a "level" per audio chunk, and finally
+// "finished" with the recording ID and quality report, or "error".
+type recordingMessage struct {
+	Type           string `json:"type"`
+	SampleRate     int    `json:"sampleRate"`
+	RecordingID    string `json:"recordingId"`
+	AutoGain       bool   `json:"autoGain"`
+	NoiseReduction bool   `json:"noiseReduction"`
+}
+
+// streamError is a protocol mistake by the client, reported back verbatim
+type streamError string
+
+func (e streamError) Error() string {
+	return string(e)
+}
+
+type levelMessage struct {
+	Type string `json:"type"`
+	recording.Level
+}
+
+// RecordingStreamHandler receives a microphone recording over a WebSocket
+// while it is being made. Chunks are written to disk as they arrive, so a
+// dropped connection loses nothing and the browser can resume. Finishing
+// stores the recording as downloads/<recording ID>.wav, the same as
+// /save-recording.
+func RecordingStreamHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		log.Printf("Failed to accept recording stream: %v", err)
+		return
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(maxRecordingChunk)
+
+	ctx := r.Context()
+	store := recording.Default()
+
+	var session *recording.Session
+	defer func() {
+		if session != nil {
+			log.Printf("Recording stream %s disconnected at %.1fs; kept for resume", session.ID, session.Elapsed())
+			session.Close()
+		}
+	}()
+
+	for {
+		kind, data, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+
+		if kind == websocket.MessageBinary {
+			if session == nil {
+				writeRecordingError(ctx, conn, nil, streamError("send start or resume before audio"))
+				return
+			}
+
+			level, err := session.Write(data)
+			if errors.Is(err, recording.ErrTooLong) {
+				writeRecordingError(ctx, conn, nil, audio.Reject(audio.ReasonTooLong, "Recordings can be at most %s long", store.MaxDuration))
+				continue
+			}
+			if err != nil {
+				writeRecordingError(ctx, conn, nil, err)
+				return
+			}
+
+			if err := wsjson.Write(ctx, conn, levelMessage{Type: "level", Level: level}); err != nil {
+				return
+			}
+			continue
+		}
+
+		var msg recordingMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			writeRecordingError(ctx, conn, nil, streamError("invalid message"))
+			return
+		}
+
+		switch msg.Type {
+		case "start", "resume":
+			if session != nil {
+				writeRecordingError(ctx, conn, nil, streamError("a recording is already in progress"))
+				return
+			}
+			if msg.Type == "start" {
+				session, err = store.Create(msg.SampleRate)
+			} else {
+				session, err = store.Resume(msg.RecordingID)
+			}
+			if err != nil {
+				writeRecordingError(ctx, conn, nil, err)
+				return
+			}
+
+			log.Printf("Recording stream %s: %s at %d Hz", session.ID, msg.Type, session.SampleRate)
+			wsjson.Write(ctx, conn, map[string]interface{}{
+				"type":        "started",
+				"recordingId": session.ID,
+				"elapsed":     session.Elapsed(),
+			})
+
+		case "finish":
+			if session == nil {
+				writeRecordingError(ctx, conn, nil, streamError("no recording in progress"))
+				return
+			}
+			finished := session
+			session = nil
+
+			enhance := audio.Enhancement{AutoGain: msg.AutoGain, NoiseReduction: msg.NoiseReduction}
+			report, err := finishRecording(ctx, store, finished, enhance)
+			if err != nil {
+				writeRecordingError(ctx, conn, report, err)
+				return
+			}
+
+			wsjson.Write(ctx, conn, map[string]interface{}{
+				"type":        "finished",
+				"recordingId": finished.ID,
+				"quality":     report,
+			})
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+
+		case "cancel":
+			if session != nil {
+				session.Close()
+				store.Discard(session.ID)
+				session = nil
+			}
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+
+		default:
+			writeRecordingError(ctx, conn, nil, streamError("unknown message type"))
+			return
+		}
+	}
+}
+
+// finishRecording turns the streamed PCM into a stored reference. When the
+// ffmpeg pool is saturated the raw recording is kept so the client can
+// resume and finish again.
+func finishRecording(ctx context.Context, store *recording.Store, session *recording.Session, enhance audio.Enhancement) (*audio.Report, error) {
+	wavPath, err := session.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(wavPath)
+	if err != nil {
+		return nil, err
+	}
+	_, report, err := storeReference(ctx, session.ID, file, enhance)
+	file.Close()
+
+	if errors.Is(err, workerpool.ErrSaturated) {
+		os.Remove(wavPath)
+		return nil, err
+	}
+	store.Discard(session.ID)
+	return report, err
+}
+
+// writeRecordingError sends the same fields as writeReferenceError, as a WebSocket message
+func writeRecordingError(ctx context.Context, conn *websocket.Conn, report *audio.Report, err error) {
+	message := map[string]interface{}{"type": "error"}
+
+	var saturated *workerpool.SaturatedError
+	var protocol streamError
+	switch rejection, ok := audio.AsRejection(err); {
+	case ok:
+		message["reason"] = rejection.Reason
+		message["error"] = rejection.Detail
+		if report != nil {
+			message["quality"] = report
+		}
+	case errors.As(err, &saturated):
+		message["error"] = "The server is busy, please try again"
+		message["retryAfter"] = int(saturated.RetryAfter.Seconds())
+	case errors.Is(err, recording.ErrNotFound):
+		message["error"] = "The recording has expired"
+	case errors.As(err, &protocol), errors.Is(err, recording.ErrInUse), errors.Is(err, recording.ErrBadSampleRate), errors.Is(err, recording.ErrEmpty):
+		message["error"] = err.Error()
+	default:
+		log.Printf("Recording stream error: %v", err)
+		message["error"] = "Could not save the recording"
+	}
+
+	wsjson.Write(ctx, conn, message)
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/youtube"
+	"github.com/henrik392/youtube-voice-go/internal/zonos"
+)
+
+// referenceRangesParam is the Params key for the ranges of the reference a
+// generation was cloned from, as audio.Ranges writes them
+const referenceRangesParam = "reference_ranges"
+
+// referenceRangesFromForm reads the reference-ranges picked for the reference
+// at path and checks them against its real duration. It returns no ranges if
+// none were picked, leaving the default crop.
+func referenceRangesFromForm(r *http.Request, path string) (audio.Ranges, error) {
+	ranges, err := audio.ParseRanges(r.FormValue("reference-ranges"))
+	if err != nil || len(ranges) == 0 {
+		return nil, err
+	}
+	info, err := audio.Probe(r.Context(), path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the reference: %w", err)
+	}
+	if err := ranges.Validate(info.Duration.Seconds(), zonos.MaxReferenceSeconds); err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+func setReferenceRanges(gen *database.Generation, ranges audio.Ranges) {
+	if len(ranges) > 0 {
+		gen.Params[referenceRangesParam] = ranges.String()
+	}
+}
+
+// videoAudioPath is where the audio downloaded from videoID is kept
+func videoAudioPath(videoID string) (string, error) {
+	if !youtube.ValidVideoID(videoID) {
+		return "", fmt.Errorf("invalid video ID")
+	}
+	path := filepath.Join(downloadsDir, videoID+".mp3")
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("the video's audio has not been downloaded")
+	}
+	return path, nil
+}
@@ -0,0 +1,349 @@
+package web
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/resumable"
+	"github.com/henrik392/youtube-voice-go/internal/workerpool"
+)
+
+// Resumable uploads follow the core tus 1.0.0 protocol with the creation,
+// expiration, checksum and termination extensions, so any tus client works.
+// When the last chunk arrives the file goes through the same validation and
+// normalization as a direct upload, in the background, and is stored under the
+// upload ID, which the client then submits as file-id. A plain tus client takes
+// the final 204 as done and may submit the ID while the file is still being
+// checked, so the form waits for that; our own client repeats the final PATCH
+// until it carries Upload-File-Id, to show problems before the form is sent.
+const tusVersion = "1.0.0"
+
+const (
+	// uploadChunkTimeout replaces the server's read and write timeouts for a
+	// PATCH, which on a slow connection takes far longer than a form post
+	uploadChunkTimeout = 5 * time.Minute
+	// uploadFinishTimeout bounds checking and normalizing a complete upload
+	uploadFinishTimeout = 5 * time.Minute
+	// uploadFinishRetention is how long the outcome of a finalization waits
+	// for the client to collect it
+	uploadFinishRetention = time.Hour
+	// uploadFinishWait is how long a form submitted with a file-id waits for
+	// that upload to be checked, well within the server's WriteTimeout
+	uploadFinishWait = 20 * time.Second
+)
+
+// uploadFinish is the finalization of a complete upload. Normalizing a large
+// file outlasts the server's WriteTimeout, so it runs in the background.
+type uploadFinish struct {
+	length int64
+	// ready is closed once the outcome is set
+	ready chan struct{}
+
+	jobState
+	report *audio.Report
+	err    error
+}
+
+var uploadFinishes = newJobRegistry[*uploadFinish](uploadFinishRetention)
+
+// TusOptionsHandler advertises the supported protocol features
+func TusOptionsHandler(w http.ResponseWriter, r *http.Request) {
+	setTusHeaders(w)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", "creation,expiration,checksum,termination")
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(resumable.Default().MaxSize, 10))
+	w.Header().Set("Tus-Checksum-Algorithm", strings.Join(resumable.ChecksumAlgorithms, ","))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateUploadHandler starts a new upload of Upload-Length bytes
+func CreateUploadHandler(w http.ResponseWriter, r *http.Request) {
+	setTusHeaders(w)
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		http.Error(w, "Upload-Length header is required", http.StatusBadRequest)
+		return
+	}
+
+	metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, "Invalid Upload-Metadata header", http.StatusBadRequest)
+		return
+	}
+
+	upload, err := resumable.Default().Create(length, metadata)
+	if errors.Is(err, resumable.ErrTooLarge) {
+		writeUploadError(w, audio.Reject(audio.ReasonTooLarge, "The file is larger than %d MB", resumable.Default().MaxSize>>20))
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create upload: %v", err)
+		http.Error(w, "Failed to create upload", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Created upload %s (%d bytes, %s)", upload.ID, upload.Length, metadata["filename"])
+	w.Header().Set("Location", "/uploads/"+upload.ID)
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// UploadOffsetHandler tells the client where to resume
+func UploadOffsetHandler(w http.ResponseWriter, r *http.Request) {
+	setTusHeaders(w)
+	w.Header().Set("Cache-Control", "no-store")
+
+	upload, err := resumable.Default().Get(chi.URLParam(r, "id"))
+	if err != nil {
+		writeTusError(w, err)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+}
+
+// UploadChunkHandler appends one chunk and finishes the upload once all bytes are in
+func UploadChunkHandler(w http.ResponseWriter, r *http.Request) {
+	setTusHeaders(w)
+	extendDeadlines(w, uploadChunkTimeout)
+
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		http.Error(w, "Content-Type must be application/offset+octet-stream", http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Upload-Offset header is required", http.StatusBadRequest)
+		return
+	}
+
+	store := resumable.Default()
+	id := chi.URLParam(r, "id")
+
+	// Once every byte is in, the client only asks how finalization is going
+	if finish := uploadFinishes.lookup(id); finish != nil {
+		writeUploadFinish(w, id, finish)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, store.MaxSize)
+
+	upload, err := store.WriteChunk(id, offset, body, r.Header.Get("Upload-Checksum"))
+	if errors.Is(err, resumable.ErrCompleted) && offset == upload.Length {
+		// The final PATCH again after a restart lost its finalization; all the
+		// bytes are already here
+		err = nil
+	}
+	if err != nil {
+		if upload != nil {
+			w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		}
+		writeTusError(w, err)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+
+	if !upload.Complete() {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeUploadFinish(w, upload.ID, startUploadFinish(upload))
+}
+
+// DeleteUploadHandler abandons an upload
+func DeleteUploadHandler(w http.ResponseWriter, r *http.Request) {
+	setTusHeaders(w)
+
+	if err := resumable.Default().Delete(chi.URLParam(r, "id")); err != nil {
+		writeTusError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startUploadFinish finalizes upload in the background, unless that is
+// already under way, dropping outcomes that were never collected
+func startUploadFinish(upload *resumable.Upload) *uploadFinish {
+	finish, added := uploadFinishes.add(upload.ID, &uploadFinish{length: upload.Length, ready: make(chan struct{})})
+	if !added {
+		return finish
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), uploadFinishTimeout)
+		defer cancel()
+
+		report, err := finishUploadWhenFree(ctx, upload)
+		resumable.Default().Delete(upload.ID)
+		if err != nil {
+			log.Printf("Failed to finish upload %s: %v", upload.ID, err)
+		}
+
+		finish.mu.Lock()
+		defer finish.mu.Unlock()
+		finish.markFinished()
+		finish.report = report
+		finish.err = err
+		close(finish.ready)
+	}()
+	return finish
+}
+
+// finishUploadWhenFree is finishUpload, waiting out a saturated worker pool:
+// nobody is left to hand a 503 to, so the upload waits its turn until ctx ends.
+func finishUploadWhenFree(ctx context.Context, upload *resumable.Upload) (*audio.Report, error) {
+	for {
+		report, err := finishUpload(ctx, upload)
+		var saturated *workerpool.SaturatedError
+		if !errors.As(err, &saturated) {
+			return report, err
+		}
+		select {
+		case <-ctx.Done():
+			return report, err
+		case <-time.After(max(saturated.RetryAfter, time.Second)):
+		}
+	}
+}
+
+// awaitUpload waits for upload id to be checked, if it is still being
+// finalized, and returns the outcome. It gives up after uploadFinishWait.
+func awaitUpload(ctx context.Context, id string) error {
+	finish := uploadFinishes.lookup(id)
+	if finish == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, uploadFinishWait)
+	defer cancel()
+	select {
+	case <-finish.ready:
+	case <-ctx.Done():
+		return fmt.Errorf("the uploaded file is still being checked, please try again in a moment")
+	}
+
+	finish.mu.Lock()
+	defer finish.mu.Unlock()
+	return finish.err
+}
+
+// writeUploadFinish answers a PATCH of a complete upload: 204 while it is
+// being finalized, 204 with Upload-File-Id once it is accepted, or the
+// rejection.
+func writeUploadFinish(w http.ResponseWriter, id string, finish *uploadFinish) {
+	finish.mu.Lock()
+	done, report, err := finish.finished, finish.report, finish.err
+	finish.mu.Unlock()
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(finish.length, 10))
+	switch {
+	case !done:
+		w.WriteHeader(http.StatusNoContent)
+	case err != nil:
+		writeReferenceError(w, report, err)
+	default:
+		w.Header().Set("Upload-File-Id", id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// extendDeadlines gives a request that legitimately runs long its own read
+// and write deadlines instead of the server-wide timeouts
+func extendDeadlines(w http.ResponseWriter, timeout time.Duration) {
+	controller := http.NewResponseController(w)
+	deadline := time.Now().Add(timeout)
+	if err := controller.SetReadDeadline(deadline); err != nil {
+		log.Printf("Failed to extend read deadline: %v", err)
+	}
+	if err := controller.SetWriteDeadline(deadline); err != nil {
+		log.Printf("Failed to extend write deadline: %v", err)
+	}
+}
+
+// finishUpload checks the whole-file checksum, if the client sent one, then
+// stores the audio as downloads/<upload ID>.wav and runs the quality gate.
+// The report is then available from /api/audio-quality/<upload ID>.
+func finishUpload(ctx context.Context, upload *resumable.Upload) (*audio.Report, error) {
+	store := resumable.Default()
+
+	if checksum := upload.Metadata["sha256"]; checksum != "" {
+		if err := store.VerifyFile(upload.ID, checksum); err != nil {
+			return nil, audio.Reject(audio.ReasonCorrupt, "The uploaded file does not match its checksum. Please upload it again")
+		}
+	}
+
+	file, err := store.Open(upload.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer file.Close()
+
+	start := time.Now()
+	enhance := enhancementFrom(func(key string) string { return upload.Metadata[key] })
+	_, report, err := storeReference(ctx, upload.ID, file, enhance)
+	if err != nil {
+		return report, err
+	}
+	log.Printf("Finished upload %s in %s", upload.ID, time.Since(start))
+	return report, nil
+}
+
+// parseUploadMetadata decodes "key base64value,key2 base64value2"
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, fmt.Errorf("empty metadata key")
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata value for %s: %w", key, err)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+func setTusHeaders(w http.ResponseWriter) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+}
+
+// writeTusError maps store errors to the status codes tus clients expect
+func writeTusError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, resumable.ErrNotFound):
+		http.Error(w, "Upload not found or expired", http.StatusNotFound)
+	case errors.Is(err, resumable.ErrOffsetMismatch), errors.Is(err, resumable.ErrCompleted):
+		http.Error(w, "Upload-Offset does not match the current offset", http.StatusConflict)
+	case errors.Is(err, resumable.ErrTooLarge):
+		http.Error(w, "Chunk exceeds Upload-Length", http.StatusRequestEntityTooLarge)
+	case errors.Is(err, resumable.ErrChecksumMismatch):
+		// 460 is the tus checksum extension's "Checksum Mismatch"
+		http.Error(w, "Checksum mismatch", 460)
+	case errors.Is(err, resumable.ErrBadChecksum):
+		http.Error(w, "Unsupported checksum", http.StatusBadRequest)
+	default:
+		log.Printf("Upload error: %v", err)
+		http.Error(w, "Upload failed", http.StatusInternalServerError)
+	}
+}
@@ -1,7 +1,23 @@
 package web
 
-import "net/http"
+import (
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
 
+	"github.com/henrik392/youtube-voice-go/internal/storage"
+)
+
+// generatedAudioName matches the names generated speech is saved under
+// (speech_<id>.wav, or <video ID>_speech_<id>.wav) and their mixes with music.
+// Nothing else in downloads/ is served here: uploads, recordings, profile
+// references and consent recordings are someone's own voice.
+var generatedAudioName = regexp.MustCompile(`^(?:[A-Za-z0-9_-]+_)?speech_[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}(?:\.mix)?\.wav$`)
+
+// ServeAudioHandler plays generated speech by path, for generations that
+// couldn't be recorded in the history
 func ServeAudioHandler(w http.ResponseWriter, r *http.Request) {
 	audioPath := r.URL.Query().Get("path")
 	if audioPath == "" {
@@ -9,5 +25,24 @@ func ServeAudioHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	http.ServeFile(w, r, audioPath)
+	// Generated speech is saved at the top of storage, so only the name counts
+	name := filepath.Base(audioPath)
+	if !generatedAudioName.MatchString(name) {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, info, err := storage.Default().Open(name)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to open audio: %v", err)
+		http.Error(w, "Failed to open audio", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
 }
@@ -0,0 +1,41 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestServeAudioOnlyServesGeneratedSpeech(t *testing.T) {
+	const id = "0123abcd-4567-89ef-0123-456789abcdef"
+	served := []string{
+		"speech_" + id + ".wav",
+		"./downloads/speech_" + id + ".wav",
+		"dQw4w9WgXcQ_speech_" + id + ".wav",
+		"speech_" + id + ".mix.wav",
+	}
+	for _, name := range served {
+		if !generatedAudioName.MatchString(filepath.Base(name)) {
+			t.Errorf("%q is not served, want it served", name)
+		}
+	}
+
+	refused := []string{
+		id + ".wav",
+		"uploads/" + id + ".bin",
+		"recordings/" + id + ".pcm",
+		"profiles/" + id + ".wav",
+		"profiles/" + id + ".consent-" + id + ".wav",
+		"speech_" + id + ".wav.json",
+		"../speech_" + id + ".txt",
+	}
+	for _, name := range refused {
+		w := httptest.NewRecorder()
+		ServeAudioHandler(w, httptest.NewRequest(http.MethodGet, "/serve-audio?path="+url.QueryEscape(name), nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("serving %q = %d, want 404", name, w.Code)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/henrik392/youtube-voice-go/cmd/web/components"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+)
+
+const (
+	defaultShareExpiry = 7 * 24 * time.Hour
+	minShareExpiry     = 5 * time.Minute
+)
+
+// CreateShareHandler makes a public link to a generation. The form sends
+// expires-in as a duration ("24h") and allow-download as a checkbox.
+func CreateShareHandler(w http.ResponseWriter, r *http.Request) {
+	gen, err := loadGeneration(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("Failed to load generation: %v", err)
+		renderShareLink(w, r, nil, "The generation could not be found")
+		return
+	}
+
+	expiry := defaultShareExpiry
+	if raw := r.FormValue("expires-in"); raw != "" {
+		expiry, err = time.ParseDuration(raw)
+		if err != nil {
+			renderShareLink(w, r, nil, "Invalid expiry")
+			return
+		}
+	}
+	if maxExpiry := shareMaxExpiry(); expiry < minShareExpiry || expiry > maxExpiry {
+		renderShareLink(w, r, nil, fmt.Sprintf("Links can last between %s and %s", minShareExpiry, maxExpiry))
+		return
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		log.Printf("Failed to create share token: %v", err)
+		renderShareLink(w, r, nil, "Failed to create the link")
+		return
+	}
+
+	share := &database.Share{
+		Token:         token,
+		GenerationID:  gen.ID,
+		AllowDownload: formBool(r.FormValue("allow-download")),
+		ExpiresAt:     time.Now().Add(expiry),
+	}
+	if err := database.New().CreateShare(r.Context(), share); err != nil {
+		log.Printf("Failed to create share: %v", err)
+		renderShareLink(w, r, nil, "Failed to create the link")
+		return
+	}
+
+	log.Printf("Shared generation %s until %s", gen.ID, share.ExpiresAt.Format(time.RFC3339))
+	renderShareLink(w, r, &components.ShareLink{
+		URL:           publicBaseURL(r) + "/s/" + share.Token,
+		ExpiresAt:     share.ExpiresAt,
+		AllowDownload: share.AllowDownload,
+	}, "")
+}
+
+// ListSharesHandler returns a generation's links with their view counts as JSON
+func ListSharesHandler(w http.ResponseWriter, r *http.Request) {
+	gen, err := loadGeneration(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+
+	shares, err := database.New().ListShares(r.Context(), gen.ID)
+	if err != nil {
+		log.Printf("Failed to list shares: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list shares"})
+		return
+	}
+	writeJSON(w, http.StatusOK, shares)
+}
+
+// DeleteShareHandler revokes a link before it expires
+func DeleteShareHandler(w http.ResponseWriter, r *http.Request) {
+	if err := database.New().DeleteShare(r.Context(), chi.URLParam(r, "token")); err != nil {
+		writeShareError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// SharePageHandler renders the public player and counts the visit
+func SharePageHandler(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	share, err := database.New().RecordShareView(r.Context(), token)
+	if errors.Is(err, database.ErrNotFound) {
+		// Tell an expired link apart from one that never existed
+		if expired, getErr := database.New().GetShare(r.Context(), token); getErr == nil {
+			share, err = expired, nil
+		}
+	}
+	if err != nil {
+		writeShareError(w, err)
+		return
+	}
+	if share.Expired() {
+		w.WriteHeader(http.StatusGone)
+		renderSharePage(w, r, SharePageData{Expired: true})
+		return
+	}
+
+	gen, err := database.New().GetGeneration(r.Context(), share.GenerationID)
+	if err != nil {
+		writeShareError(w, err)
+		return
+	}
+
+	base := publicBaseURL(r)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	renderSharePage(w, r, SharePageData{
+		PageURL:       base + "/s/" + share.Token,
+		AudioURL:      base + "/s/" + share.Token + "/audio",
+		Text:          gen.Text,
+		Duration:      gen.Duration,
+		Views:         share.Views,
+		ExpiresAt:     share.ExpiresAt,
+		AllowDownload: share.AllowDownload,
+	})
+}
+
+// ShareAudioHandler streams a shared generation from storage. Range requests
+// from the player don't count as views.
+func ShareAudioHandler(w http.ResponseWriter, r *http.Request) {
+	share, err := database.New().GetShare(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		writeShareError(w, err)
+		return
+	}
+	if share.Expired() {
+		http.Error(w, "This link has expired", http.StatusGone)
+		return
+	}
+
+	download := r.URL.Query().Get("download") != ""
+	if download && !share.AllowDownload {
+		http.Error(w, "Downloading is not allowed for this link", http.StatusForbidden)
+		return
+	}
+
+	gen, err := database.New().GetGeneration(r.Context(), share.GenerationID)
+	if err != nil {
+		writeShareError(w, err)
+		return
+	}
+
+	// A clip with music is shared as the finished mix
+	w.Header().Set("Cache-Control", "private, no-store")
+	serveGenerationAudio(w, r, gen, hasMusic(gen), download)
+}
+
+func renderShareLink(w http.ResponseWriter, r *http.Request, link *components.ShareLink, errorMessage string) {
+	if err := components.ShareLinkResult(link, errorMessage).Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Error rendering ShareLinkResult component: %v", err)
+	}
+}
+
+func renderSharePage(w http.ResponseWriter, r *http.Request, data SharePageData) {
+	if err := SharePage(data).Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Error rendering SharePage: %v", err)
+	}
+}
+
+// shareMaxExpiry is SHARE_MAX_EXPIRY, 30 days by default
+func shareMaxExpiry() time.Duration {
+	if raw := os.Getenv("SHARE_MAX_EXPIRY"); raw != "" {
+		if value, err := time.ParseDuration(raw); err == nil && value >= minShareExpiry {
+			return value
+		}
+		log.Printf("Invalid SHARE_MAX_EXPIRY %q, using the default", raw)
+	}
+	return 30 * 24 * time.Hour
+}
+
+// publicBaseURL is where this instance is reachable from outside, for links
+// and Open Graph tags. PUBLIC_BASE_URL wins over the request's own host.
+func publicBaseURL(r *http.Request) string {
+	if base := os.Getenv("PUBLIC_BASE_URL"); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// newShareToken returns 128 random bits, URL-safe
+func newShareToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func writeShareError(w http.ResponseWriter, err error) {
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("Share lookup error: %v", err)
+	http.Error(w, "Link lookup failed", http.StatusInternalServerError)
+}
+
+// SharePageData is what the public player shows
+type SharePageData struct {
+	Expired       bool
+	PageURL       string
+	AudioURL      string
+	Text          string
+	Duration      float64
+	Views         int
+	ExpiresAt     time.Time
+	AllowDownload bool
+}
@@ -6,14 +6,22 @@ import (
 	"strings"
 
 	"github.com/henrik392/youtube-voice-go/cmd/web/components"
+	"github.com/henrik392/youtube-voice-go/internal/platformstatus"
 	"github.com/henrik392/youtube-voice-go/internal/youtube"
 )
 
 func ValidateURLHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("ValidateURLHandler")
 	url := strings.TrimSpace(r.FormValue("url"))
+	platforms := platformstatus.Default().Statuses(youtube.Platforms)
 
-	component := components.URLInput(youtube.ExtractVideoID(url) != "", url)
+	// Links from a platform that is currently down are refused up front
+	isValid := youtube.ExtractVideoID(url) != ""
+	if platform := youtube.DetectPlatform(url); platform != "" && platformstatus.Default().Status(platform).Disabled() {
+		isValid = false
+	}
+
+	component := components.URLInput(isValid, url, platforms)
 
 	log.Println(youtube.ExtractVideoID(url))
 
@@ -0,0 +1,211 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image/png"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/waveform"
+)
+
+const (
+	// visualSampleRate is the rate audio is decoded at to be drawn. Speech
+	// has little above the 8 kHz it keeps.
+	visualSampleRate = 16000
+
+	spectrogramWidth  = 800
+	spectrogramHeight = 200
+)
+
+// visual is one way of drawing audio, cached next to the audio under suffix
+type visual struct {
+	suffix      string
+	contentType string
+	render      func(samples []float32) ([]byte, error)
+}
+
+var (
+	peaksVisual = visual{
+		suffix:      ".peaks.json",
+		contentType: "application/json",
+		render: func(samples []float32) ([]byte, error) {
+			return json.Marshal(waveform.ComputePeaks(samples, visualSampleRate, waveform.DefaultBuckets))
+		},
+	}
+	spectrogramVisual = visual{
+		suffix:      ".spectrogram.png",
+		contentType: "image/png",
+		render: func(samples []float32) ([]byte, error) {
+			var buf bytes.Buffer
+			err := png.Encode(&buf, waveform.Spectrogram(samples, spectrogramWidth, spectrogramHeight))
+			return buf.Bytes(), err
+		},
+	}
+	visuals = []visual{peaksVisual, spectrogramVisual}
+)
+
+// visualPath is where v of the audio at audioPath is cached
+func visualPath(audioPath string, v visual) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + v.suffix
+}
+
+// cachedVisual returns the path of v drawn for the audio at audioPath,
+// drawing it first if there is no cached copy or the audio has changed
+// since, as it does when effects are re-rendered.
+func cachedVisual(ctx context.Context, audioPath string, v visual) (string, error) {
+	audioInfo, err := os.Stat(audioPath)
+	if err != nil {
+		return "", err
+	}
+	path := visualPath(audioPath, v)
+	if info, err := os.Stat(path); err == nil && !info.ModTime().Before(audioInfo.ModTime()) {
+		return path, nil
+	}
+
+	samples, err := audio.DecodeSamples(ctx, audioPath, visualSampleRate)
+	if err != nil {
+		return "", err
+	}
+	data, err := v.render(samples)
+	if err != nil {
+		return "", err
+	}
+
+	// Written aside and renamed, so a concurrent request never reads half a file
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp.Name(), path)
+}
+
+// removeVisuals deletes whatever is cached for the audio at audioPath
+func removeVisuals(audioPath string) {
+	for _, v := range visuals {
+		os.Remove(visualPath(audioPath, v))
+	}
+}
+
+// serveVisual draws or loads v of the audio at audioPath and serves it
+func serveVisual(w http.ResponseWriter, r *http.Request, audioPath string, v visual) {
+	path, err := cachedVisual(r.Context(), audioPath, v)
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, "Audio file not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to draw %s: %v", audioPath, err)
+		http.Error(w, "Failed to draw audio", http.StatusInternalServerError)
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		http.Error(w, "Failed to draw audio", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "Failed to draw audio", http.StatusInternalServerError)
+		return
+	}
+
+	// The audio behind a URL can change, so always check back
+	w.Header().Set("Content-Type", v.contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, filepath.Base(path), info.ModTime(), file)
+}
+
+// GenerationPeaksHandler serves a generation's waveform peaks as JSON, of its
+// mix with music with ?mix=1
+func GenerationPeaksHandler(w http.ResponseWriter, r *http.Request) {
+	serveGenerationVisual(w, r, peaksVisual)
+}
+
+// GenerationSpectrogramHandler serves a generation's spectrogram as PNG, of
+// its mix with music with ?mix=1
+func GenerationSpectrogramHandler(w http.ResponseWriter, r *http.Request) {
+	serveGenerationVisual(w, r, spectrogramVisual)
+}
+
+func serveGenerationVisual(w http.ResponseWriter, r *http.Request, v visual) {
+	gen, err := loadGeneration(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+	path := gen.AudioPath
+	if r.URL.Query().Get("mix") != "" {
+		if !hasMusic(gen) {
+			http.Error(w, "This generation has no music", http.StatusNotFound)
+			return
+		}
+		path = mixPath(gen.AudioPath)
+	}
+	serveVisual(w, r, path, v)
+}
+
+// ReferencePeaksHandler serves the waveform peaks of an uploaded or recorded
+// reference as JSON
+func ReferencePeaksHandler(w http.ResponseWriter, r *http.Request) {
+	serveReferenceVisual(w, r, peaksVisual)
+}
+
+// ReferenceSpectrogramHandler serves the spectrogram of an uploaded or
+// recorded reference as PNG
+func ReferenceSpectrogramHandler(w http.ResponseWriter, r *http.Request) {
+	serveReferenceVisual(w, r, spectrogramVisual)
+}
+
+func serveReferenceVisual(w http.ResponseWriter, r *http.Request, v visual) {
+	// Uploads and recordings are stored under their ID in the canonical format
+	fileID := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(fileID); err != nil {
+		http.Error(w, "Invalid file ID", http.StatusBadRequest)
+		return
+	}
+	serveVisual(w, r, filepath.Join(downloadsDir, fileID+audio.CanonicalExt), v)
+}
+
+// VideoPeaksHandler serves the waveform peaks of the audio downloaded from a
+// video as JSON
+func VideoPeaksHandler(w http.ResponseWriter, r *http.Request) {
+	serveVideoVisual(w, r, peaksVisual)
+}
+
+// VideoSpectrogramHandler serves the spectrogram of the audio downloaded from
+// a video as PNG
+func VideoSpectrogramHandler(w http.ResponseWriter, r *http.Request) {
+	serveVideoVisual(w, r, spectrogramVisual)
+}
+
+func serveVideoVisual(w http.ResponseWriter, r *http.Request, v visual) {
+	path, err := videoAudioPath(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	serveVisual(w, r, path, v)
+}
@@ -0,0 +1,282 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/henrik392/youtube-voice-go/cmd/web/components"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/provenance"
+	"github.com/henrik392/youtube-voice-go/internal/zonos"
+)
+
+const (
+	maxProfileNameLength = 100
+
+	// zonosProvider is the ProviderVoiceIDs key for the uploaded reference URL
+	zonosProvider = "zonos"
+
+	// profileSampleText is spoken by every profile's preview clip
+	profileSampleText = "Hello! This is a short preview of how this voice sounds."
+)
+
+// profilesDir holds each profile's processed reference (<id>.wav) and preview (<id>.sample.wav)
+var profilesDir = filepath.Join(downloadsDir, "profiles")
+
+// VoiceProfilesHandler serves the saved voice picker component
+func VoiceProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	profiles, err := database.New().ListVoiceProfiles(r.Context())
+	errorMessage := ""
+	if err != nil {
+		log.Printf("Failed to list voice profiles: %v", err)
+		errorMessage = "Saved voices are unavailable right now"
+	}
+
+	component := components.VoiceProfilePicker(profiles, errorMessage, consentRequired(), elevenLabsConfigured())
+	if err := component.Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Error rendering VoiceProfilePicker component: %v", err)
+	}
+}
+
+// CreateVoiceProfileHandler saves the reference currently selected in the
+// form (URL, upload or recording) as a named profile. The preview clip is
+// generated in the background.
+func CreateVoiceProfileHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+	profile, err := createVoiceProfile(r)
+	if err != nil {
+		log.Printf("Failed to save voice profile: %v", err)
+		renderComponent(w, r, retryStatus(w, err, http.StatusOK), components.VoiceProfileSaveResult("", "Failed to save voice: "+err.Error(), consentRequired()))
+		return
+	}
+
+	log.Printf("Saved voice profile %s (%q from %s)", profile.ID, profile.Name, profile.SourceType)
+	go prepareVoiceProfile(*profile)
+
+	renderProfileSaveResult(w, r, profile.Name, "")
+}
+
+// ListVoiceProfilesHandler returns every profile as JSON
+func ListVoiceProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	profiles, err := database.New().ListVoiceProfiles(r.Context())
+	if err != nil {
+		log.Printf("Failed to list voice profiles: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list voice profiles"})
+		return
+	}
+	writeJSON(w, http.StatusOK, profiles)
+}
+
+// GetVoiceProfileHandler returns one profile as JSON
+func GetVoiceProfileHandler(w http.ResponseWriter, r *http.Request) {
+	profile, err := loadVoiceProfile(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, profile)
+}
+
+// VoiceProfileSampleHandler serves a profile's preview clip
+func VoiceProfileSampleHandler(w http.ResponseWriter, r *http.Request) {
+	profile, err := loadVoiceProfile(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+	if profile.SamplePath == "" {
+		http.Error(w, "The preview is not ready yet", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/wav")
+	http.ServeFile(w, r, profile.SamplePath)
+}
+
+// DeleteVoiceProfileHandler removes a profile and its stored audio
+func DeleteVoiceProfileHandler(w http.ResponseWriter, r *http.Request) {
+	profile, err := loadVoiceProfile(r.Context(), chi.URLParam(r, "id"))
+	if err == nil {
+		err = database.New().DeleteVoiceProfile(r.Context(), profile.ID)
+	}
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+
+	os.Remove(profile.ReferencePath)
+	removeProfileClips(profile.ID)
+	if voiceID := profile.ProviderVoiceIDs[elevenLabsProvider]; voiceID != "" {
+		removeElevenLabsVoice(r.Context(), voiceID)
+	}
+	if profile.SamplePath != "" {
+		os.Remove(profile.SamplePath)
+		os.Remove(profile.SamplePath + provenance.SidecarExt)
+	}
+	// Consent records go with the profile, so their recordings do too
+	recordings, _ := filepath.Glob(consentRecordingPath(profile.ID, "*"))
+	for _, recording := range recordings {
+		os.Remove(recording)
+	}
+	log.Printf("Deleted voice profile %s (%q)", profile.ID, profile.Name)
+
+	// HTMX swaps the profile's card for this empty body
+	w.WriteHeader(http.StatusOK)
+}
+
+// createVoiceProfile resolves the form's reference like a generation would,
+// then keeps its own canonical copy so the profile outlives the upload.
+// Several references are merged into one.
+func createVoiceProfile(r *http.Request) (*database.VoiceProfile, error) {
+	name := strings.TrimSpace(r.FormValue("profile-name"))
+	if name == "" {
+		return nil, fmt.Errorf("please give the voice a name")
+	}
+	if utf8.RuneCountInString(name) > maxProfileNameLength {
+		return nil, fmt.Errorf("the name must be %d characters or less", maxProfileNameLength)
+	}
+
+	mode := r.FormValue("audio-mode")
+	paths, err := profileReferences(r, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(profilesDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create profiles directory: %w", err)
+	}
+
+	profile := &database.VoiceProfile{
+		ID:         uuid.New().String(),
+		Name:       name,
+		SourceType: mode,
+	}
+	profile.ReferencePath = filepath.Join(profilesDir, profile.ID+audio.CanonicalExt)
+
+	if len(paths) > 1 {
+		profile.SourceType = mergedSourceType
+		err = mergeProfileReferences(r.Context(), profile, paths)
+	} else {
+		if mode == "url" {
+			profile.Source = r.FormValue("url")
+		}
+		err = audio.Normalize(r.Context(), paths[0], profile.ReferencePath)
+	}
+	if err == nil {
+		err = database.New().CreateVoiceProfile(r.Context(), profile)
+	}
+	if err != nil {
+		os.Remove(profile.ReferencePath)
+		removeProfileClips(profile.ID)
+		return nil, err
+	}
+	return profile, nil
+}
+
+// prepareVoiceProfile uploads a new profile's reference, clones it on
+// ElevenLabs if configured and generates its preview clip. All are best
+// effort: without an uploaded reference the first generation uploads it
+// instead, and a profile without a preview still works.
+// In consent mode this waits until the owner has consented.
+func prepareVoiceProfile(profile database.VoiceProfile) {
+	if err := checkConsent(&profile); err != nil {
+		log.Printf("Voice profile %s waits for consent before its preview", profile.ID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	if os.Getenv("ELEVENLABS_API_KEY") != "" {
+		if err := registerElevenLabsVoice(ctx, &profile); err != nil {
+			log.Printf("Failed to clone voice profile %s on ElevenLabs: %v", profile.ID, err)
+		}
+	}
+
+	client := zonos.NewClient(os.Getenv("FAL_KEY"))
+	audioData, err := cloneFromProfile(ctx, client, &profile, profileSampleText)
+	if err != nil {
+		log.Printf("Failed to generate preview for voice profile %s: %v", profile.ID, err)
+		return
+	}
+
+	samplePath := filepath.Join(profilesDir, profile.ID+".sample"+audio.CanonicalExt)
+	if err := writeGeneratedAudio(ctx, samplePath, profile.ID, zonosProvider, audioData); err != nil {
+		log.Printf("Failed to save preview for voice profile %s: %v", profile.ID, err)
+		return
+	}
+	if err := database.New().SetVoiceProfileSample(ctx, profile.ID, samplePath); err != nil {
+		// The profile was deleted while the preview was being generated
+		log.Printf("Failed to record preview for voice profile %s: %v", profile.ID, err)
+		os.Remove(samplePath)
+		os.Remove(samplePath + provenance.SidecarExt)
+	}
+}
+
+// cloneFromProfile generates speech in a saved voice. The reference is
+// uploaded and transcribed once and both kept on the profile, so later
+// generations skip cropping, transcribing and uploading entirely.
+func cloneFromProfile(ctx context.Context, client *zonos.Client, profile *database.VoiceProfile, text string) ([]byte, error) {
+	ref := zonos.Reference{URL: profile.ProviderVoiceIDs[zonosProvider], Text: profile.ReferenceText}
+	if ref.URL == "" {
+		uploaded, err := client.UploadReference(ctx, profile.ReferencePath)
+		if err != nil {
+			return nil, err
+		}
+		ref = *uploaded
+
+		db := database.New()
+		if err := db.SetProviderVoiceID(ctx, profile.ID, zonosProvider, ref.URL); err != nil {
+			log.Printf("Failed to record uploaded reference for voice profile %s: %v", profile.ID, err)
+		}
+		if ref.Text != "" {
+			if err := db.SetReferenceText(ctx, profile.ID, ref.Text); err != nil {
+				log.Printf("Failed to record reference transcript for voice profile %s: %v", profile.ID, err)
+			}
+		}
+		if profile.ProviderVoiceIDs == nil {
+			profile.ProviderVoiceIDs = map[string]string{}
+		}
+		profile.ProviderVoiceIDs[zonosProvider] = ref.URL
+		profile.ReferenceText = ref.Text
+	}
+
+	return client.VoiceCloneWithReference(ctx, text, ref)
+}
+
+func loadVoiceProfile(ctx context.Context, id string) (*database.VoiceProfile, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, database.ErrNotFound
+	}
+	return database.New().GetVoiceProfile(ctx, id)
+}
+
+func renderProfileSaveResult(w http.ResponseWriter, r *http.Request, name, errorMessage string) {
+	component := components.VoiceProfileSaveResult(name, errorMessage, consentRequired())
+	if err := component.Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Error rendering VoiceProfileSaveResult component: %v", err)
+	}
+}
+
+func writeProfileError(w http.ResponseWriter, err error) {
+	if errors.Is(err, database.ErrNotFound) {
+		http.Error(w, "Voice profile not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("Voice profile error: %v", err)
+	http.Error(w, "Voice profile lookup failed", http.StatusInternalServerError)
+}
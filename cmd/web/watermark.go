@@ -0,0 +1,118 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/watermark"
+)
+
+// watermarkAudio embeds the generation ID into generated speech. Audio that
+// isn't already 16-bit PCM WAV at the watermark's rate is converted first.
+// Failures are logged and leave the audio unmarked.
+func watermarkAudio(ctx context.Context, data []byte, generationID string) []byte {
+	payload, err := watermark.PayloadFromID(generationID)
+	if err != nil {
+		log.Printf("Failed to watermark %s: %v", generationID, err)
+		return data
+	}
+
+	marked, err := watermark.EmbedWAV(data, payload, watermark.DefaultStrength)
+	if errors.Is(err, watermark.ErrUnsupportedFormat) {
+		var converted []byte
+		converted, err = convertForWatermark(ctx, data)
+		if err == nil {
+			marked, err = watermark.EmbedWAV(converted, payload, watermark.DefaultStrength)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to watermark %s: %v", generationID, err)
+		return data
+	}
+	return marked
+}
+
+// convertForWatermark runs data through audio.Normalize, whose output is the
+// format EmbedWAV takes
+func convertForWatermark(ctx context.Context, data []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "watermark_*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input")
+	output := filepath.Join(dir, "output"+audio.CanonicalExt)
+	if err := os.WriteFile(input, data, 0600); err != nil {
+		return nil, err
+	}
+	if err := audio.Normalize(ctx, input, output); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(output)
+}
+
+// detectResult is the answer from /api/watermark/detect
+type detectResult struct {
+	watermark.Result
+	// Generations are this instance's generations matching the payload
+	Generations []database.Generation `json:"generations,omitempty"`
+}
+
+// DetectWatermarkHandler looks for a watermark in an uploaded file ("file")
+// of any format ffmpeg can decode, and lists the generations it points to.
+func DetectWatermarkHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Expected a multipart form with a file"})
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "No file provided"})
+		return
+	}
+	defer file.Close()
+
+	tmp, err := os.CreateTemp("", "detect_*")
+	if err != nil {
+		log.Printf("Failed to create temp file: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to read file"})
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, file)
+	tmp.Close()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Failed to read file"})
+		return
+	}
+
+	samples, err := audio.DecodeSamples(r.Context(), tmp.Name(), watermark.SampleRate)
+	if err != nil {
+		log.Printf("Failed to decode file for watermark detection: %v", err)
+		if status := retryStatus(w, err, 0); status == http.StatusServiceUnavailable {
+			http.Error(w, "Server busy, please retry", status)
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "The file could not be decoded as audio"})
+		return
+	}
+
+	result := detectResult{Result: watermark.Detect(samples)}
+	if result.Found {
+		result.Generations, err = database.New().FindGenerationsByPrefix(r.Context(), result.Prefix)
+		if err != nil {
+			log.Printf("Failed to look up watermark %s: %v", result.Prefix, err)
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
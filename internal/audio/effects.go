@@ -0,0 +1,183 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Effects is a post-processing chain for generated speech. The zero value
+// leaves audio untouched. Effects are applied in a fixed order: tempo and
+// pitch, EQ, compression, reverb, loudness, then fades, so nothing after
+// the fades can bring the edges back up.
+type Effects struct {
+	// Tempo speeds speech up (> 1) or slows it down without changing its
+	// pitch, from 0.5 to 2. 0 and 1 leave it as is.
+	Tempo float64 `json:"tempo,omitempty"`
+	// Pitch shifts the voice up or down by this many semitones, from -12 to
+	// 12, without changing its tempo
+	Pitch float64 `json:"pitch,omitempty"`
+	// EQ is the name of one of EQPresets
+	EQ string `json:"eq,omitempty"`
+	// Reverb is how much room to add, from 0 (dry) to 1
+	Reverb float64 `json:"reverb,omitempty"`
+	// Compress evens out loud and quiet passages
+	Compress bool `json:"compress,omitempty"`
+	// Loudness normalizes to this integrated loudness in LUFS, from -36 to
+	// -6. 0 leaves the level as is.
+	Loudness float64 `json:"loudness,omitempty"`
+	// FadeIn and FadeOut are in seconds, at most MaxFade each
+	FadeIn  float64 `json:"fade_in,omitempty"`
+	FadeOut float64 `json:"fade_out,omitempty"`
+}
+
+// Limits of the effect parameters
+const (
+	MinTempo    = 0.5
+	MaxTempo    = 2
+	MaxPitch    = 12
+	MinLoudness = -36
+	MaxLoudness = -6
+	MaxFade     = 10
+
+	// minReverb is the least reverb applied once any is asked for
+	minReverb = 0.01
+)
+
+// EQPresets are the equalizer curves Effects.EQ can name, as ffmpeg filters.
+var EQPresets = map[string]string{
+	// Presence boost and less mud: clearer speech
+	"voice":  "highpass=f=80,equalizer=f=300:t=q:w=1:g=-2,equalizer=f=3500:t=q:w=1:g=3",
+	"warm":   "equalizer=f=180:t=q:w=1:g=3,treble=g=-2:f=6000",
+	"bright": "bass=g=-2:f=150,treble=g=4:f=5000",
+	// Narrow band like a phone line
+	"telephone": "highpass=f=300,lowpass=f=3400,equalizer=f=1500:t=q:w=1:g=4",
+}
+
+// EQPresetNames lists EQPresets in alphabetical order.
+func EQPresetNames() []string {
+	names := make([]string, 0, len(EQPresets))
+	for name := range EQPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Enabled reports whether e changes the audio at all.
+func (e Effects) Enabled() bool {
+	return e.Speed() != 1 || e.Pitch != 0 || e.EQ != "" || e.Reverb > 0 || e.Compress ||
+		e.Loudness != 0 || e.FadeIn > 0 || e.FadeOut > 0
+}
+
+// Speed is the factor e changes the length of audio by: the output is
+// 1/Speed as long as the input.
+func (e Effects) Speed() float64 {
+	if e.Tempo == 0 {
+		return 1
+	}
+	return e.Tempo
+}
+
+// Validate checks every parameter is within its limits.
+func (e Effects) Validate() error {
+	switch {
+	case e.Tempo != 0 && (e.Tempo < MinTempo || e.Tempo > MaxTempo || math.IsNaN(e.Tempo)):
+		return fmt.Errorf("tempo must be between %g and %g", float64(MinTempo), float64(MaxTempo))
+	case math.Abs(e.Pitch) > MaxPitch || math.IsNaN(e.Pitch):
+		return fmt.Errorf("pitch must be between -%d and %d semitones", MaxPitch, MaxPitch)
+	case e.EQ != "" && EQPresets[e.EQ] == "":
+		return fmt.Errorf("unknown EQ preset %q, use one of %s", e.EQ, strings.Join(EQPresetNames(), ", "))
+	case e.Reverb < 0 || e.Reverb > 1 || math.IsNaN(e.Reverb):
+		return fmt.Errorf("reverb must be between 0 and 1")
+	case e.Loudness != 0 && (e.Loudness < MinLoudness || e.Loudness > MaxLoudness || math.IsNaN(e.Loudness)):
+		return fmt.Errorf("loudness must be between %d and %d LUFS", MinLoudness, MaxLoudness)
+	case e.FadeIn < 0 || e.FadeIn > MaxFade || math.IsNaN(e.FadeIn),
+		e.FadeOut < 0 || e.FadeOut > MaxFade || math.IsNaN(e.FadeOut):
+		return fmt.Errorf("fades must be between 0 and %d seconds", MaxFade)
+	}
+	return nil
+}
+
+// Filters returns the ffmpeg filtergraph for e, applied to audio that is
+// duration seconds long before any tempo change. The fade out needs the
+// duration; without it (0) it is left out.
+func (e Effects) Filters(duration float64) []string {
+	filters := []string{}
+
+	// Pitch is shifted by resampling, which also changes the tempo; atempo
+	// makes up for that together with the tempo asked for
+	speed := e.Speed()
+	if e.Pitch != 0 {
+		factor := math.Pow(2, e.Pitch/12)
+		rate := strconv.Itoa(CanonicalSampleRate)
+		filters = append(filters,
+			"aresample="+rate,
+			"asetrate="+strconv.Itoa(int(math.Round(CanonicalSampleRate*factor))),
+			"aresample="+rate)
+		speed /= factor
+	}
+	if math.Abs(speed-1) > 1e-4 {
+		filters = append(filters, tempoFilters(speed)...)
+	}
+
+	if e.EQ != "" {
+		filters = append(filters, EQPresets[e.EQ])
+	}
+	if e.Compress {
+		filters = append(filters, "acompressor=threshold=-20dB:ratio=3:attack=5:release=100:makeup=2")
+	}
+	if e.Reverb > 0 {
+		// Early reflections and a decaying tail, scaled by the amount. aecho
+		// refuses decays that round to 0, so tiny amounts get the least.
+		amount := max(e.Reverb, minReverb)
+		filters = append(filters, fmt.Sprintf("aecho=0.9:%.3f:37|61|97|149:%.3f|%.3f|%.3f|%.3f",
+			1-0.2*amount, 0.5*amount, 0.4*amount, 0.3*amount, 0.2*amount))
+	}
+	if e.Loudness != 0 {
+		filters = append(filters, fmt.Sprintf("loudnorm=I=%g:TP=-1.5:LRA=11", e.Loudness))
+	}
+
+	if e.FadeIn > 0 {
+		filters = append(filters, fmt.Sprintf("afade=t=in:st=0:d=%.3f", e.FadeIn))
+	}
+	if length := duration / e.Speed(); e.FadeOut > 0 && length > 0 {
+		fade := min(e.FadeOut, length)
+		filters = append(filters, fmt.Sprintf("afade=t=out:st=%.3f:d=%.3f", length-fade, fade))
+	}
+	return filters
+}
+
+// ApplyEffects runs inputPath through e and writes canonical WAV to outputPath.
+func ApplyEffects(ctx context.Context, inputPath, outputPath string, e Effects) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+
+	duration := 0.0
+	if e.FadeOut > 0 {
+		info, err := Probe(ctx, inputPath)
+		if err != nil {
+			return err
+		}
+		duration = info.Duration.Seconds()
+	}
+
+	args := []string{"-i", inputPath, "-map", "0:a:0"}
+	if filters := e.Filters(duration); len(filters) > 0 {
+		args = append(args, "-af", strings.Join(filters, ","))
+	}
+	err := runFFmpeg(ctx, append(args,
+		"-acodec", "pcm_s16le",
+		// loudnorm resamples internally, so pin the output rate
+		"-ar", strconv.Itoa(CanonicalSampleRate),
+		"-ac", strconv.Itoa(CanonicalChannels),
+		outputPath)...)
+	if err != nil {
+		return fmt.Errorf("error applying effects: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Enhancement selects optional clean-up applied to a reference before use.
+type Enhancement struct {
+	// AutoGain normalizes loudness so quiet recordings reach a usable level
+	AutoGain bool
+	// NoiseReduction removes rumble and steady background noise
+	NoiseReduction bool
+}
+
+func (e Enhancement) Enabled() bool {
+	return e.AutoGain || e.NoiseReduction
+}
+
+// Filters returns the ffmpeg audio filter chain for the enhancement.
+// Noise reduction runs first so auto-gain doesn't bring the noise up with the voice.
+func (e Enhancement) Filters() []string {
+	filters := []string{}
+	if e.NoiseReduction {
+		filters = append(filters, "highpass=f=80", "afftdn=nr=12:nf=-50:tn=1")
+	}
+	if e.AutoGain {
+		filters = append(filters, "loudnorm=I=-18:TP=-2:LRA=11")
+	}
+	return filters
+}
+
+// Enhance applies e to inputPath and writes canonical WAV to outputPath.
+func Enhance(ctx context.Context, inputPath, outputPath string, e Enhancement) error {
+	err := runFFmpeg(ctx,
+		"-i", inputPath,
+		"-map", "0:a:0",
+		"-af", strings.Join(e.Filters(), ","),
+		"-acodec", "pcm_s16le",
+		// loudnorm resamples internally, so pin the output rate
+		"-ar", strconv.Itoa(CanonicalSampleRate),
+		"-ac", strconv.Itoa(CanonicalChannels),
+		outputPath)
+	if err != nil {
+		return fmt.Errorf("error enhancing audio: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/henrik392/youtube-voice-go/internal/workerpool"
+)
+
+// runTool runs ffmpeg or ffprobe while holding a slot in the ffmpeg pool and
+// returns stdout. stderr is folded into the error on failure.
+func runTool(ctx context.Context, name string, args ...string) ([]byte, error) {
+	release, err := workerpool.FFmpeg().Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("waiting for ffmpeg slot: %w", err)
+	}
+	defer release()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = 5 * time.Second
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%s stopped: %w", name, ctx.Err())
+		}
+		return nil, fmt.Errorf("%s failed: %v (output: %s)", name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return output, nil
+}
+
+func runFFmpeg(ctx context.Context, args ...string) error {
+	_, err := runTool(ctx, "ffmpeg", append([]string{"-hide_banner", "-nostdin", "-y"}, args...)...)
+	return err
+}
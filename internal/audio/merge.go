@@ -0,0 +1,132 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// referenceLoudness is the level every clip of a merged reference is
+	// brought to, in LUFS, so no clip drowns out the others
+	referenceLoudness = -20
+	// silenceThreshold and minSilence say what counts as a pause worth
+	// removing from a reference: quieter than -45 dB for half a second
+	silenceThreshold = "-45dB"
+	minSilence       = 0.5
+)
+
+// prepareFilters bring a reference clip to referenceLoudness in the
+// canonical format, without its leading silence or any long pauses.
+func prepareFilters() string {
+	return strings.Join([]string{
+		fmt.Sprintf("silenceremove=start_periods=1:start_threshold=%[1]s:stop_periods=-1:stop_duration=%[2]s:stop_threshold=%[1]s",
+			silenceThreshold, strconv.FormatFloat(minSilence, 'f', -1, 64)),
+		fmt.Sprintf("loudnorm=I=%d:TP=-2:LRA=11", referenceLoudness),
+		// loudnorm works at 192 kHz
+		"aresample=" + strconv.Itoa(CanonicalSampleRate),
+	}, ",")
+}
+
+// PrepareReference writes inputPath to outputPath as one clip of a merged
+// reference: canonical PCM WAV at a common loudness with silences removed,
+// and at most maxSeconds long, or any length if maxSeconds is 0.
+func PrepareReference(ctx context.Context, inputPath, outputPath string, maxSeconds float64) error {
+	args := []string{
+		"-i", inputPath,
+		"-map", "0:a:0",
+		"-vn",
+		"-map_metadata", "-1",
+		"-af", prepareFilters(),
+	}
+	if maxSeconds > 0 {
+		args = append(args, "-t", strconv.FormatFloat(maxSeconds, 'f', 3, 64))
+	}
+	args = append(args,
+		"-acodec", "pcm_s16le",
+		"-ar", strconv.Itoa(CanonicalSampleRate),
+		"-ac", strconv.Itoa(CanonicalChannels),
+		outputPath)
+	if err := runFFmpeg(ctx, args...); err != nil {
+		return fmt.Errorf("error preparing reference: %w", err)
+	}
+	return nil
+}
+
+// Shares splits maxSeconds between clips of the given durations so each
+// gets as much of it as possible: clips shorter than an equal share are used
+// whole and what they leave is split among the longer ones.
+func Shares(durations []float64, maxSeconds float64) []float64 {
+	shares := make([]float64, len(durations))
+	order := make([]int, len(durations))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return durations[order[a]] < durations[order[b]] })
+
+	left := maxSeconds
+	for n, i := range order {
+		share := left / float64(len(order)-n)
+		shares[i] = min(durations[i], share)
+		left -= shares[i]
+	}
+	return shares
+}
+
+// Concat joins the first seconds[i] of each of inputPaths, in order, into
+// outputPath in the canonical format.
+func Concat(ctx context.Context, inputPaths []string, seconds []float64, outputPath string) error {
+	if len(inputPaths) == 0 || len(seconds) != len(inputPaths) {
+		return fmt.Errorf("concat needs one length per input")
+	}
+
+	var args []string
+	var graph strings.Builder
+	for i, path := range inputPaths {
+		args = append(args, "-i", path)
+		fmt.Fprintf(&graph, "[%d:a]atrim=end=%s,asetpts=PTS-STARTPTS[c%d];", i, strconv.FormatFloat(seconds[i], 'f', 3, 64), i)
+	}
+	for i := range inputPaths {
+		fmt.Fprintf(&graph, "[c%d]", i)
+	}
+	fmt.Fprintf(&graph, "concat=n=%d:v=0:a=1[out]", len(inputPaths))
+
+	args = append(args,
+		"-filter_complex", graph.String(),
+		"-map", "[out]",
+		"-acodec", "pcm_s16le",
+		"-ar", strconv.Itoa(CanonicalSampleRate),
+		"-ac", strconv.Itoa(CanonicalChannels),
+		outputPath)
+	if err := runFFmpeg(ctx, args...); err != nil {
+		return fmt.Errorf("error joining references: %w", err)
+	}
+	return nil
+}
+
+// MergeReferences prepares each of inputPaths into the matching clipPaths,
+// as PrepareReference does with clipSeconds, then joins the clips into
+// outputPath, giving each its share of maxSeconds.
+func MergeReferences(ctx context.Context, inputPaths, clipPaths []string, outputPath string, clipSeconds, maxSeconds float64) error {
+	if len(clipPaths) != len(inputPaths) {
+		return fmt.Errorf("merging needs one clip path per input")
+	}
+
+	durations := make([]float64, len(inputPaths))
+	for i, path := range inputPaths {
+		if err := PrepareReference(ctx, path, clipPaths[i], clipSeconds); err != nil {
+			return err
+		}
+		info, err := Probe(ctx, clipPaths[i])
+		if err != nil {
+			return err
+		}
+		// A clip that was nothing but silence adds nothing
+		if durations[i] = info.Duration.Seconds(); durations[i] == 0 {
+			return fmt.Errorf("reference %d has no speech left once silence is removed", i+1)
+		}
+	}
+	return Concat(ctx, clipPaths, Shares(durations, maxSeconds), outputPath)
+}
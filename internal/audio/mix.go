@@ -0,0 +1,144 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Mix describes how speech is laid over background music.
+type Mix struct {
+	// MusicVolume and VoiceVolume are gains in dB
+	MusicVolume float64 `json:"music_volume"`
+	VoiceVolume float64 `json:"voice_volume"`
+	// Ducking is how far the music dips under the voice, from 0 (not at
+	// all) to 1. The voice drives a sidechain compressor on the music.
+	Ducking float64 `json:"ducking"`
+	// Loop repeats music shorter than the mix. Longer music is always trimmed.
+	Loop bool `json:"loop"`
+	// LeadIn and Tail are seconds of music before and after the voice
+	LeadIn float64 `json:"lead_in"`
+	Tail   float64 `json:"tail"`
+	// FadeIn and FadeOut fade the music at the start and end of the mix, in seconds
+	FadeIn  float64 `json:"fade_in"`
+	FadeOut float64 `json:"fade_out"`
+}
+
+// DefaultMix keeps the music well under the voice, ducks it further while
+// the voice speaks and gives it a second before and two after.
+var DefaultMix = Mix{
+	MusicVolume: -14,
+	Ducking:     0.6,
+	Loop:        true,
+	LeadIn:      1,
+	Tail:        2,
+	FadeIn:      1,
+	FadeOut:     2,
+}
+
+// Limits of the mix parameters
+const (
+	MinMusicVolume = -40
+	MaxMusicVolume = 6
+	MinVoiceVolume = -20
+	MaxVoiceVolume = 12
+	MaxPadding     = 30
+)
+
+// Validate checks every parameter is within its limits.
+func (m Mix) Validate() error {
+	outside := func(value, low, high float64) bool {
+		return value < low || value > high || math.IsNaN(value)
+	}
+	switch {
+	case outside(m.MusicVolume, MinMusicVolume, MaxMusicVolume):
+		return fmt.Errorf("music volume must be between %d and %d dB", MinMusicVolume, MaxMusicVolume)
+	case outside(m.VoiceVolume, MinVoiceVolume, MaxVoiceVolume):
+		return fmt.Errorf("voice volume must be between %d and %d dB", MinVoiceVolume, MaxVoiceVolume)
+	case outside(m.Ducking, 0, 1):
+		return fmt.Errorf("ducking must be between 0 and 1")
+	case outside(m.LeadIn, 0, MaxPadding), outside(m.Tail, 0, MaxPadding):
+		return fmt.Errorf("lead-in and tail must be between 0 and %d seconds", MaxPadding)
+	case outside(m.FadeIn, 0, MaxFade), outside(m.FadeOut, 0, MaxFade):
+		return fmt.Errorf("fades must be between 0 and %d seconds", MaxFade)
+	}
+	return nil
+}
+
+// Filtergraph returns the ffmpeg filter_complex mixing the voice (input 0)
+// over the music (input 1) for a voice that is duration seconds long. Its
+// output is labelled [out] and runs LeadIn + duration + Tail seconds.
+func (m Mix) Filtergraph(duration float64) string {
+	total := m.Length(duration)
+	rate := strconv.Itoa(CanonicalSampleRate)
+	prepare := "aresample=" + rate + ",aformat=channel_layouts=mono"
+
+	voice := []string{prepare, fmt.Sprintf("volume=%gdB", m.VoiceVolume)}
+	if m.LeadIn > 0 {
+		voice = append(voice, fmt.Sprintf("adelay=delays=%d:all=1", int(math.Round(m.LeadIn*1000))))
+	}
+	voice = append(voice, fmt.Sprintf("apad=whole_dur=%.3f", total))
+
+	music := []string{prepare, fmt.Sprintf("volume=%gdB", m.MusicVolume), fmt.Sprintf("atrim=end=%.3f", total), "asetpts=N/SR/TB"}
+	if m.FadeIn > 0 {
+		music = append(music, fmt.Sprintf("afade=t=in:st=0:d=%.3f", min(m.FadeIn, total)))
+	}
+	if m.FadeOut > 0 {
+		fade := min(m.FadeOut, total)
+		music = append(music, fmt.Sprintf("afade=t=out:st=%.3f:d=%.3f", total-fade, fade))
+	}
+
+	graph := []string{}
+	if m.Ducking > 0 {
+		// The voice is split so one copy can key the compressor on the music
+		graph = append(graph,
+			"[0:a]"+strings.Join(voice, ",")+",asplit=2[voice][key]",
+			"[1:a]"+strings.Join(music, ",")+"[music]",
+			fmt.Sprintf("[music][key]sidechaincompress=threshold=0.02:ratio=%g:attack=20:release=350[ducked]", 1+19*m.Ducking),
+			"[ducked][voice]amix=inputs=2:duration=longest:dropout_transition=0:normalize=0[out]")
+	} else {
+		graph = append(graph,
+			"[0:a]"+strings.Join(voice, ",")+"[voice]",
+			"[1:a]"+strings.Join(music, ",")+"[music]",
+			"[music][voice]amix=inputs=2:duration=longest:dropout_transition=0:normalize=0[out]")
+	}
+	return strings.Join(graph, ";")
+}
+
+// Length is how long a mix of a voice of duration seconds runs.
+func (m Mix) Length(duration float64) float64 {
+	return m.LeadIn + duration + m.Tail
+}
+
+// MixMusic lays the voice at voicePath over the music at musicPath as m
+// describes and writes canonical WAV to outputPath.
+func MixMusic(ctx context.Context, voicePath, musicPath, outputPath string, m Mix) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	info, err := Probe(ctx, voicePath)
+	if err != nil {
+		return err
+	}
+	duration := info.Duration.Seconds()
+
+	args := []string{"-i", voicePath}
+	if m.Loop {
+		args = append(args, "-stream_loop", "-1")
+	}
+	args = append(args,
+		"-i", musicPath,
+		"-filter_complex", m.Filtergraph(duration),
+		"-map", "[out]",
+		"-t", strconv.FormatFloat(m.Length(duration), 'f', 3, 64),
+		"-acodec", "pcm_s16le",
+		"-ar", strconv.Itoa(CanonicalSampleRate),
+		"-ac", strconv.Itoa(CanonicalChannels),
+		outputPath)
+	if err := runFFmpeg(ctx, args...); err != nil {
+		return fmt.Errorf("error mixing music: %w", err)
+	}
+	return nil
+}
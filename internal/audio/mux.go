@@ -0,0 +1,35 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReplaceAudio writes a copy of videoPath to outputPath with audioPath as its
+// first, default sound track, tagged with language (an ISO 639 code, may be
+// empty). The video's own sound is kept as a second track so players can
+// switch back to it. The video stream is copied, not re-encoded.
+func ReplaceAudio(ctx context.Context, videoPath, audioPath, outputPath, language string) error {
+	args := []string{
+		"-i", videoPath,
+		"-i", audioPath,
+		"-map", "0:v:0",
+		"-map", "1:a:0",
+		"-map", "0:a:0?",
+		"-c:v", "copy",
+		"-c:a", "aac",
+		"-b:a", "160k",
+		"-disposition:a:0", "default",
+		"-disposition:a:1", "0",
+		"-metadata:s:a:1", "title=Original",
+	}
+	if language != "" {
+		args = append(args, "-metadata:s:a:0", "language="+language)
+	}
+	args = append(args, "-movflags", "+faststart", outputPath)
+
+	if err := runFFmpeg(ctx, args...); err != nil {
+		return fmt.Errorf("error muxing audio: %w", err)
+	}
+	return nil
+}
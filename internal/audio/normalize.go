@@ -0,0 +1,32 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Canonical intermediate format every reference is converted to before use.
+const (
+	CanonicalExt        = ".wav"
+	CanonicalSampleRate = 44100
+	CanonicalChannels   = 1
+)
+
+// Normalize decodes inputPath and writes it to outputPath as 16-bit PCM WAV at
+// CanonicalSampleRate in mono, dropping any video, cover art and metadata.
+func Normalize(ctx context.Context, inputPath, outputPath string) error {
+	err := runFFmpeg(ctx,
+		"-i", inputPath,
+		"-map", "0:a:0",
+		"-vn",
+		"-map_metadata", "-1",
+		"-acodec", "pcm_s16le",
+		"-ar", strconv.Itoa(CanonicalSampleRate),
+		"-ac", strconv.Itoa(CanonicalChannels),
+		outputPath)
+	if err != nil {
+		return fmt.Errorf("error normalizing audio: %w", err)
+	}
+	return nil
+}
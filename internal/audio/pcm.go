@@ -0,0 +1,31 @@
+package audio
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// DecodeSamples decodes the first audio stream of path to mono samples in
+// [-1, 1) at sampleRate, for analysis done in Go rather than by ffmpeg.
+func DecodeSamples(ctx context.Context, path string, sampleRate int) ([]float32, error) {
+	output, err := runTool(ctx, "ffmpeg",
+		"-hide_banner", "-nostdin",
+		"-i", path,
+		"-map", "0:a:0",
+		"-ac", "1",
+		"-ar", strconv.Itoa(sampleRate),
+		"-f", "s16le",
+		"-acodec", "pcm_s16le",
+		"pipe:1")
+	if err != nil {
+		return nil, fmt.Errorf("error decoding audio: %w", err)
+	}
+
+	samples := make([]float32, len(output)/2)
+	for i := range samples {
+		samples[i] = float32(int16(binary.LittleEndian.Uint16(output[2*i:]))) / 32768
+	}
+	return samples, nil
+}
@@ -0,0 +1,104 @@
+package audio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Info describes the first audio stream of a file as reported by ffprobe.
+type Info struct {
+	Format     string
+	Codec      string
+	Duration   time.Duration
+	Channels   int
+	SampleRate int
+	BitRate    int
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType  string `json:"codec_type"`
+		CodecName  string `json:"codec_name"`
+		Channels   int    `json:"channels"`
+		SampleRate string `json:"sample_rate"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"streams"`
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+// ErrNoAudioStream is returned by Probe for files without any audio stream.
+var ErrNoAudioStream = fmt.Errorf("no audio stream found")
+
+// Probe reads container and stream information with ffprobe.
+func Probe(ctx context.Context, path string) (*Info, error) {
+	output, err := runTool(ctx, "ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		path)
+	if err != nil {
+		return nil, err
+	}
+
+	var probe ffprobeOutput
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("error parsing ffprobe output: %w", err)
+	}
+
+	for _, stream := range probe.Streams {
+		if stream.CodecType != "audio" {
+			continue
+		}
+
+		info := &Info{
+			Format:   probe.Format.FormatName,
+			Codec:    stream.CodecName,
+			Channels: stream.Channels,
+		}
+		info.SampleRate, _ = strconv.Atoi(stream.SampleRate)
+
+		// Some containers (webm, streamed mp3) only carry the duration at format
+		// level, and browser MediaRecorder WebM carries none, so it is decoded
+		duration := stream.Duration
+		if duration == "" || duration == "N/A" {
+			duration = probe.Format.Duration
+		}
+		if seconds, err := strconv.ParseFloat(duration, 64); err == nil {
+			info.Duration = time.Duration(seconds * float64(time.Second))
+		} else if info.Duration, err = decodedDuration(ctx, path); err != nil {
+			return nil, err
+		}
+
+		bitRate := stream.BitRate
+		if bitRate == "" || bitRate == "N/A" {
+			bitRate = probe.Format.BitRate
+		}
+		info.BitRate, _ = strconv.Atoi(bitRate)
+
+		return info, nil
+	}
+
+	return nil, ErrNoAudioStream
+}
+
+// durationSampleRate is all decodedDuration needs to count the length
+const durationSampleRate = 1000
+
+// decodedDuration measures the audio of path by decoding all of it, for
+// containers that don't say how long they are.
+func decodedDuration(ctx context.Context, path string) (time.Duration, error) {
+	samples, err := DecodeSamples(ctx, path, durationSampleRate)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(len(samples)) * time.Second / durationSampleRate, nil
+}
@@ -0,0 +1,246 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ReasonPoorQuality rejects audio that decodes fine but would make a bad reference.
+const ReasonPoorQuality Reason = "poor_quality"
+
+// analysisSampleRate is plenty for level measurements and keeps decoding cheap.
+const analysisSampleRate = 16000
+
+const (
+	// frameLength is the window levels are measured over
+	frameLength = 30 * time.Millisecond
+	// silenceLevel is the frame level, in dBFS, below which a frame counts as silence
+	silenceLevel = -45.0
+	// clipLevel is the sample magnitude treated as clipped
+	clipLevel = 0.999
+	minLevel  = -120.0
+)
+
+// Analysis holds level measurements of a recording. Levels are in dBFS.
+type Analysis struct {
+	Duration float64 `json:"duration"`
+	RMS      float64 `json:"rms"`
+	Peak     float64 `json:"peak"`
+	// ClippedRatio is the share of samples at full scale
+	ClippedRatio float64 `json:"clipped_ratio"`
+	// NoiseFloor and SpeechLevel are the quiet and loud ends of the frame levels
+	NoiseFloor  float64 `json:"noise_floor"`
+	SpeechLevel float64 `json:"speech_level"`
+	SNR         float64 `json:"snr"`
+	// SilenceRatio is the share of frames below silenceLevel
+	SilenceRatio float64 `json:"silence_ratio"`
+}
+
+// AnalyzeFile decodes path and measures it.
+func AnalyzeFile(ctx context.Context, path string) (*Analysis, error) {
+	samples, err := DecodeSamples(ctx, path, analysisSampleRate)
+	if err != nil {
+		return nil, err
+	}
+	analysis := Analyze(samples, analysisSampleRate)
+	return &analysis, nil
+}
+
+// Analyze measures mono samples. The noise floor is estimated as the 10th
+// percentile of 30ms frame levels and the speech level as the 95th, which
+// holds up as long as the speaker pauses now and then.
+func Analyze(samples []float32, sampleRate int) Analysis {
+	analysis := Analysis{
+		Duration:    float64(len(samples)) / float64(sampleRate),
+		RMS:         minLevel,
+		Peak:        minLevel,
+		NoiseFloor:  minLevel,
+		SpeechLevel: minLevel,
+	}
+	if len(samples) == 0 {
+		return analysis
+	}
+
+	var clipped int
+	analysis.RMS, analysis.Peak, clipped = Levels(samples)
+	analysis.ClippedRatio = float64(clipped) / float64(len(samples))
+
+	frameSize := int(frameLength.Seconds() * float64(sampleRate))
+	levels := make([]float64, 0, len(samples)/frameSize+1)
+	silent := 0
+	for start := 0; start < len(samples); start += frameSize {
+		frame := samples[start:min(start+frameSize, len(samples))]
+		var frameSquares float64
+		for _, sample := range frame {
+			frameSquares += float64(sample) * float64(sample)
+		}
+		level := decibels(math.Sqrt(frameSquares / float64(len(frame))))
+		levels = append(levels, level)
+		if level < silenceLevel {
+			silent++
+		}
+	}
+	slices.Sort(levels)
+
+	analysis.NoiseFloor = percentile(levels, 0.10)
+	analysis.SpeechLevel = percentile(levels, 0.95)
+	analysis.SNR = analysis.SpeechLevel - analysis.NoiseFloor
+	analysis.SilenceRatio = float64(silent) / float64(len(levels))
+
+	return analysis
+}
+
+// Levels returns the RMS and peak level of samples in dBFS and how many
+// samples are clipped.
+func Levels(samples []float32) (rms, peak float64, clipped int) {
+	if len(samples) == 0 {
+		return minLevel, minLevel, 0
+	}
+
+	var sumSquares, maxMagnitude float64
+	for _, sample := range samples {
+		magnitude := math.Abs(float64(sample))
+		sumSquares += magnitude * magnitude
+		maxMagnitude = math.Max(maxMagnitude, magnitude)
+		if magnitude >= clipLevel {
+			clipped++
+		}
+	}
+	return decibels(math.Sqrt(sumSquares / float64(len(samples)))), decibels(maxMagnitude), clipped
+}
+
+// Severity tells whether an issue blocks the recording or is only advice.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is one problem found by the quality gate, phrased as advice to the user.
+type Issue struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// QualityLimits are the thresholds of the quality gate. Each check has a
+// hard limit that rejects the recording and a softer one that only warns,
+// except noise: the SNR estimate can't tell a noisy room from speech
+// without pauses, so both of its limits only warn.
+type QualityLimits struct {
+	MinDuration         time.Duration
+	RecommendedDuration time.Duration
+	MinSpeechLevel      float64
+	QuietSpeechLevel    float64
+	MaxClippedRatio     float64
+	WarnClippedRatio    float64
+	NoisySNR            float64
+	WarnSNR             float64
+	MaxSilenceRatio     float64
+	WarnSilenceRatio    float64
+}
+
+// ReferenceQuality applies to reference recordings and uploads.
+var ReferenceQuality = QualityLimits{
+	MinDuration:         3 * time.Second,
+	RecommendedDuration: 10 * time.Second,
+	MinSpeechLevel:      -40,
+	QuietSpeechLevel:    -30,
+	MaxClippedRatio:     0.01,
+	WarnClippedRatio:    0.001,
+	NoisySNR:            10,
+	WarnSNR:             20,
+	MaxSilenceRatio:     0.8,
+	WarnSilenceRatio:    0.5,
+}
+
+// Report is the outcome of the quality gate.
+type Report struct {
+	Analysis Analysis `json:"analysis"`
+	Issues   []Issue  `json:"issues"`
+	Passed   bool     `json:"passed"`
+}
+
+// Assess checks an analysis against limits.
+func Assess(analysis Analysis, limits QualityLimits) *Report {
+	report := &Report{Analysis: analysis, Issues: []Issue{}}
+	add := func(code string, severity Severity, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, Issue{Code: code, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	duration := time.Duration(analysis.Duration * float64(time.Second))
+	switch {
+	case duration < limits.MinDuration:
+		add("too_short", SeverityError, "Too short: the recording is %.1f seconds long. Record at least %.0f seconds of speech", analysis.Duration, limits.MinDuration.Seconds())
+	case duration < limits.RecommendedDuration:
+		add("short", SeverityWarning, "Short recording: %.0f seconds or more of speech gives a closer clone", limits.RecommendedDuration.Seconds())
+	}
+
+	switch {
+	case analysis.SpeechLevel < limits.MinSpeechLevel:
+		add("too_quiet", SeverityError, "Too quiet: move closer to the microphone, speak up, or turn on auto-gain")
+	case analysis.SpeechLevel < limits.QuietSpeechLevel:
+		add("quiet", SeverityWarning, "A little quiet: speaking closer to the microphone will improve the clone")
+	}
+
+	switch {
+	case analysis.ClippedRatio > limits.MaxClippedRatio:
+		add("clipping", SeverityError, "Distorted: the recording is clipping. Move back from the microphone or lower its input volume")
+	case analysis.ClippedRatio > limits.WarnClippedRatio:
+		add("some_clipping", SeverityWarning, "Some loud parts are clipping; lowering the microphone volume slightly would help")
+	}
+
+	// Noise and silence only mean something once the speech itself is loud
+	// enough; otherwise "too quiet" already says it all
+	if analysis.SpeechLevel >= limits.MinSpeechLevel {
+		switch {
+		case analysis.SNR < limits.NoisySNR:
+			add("noisy", SeverityWarning, "Noisy: background noise may be almost as loud as your voice. Record somewhere quieter or turn on noise reduction")
+		case analysis.SNR < limits.WarnSNR:
+			add("some_noise", SeverityWarning, "Some background noise: a quieter room or noise reduction will give a cleaner clone")
+		}
+
+		switch {
+		case analysis.SilenceRatio > limits.MaxSilenceRatio:
+			add("mostly_silent", SeverityError, "Mostly silence: %.0f%% of the recording has no speech. Start speaking right after you press record", analysis.SilenceRatio*100)
+		case analysis.SilenceRatio > limits.WarnSilenceRatio:
+			add("long_pauses", SeverityWarning, "Long pauses: about %.0f%% of the recording is silence", analysis.SilenceRatio*100)
+		}
+	}
+
+	report.Passed = !slices.ContainsFunc(report.Issues, func(issue Issue) bool { return issue.Severity == SeverityError })
+	return report
+}
+
+// Rejection summarises the blocking issues, or returns nil if the report passed.
+func (r *Report) Rejection() *RejectionError {
+	if r.Passed {
+		return nil
+	}
+	messages := []string{}
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			messages = append(messages, issue.Message)
+		}
+	}
+	return Reject(ReasonPoorQuality, "%s", strings.Join(messages, ". "))
+}
+
+func decibels(amplitude float64) float64 {
+	if amplitude <= 0 {
+		return minLevel
+	}
+	return math.Max(20*math.Log10(amplitude), minLevel)
+}
+
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return minLevel
+	}
+	return sorted[int(p*float64(len(sorted)-1))]
+}
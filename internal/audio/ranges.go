@@ -0,0 +1,127 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Range is a stretch of a recording, in seconds from its start.
+type Range struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// Ranges are stretches of a recording to be joined in order, as picked for
+// a voice reference.
+type Ranges []Range
+
+const (
+	// MaxRanges is how many stretches can be picked from one recording
+	MaxRanges = 10
+	// MinRangeLength is the shortest stretch worth keeping, in seconds
+	MinRangeLength = 0.5
+	// rangeSlack absorbs rounding between the duration a browser draws and
+	// the one ffprobe reports
+	rangeSlack = 0.1
+)
+
+// ParseRanges reads ranges written as "start-end" pairs of seconds separated
+// by commas, such as "0-12.5,20-31". An empty string is no ranges.
+func ParseRanges(s string) (Ranges, error) {
+	var ranges Ranges
+	if strings.TrimSpace(s) == "" {
+		return ranges, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		start, end, ok := strings.Cut(strings.TrimSpace(part), "-")
+		if !ok {
+			return nil, fmt.Errorf("range %q is not start-end", part)
+		}
+		var r Range
+		var err error
+		if r.Start, err = strconv.ParseFloat(start, 64); err != nil || !finite(r.Start) {
+			return nil, fmt.Errorf("range %q has an invalid start", part)
+		}
+		if r.End, err = strconv.ParseFloat(end, 64); err != nil || !finite(r.End) {
+			return nil, fmt.Errorf("range %q has an invalid end", part)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// String writes the ranges the way ParseRanges reads them.
+func (rs Ranges) String() string {
+	parts := make([]string, len(rs))
+	for i, r := range rs {
+		parts[i] = strconv.FormatFloat(r.Start, 'f', -1, 64) + "-" + strconv.FormatFloat(r.End, 'f', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Total is how long the joined ranges last, in seconds.
+func (rs Ranges) Total() float64 {
+	total := 0.0
+	for _, r := range rs {
+		total += r.End - r.Start
+	}
+	return total
+}
+
+// Validate checks the ranges fall within a recording of duration seconds, in
+// order and without overlapping, and together last at most maxTotal seconds.
+// Ends a fraction past the duration are clamped to it.
+func (rs Ranges) Validate(duration, maxTotal float64) error {
+	if len(rs) > MaxRanges {
+		return fmt.Errorf("at most %d ranges can be picked", MaxRanges)
+	}
+	previousEnd := 0.0
+	for i := range rs {
+		r := &rs[i]
+		if r.End > duration && r.End <= duration+rangeSlack {
+			r.End = duration
+		}
+		switch {
+		case !finite(r.Start) || !finite(r.End):
+			return fmt.Errorf("%g-%g is not a range of seconds", r.Start, r.End)
+		case r.Start < 0 || r.End > duration:
+			return fmt.Errorf("%.1f-%.1f is outside the %.1f second recording", r.Start, r.End, duration)
+		case r.End-r.Start < MinRangeLength:
+			return fmt.Errorf("%.1f-%.1f is shorter than %.1f seconds", r.Start, r.End, MinRangeLength)
+		case r.Start < previousEnd:
+			return fmt.Errorf("%.1f-%.1f overlaps or comes before the range ahead of it", r.Start, r.End)
+		}
+		previousEnd = r.End
+	}
+	if total := rs.Total(); total > maxTotal+rangeSlack {
+		return fmt.Errorf("the ranges last %.1f seconds, more than the %.0f allowed", total, maxTotal)
+	}
+	return nil
+}
+
+// finite rules out NaN, which passes every comparison, and infinities
+func finite(x float64) bool {
+	return !math.IsNaN(x) && !math.IsInf(x, 0)
+}
+
+// Filtergraph cuts the ranges out of the first input and joins them, leaving
+// the result on [out].
+func (rs Ranges) Filtergraph() string {
+	var graph strings.Builder
+	fmt.Fprintf(&graph, "[0:a]asplit=%d", len(rs))
+	for i := range rs {
+		fmt.Fprintf(&graph, "[s%d]", i)
+	}
+	graph.WriteString(";")
+	for i, r := range rs {
+		fmt.Fprintf(&graph, "[s%d]atrim=start=%s:end=%s,asetpts=PTS-STARTPTS[r%d];",
+			i, strconv.FormatFloat(r.Start, 'f', 3, 64), strconv.FormatFloat(r.End, 'f', 3, 64), i)
+	}
+	for i := range rs {
+		fmt.Fprintf(&graph, "[r%d]", i)
+	}
+	fmt.Fprintf(&graph, "concat=n=%d:v=0:a=1[out]", len(rs))
+	return graph.String()
+}
@@ -0,0 +1,32 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseRangesRejectsNonFinite(t *testing.T) {
+	for _, s := range []string{"NaN-5", "0-NaN", "Inf-5", "0-Inf", "0-+Inf"} {
+		if ranges, err := ParseRanges(s); err == nil {
+			t.Errorf("ParseRanges(%q) = %v, want an error", s, ranges)
+		}
+	}
+	if ranges, err := ParseRanges("0-12.5, 20-31"); err != nil || ranges.String() != "0-12.5,20-31" {
+		t.Errorf("ParseRanges = %v, %v", ranges, err)
+	}
+}
+
+func TestValidateRejectsNonFinite(t *testing.T) {
+	for _, r := range []Range{
+		{Start: math.NaN(), End: 5},
+		{Start: 0, End: math.NaN()},
+		{Start: math.Inf(-1), End: 5},
+	} {
+		if err := (Ranges{r}).Validate(60, 30); err == nil {
+			t.Errorf("Validate(%v) passed, want an error", r)
+		}
+	}
+	if err := (Ranges{{Start: 1, End: 5}, {Start: 10, End: 20}}).Validate(60, 30); err != nil {
+		t.Errorf("Validate = %v, want valid ranges to pass", err)
+	}
+}
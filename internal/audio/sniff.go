@@ -0,0 +1,57 @@
+package audio
+
+import (
+	"bytes"
+)
+
+// Container formats recognised from their leading bytes.
+const (
+	FormatUnknown = ""
+	FormatMP3     = "mp3"
+	FormatWAV     = "wav"
+	FormatFLAC    = "flac"
+	FormatOGG     = "ogg"
+	FormatMP4     = "mp4"
+	FormatWebM    = "webm"
+)
+
+// SniffLen is how many leading bytes Sniff needs to recognise every format.
+const SniffLen = 512
+
+// Sniff identifies the container of an audio file from its first bytes,
+// ignoring whatever name or Content-Type the client claimed.
+func Sniff(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte("ID3")):
+		return FormatMP3
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WAVE")):
+		return FormatWAV
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return FormatFLAC
+	case bytes.HasPrefix(header, []byte("OggS")):
+		return FormatOGG
+	case len(header) >= 8 && bytes.Equal(header[4:8], []byte("ftyp")):
+		return FormatMP4
+	case bytes.HasPrefix(header, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return FormatWebM
+	case isMPEGFrame(header):
+		return FormatMP3
+	}
+	return FormatUnknown
+}
+
+// isMPEGFrame checks for an MPEG audio frame sync without an ID3 tag:
+// 11 set bits, a valid version and layer, and a bitrate index that isn't "bad".
+func isMPEGFrame(header []byte) bool {
+	if len(header) < 4 {
+		return false
+	}
+	if header[0] != 0xFF || header[1]&0xE0 != 0xE0 {
+		return false
+	}
+	version := (header[1] >> 3) & 0x03
+	layer := (header[1] >> 1) & 0x03
+	bitrate := header[2] >> 4
+	sampleRate := (header[2] >> 2) & 0x03
+	return version != 0x01 && layer != 0x00 && bitrate != 0x0F && sampleRate != 0x03
+}
@@ -0,0 +1,43 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ChangeTempo speeds inputPath up (tempo > 1) or slows it down without
+// changing its pitch and writes canonical WAV to outputPath.
+func ChangeTempo(ctx context.Context, inputPath, outputPath string, tempo float64) error {
+	if tempo <= 0 {
+		return fmt.Errorf("invalid tempo %g", tempo)
+	}
+
+	err := runFFmpeg(ctx,
+		"-i", inputPath,
+		"-map", "0:a:0",
+		"-af", strings.Join(tempoFilters(tempo), ","),
+		"-acodec", "pcm_s16le",
+		"-ar", strconv.Itoa(CanonicalSampleRate),
+		"-ac", strconv.Itoa(CanonicalChannels),
+		outputPath)
+	if err != nil {
+		return fmt.Errorf("error changing tempo: %w", err)
+	}
+	return nil
+}
+
+// tempoFilters chains atempo filters, each of which only takes 0.5 to 2.
+func tempoFilters(tempo float64) []string {
+	filters := []string{}
+	for tempo > 2 {
+		filters = append(filters, "atempo=2")
+		tempo /= 2
+	}
+	for tempo < 0.5 {
+		filters = append(filters, "atempo=0.5")
+		tempo /= 0.5
+	}
+	return append(filters, "atempo="+strconv.FormatFloat(tempo, 'f', 4, 64))
+}
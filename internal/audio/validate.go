@@ -0,0 +1,108 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Reason is a machine-readable cause for rejecting an audio file.
+type Reason string
+
+const (
+	ReasonTooLarge         Reason = "too_large"
+	ReasonUnknownFormat    Reason = "unknown_format"
+	ReasonUnsupportedCodec Reason = "unsupported_codec"
+	ReasonNoAudio          Reason = "no_audio"
+	ReasonCorrupt          Reason = "corrupt"
+	ReasonTooShort         Reason = "too_short"
+	ReasonTooLong          Reason = "too_long"
+	ReasonBadChannels      Reason = "bad_channels"
+	ReasonBadSampleRate    Reason = "bad_sample_rate"
+)
+
+// RejectionError explains why a file was refused, in words fit for the user.
+type RejectionError struct {
+	Reason Reason
+	Detail string
+}
+
+func (e *RejectionError) Error() string {
+	return e.Detail
+}
+
+func Reject(reason Reason, format string, args ...interface{}) *RejectionError {
+	return &RejectionError{Reason: reason, Detail: fmt.Sprintf(format, args...)}
+}
+
+// AsRejection returns the RejectionError wrapped in err, if any.
+func AsRejection(err error) (*RejectionError, bool) {
+	var rejection *RejectionError
+	if errors.As(err, &rejection) {
+		return rejection, true
+	}
+	return nil, false
+}
+
+// Limits are the properties an accepted audio file must have.
+type Limits struct {
+	MinDuration   time.Duration
+	MaxDuration   time.Duration
+	MaxChannels   int
+	MinSampleRate int
+	MaxSampleRate int
+	Codecs        []string
+}
+
+// UploadLimits apply to reference audio uploaded by users.
+var UploadLimits = Limits{
+	MinDuration:   time.Second,
+	MaxDuration:   10 * time.Minute,
+	MaxChannels:   2,
+	MinSampleRate: 8000,
+	MaxSampleRate: 192000,
+	Codecs:        []string{"mp3", "pcm_s16le", "pcm_s24le", "pcm_s32le", "pcm_f32le", "pcm_u8", "flac", "vorbis", "opus", "aac", "alac"},
+}
+
+// Validate sniffs and probes path and checks the result against limits.
+// Failures caused by the file itself come back as *RejectionError.
+func Validate(ctx context.Context, path string, header []byte, limits Limits) (*Info, error) {
+	format := Sniff(header)
+	if format == FormatUnknown {
+		return nil, Reject(ReasonUnknownFormat, "The file is not a recognised audio format. Please upload MP3, WAV, M4A, OGG, or FLAC files")
+	}
+
+	info, err := Probe(ctx, path)
+	if errors.Is(err, ErrNoAudioStream) {
+		return nil, Reject(ReasonNoAudio, "The file does not contain an audio track")
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, Reject(ReasonCorrupt, "The file looks like %s but could not be read; it may be damaged", format)
+	}
+
+	if len(limits.Codecs) > 0 && !slices.Contains(limits.Codecs, info.Codec) {
+		return nil, Reject(ReasonUnsupportedCodec, "Audio encoded as %s is not supported", info.Codec)
+	}
+	if info.Duration <= 0 {
+		return nil, Reject(ReasonCorrupt, "Could not determine the length of the audio; it may be damaged")
+	}
+	if limits.MinDuration > 0 && info.Duration < limits.MinDuration {
+		return nil, Reject(ReasonTooShort, "The audio is %.1f seconds long; at least %.0f seconds are needed", info.Duration.Seconds(), limits.MinDuration.Seconds())
+	}
+	if limits.MaxDuration > 0 && info.Duration > limits.MaxDuration {
+		return nil, Reject(ReasonTooLong, "The audio is %s long; the maximum is %s", info.Duration.Round(time.Second), limits.MaxDuration)
+	}
+	if info.Channels < 1 || (limits.MaxChannels > 0 && info.Channels > limits.MaxChannels) {
+		return nil, Reject(ReasonBadChannels, "Audio with %d channels is not supported; use mono or stereo", info.Channels)
+	}
+	if info.SampleRate < limits.MinSampleRate || (limits.MaxSampleRate > 0 && info.SampleRate > limits.MaxSampleRate) {
+		return nil, Reject(ReasonBadSampleRate, "A sample rate of %d Hz is not supported", info.SampleRate)
+	}
+
+	return info, nil
+}
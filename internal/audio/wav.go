@@ -0,0 +1,102 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// WAVHeaderSize is the length of the header written by WAVHeader.
+const WAVHeaderSize = 44
+
+// WAVHeader returns a canonical RIFF header for dataSize bytes of 16-bit
+// little-endian PCM.
+func WAVHeader(sampleRate, channels int, dataSize uint32) []byte {
+	const bitsPerSample = 16
+	blockAlign := channels * bitsPerSample / 8
+
+	header := make([]byte, WAVHeaderSize)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], 36+dataSize)
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:24], uint16(channels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:36], bitsPerSample)
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], dataSize)
+	return header
+}
+
+// ErrNotPCM16 is returned by ParseWAV for anything but 16-bit PCM WAV.
+var ErrNotPCM16 = errors.New("not a 16-bit PCM WAV file")
+
+// WAVLayout locates the samples inside a 16-bit PCM WAV file.
+type WAVLayout struct {
+	SampleRate int
+	Channels   int
+	// DataOffset and DataSize delimit the interleaved little-endian samples
+	DataOffset int
+	DataSize   int
+}
+
+// ParseWAV reads the fmt and data chunks of a 16-bit PCM WAV file. A data
+// size that runs past the end, as streaming encoders write it, is cut to fit.
+func ParseWAV(data []byte) (*WAVLayout, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, ErrNotPCM16
+	}
+
+	var layout WAVLayout
+	haveFormat := false
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		start := offset + 8
+		if size < 0 || start+size > len(data) {
+			size = len(data) - start
+		}
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, ErrNotPCM16
+			}
+			format := binary.LittleEndian.Uint16(data[start:])
+			bits := binary.LittleEndian.Uint16(data[start+14:])
+			// 0xFFFE is WAVE_FORMAT_EXTENSIBLE, which ffmpeg uses for PCM too
+			if (format != 1 && format != 0xFFFE) || bits != 16 {
+				return nil, ErrNotPCM16
+			}
+			layout.Channels = int(binary.LittleEndian.Uint16(data[start+2:]))
+			layout.SampleRate = int(binary.LittleEndian.Uint32(data[start+4:]))
+			haveFormat = layout.Channels > 0
+		case "data":
+			if !haveFormat {
+				return nil, ErrNotPCM16
+			}
+			layout.DataOffset = start
+			layout.DataSize = size - size%(2*layout.Channels)
+			return &layout, nil
+		}
+		offset = start + size + size%2
+	}
+	return nil, ErrNotPCM16
+}
+
+// EncodeWAV writes mono samples in [-1, 1] as a 16-bit PCM WAV file,
+// clipping anything outside that range.
+func EncodeWAV(samples []float32, sampleRate int) []byte {
+	data := make([]byte, WAVHeaderSize+2*len(samples))
+	copy(data, WAVHeader(sampleRate, 1, uint32(2*len(samples))))
+	for i, s := range samples {
+		value := math.Round(float64(s) * 32768)
+		value = math.Max(math.MinInt16, math.Min(math.MaxInt16, value))
+		binary.LittleEndian.PutUint16(data[WAVHeaderSize+2*i:], uint16(int16(value)))
+	}
+	return data
+}
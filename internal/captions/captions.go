@@ -0,0 +1,208 @@
+// Package captions turns timed words into subtitle cues and writes them as
+// SRT or WebVTT. Word timings come from the speech provider when it reports
+// them, and are otherwise estimated from where the speech is in the audio.
+package captions
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Word is one spoken word with its position in seconds.
+type Word struct {
+	Text  string  `json:"text"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// Cue is one caption on screen.
+type Cue struct {
+	Start float64
+	End   float64
+	Lines []string
+}
+
+// Rules are the readability limits cues are built to.
+type Rules struct {
+	// MaxLineLength and MaxLines bound the text of a cue, in characters
+	MaxLineLength int
+	MaxLines      int
+	// MinDuration and MaxDuration bound how long a cue is shown, in seconds.
+	// Short cues are held longer if the next one leaves room.
+	MinDuration float64
+	MaxDuration float64
+	// MaxPause is the silence between words, in seconds, that always starts a new cue
+	MaxPause float64
+}
+
+// DefaultRules follow common broadcast guidelines: two lines of 42
+// characters, shown for 1 to 7 seconds.
+var DefaultRules = Rules{
+	MaxLineLength: 42,
+	MaxLines:      2,
+	MinDuration:   1,
+	MaxDuration:   7,
+	MaxPause:      0.7,
+}
+
+// minGap keeps consecutive cues from touching, so players don't merge them
+const minGap = 0.04
+
+// Build groups words into cues following rules.
+func Build(words []Word, rules Rules) []Cue {
+	cues := []Cue{}
+	var current []Word
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		lines, _ := wrap(texts(current), rules.MaxLineLength, rules.MaxLines)
+		cues = append(cues, Cue{Start: current[0].Start, End: current[len(current)-1].End, Lines: lines})
+		current = nil
+	}
+
+	for _, word := range words {
+		if strings.TrimSpace(word.Text) == "" {
+			continue
+		}
+		if len(current) > 0 {
+			last := current[len(current)-1]
+			_, fits := wrap(append(texts(current), word.Text), rules.MaxLineLength, rules.MaxLines)
+			switch {
+			case !fits,
+				word.End-current[0].Start > rules.MaxDuration,
+				word.Start-last.End >= rules.MaxPause,
+				endsSentence(last.Text) && last.End-current[0].Start >= rules.MinDuration:
+				flush()
+			}
+		}
+		current = append(current, word)
+	}
+	flush()
+
+	// Hold short cues longer where the next one leaves room, and never overlap
+	for i := range cues {
+		limit := cues[i].Start + rules.MaxDuration
+		if i+1 < len(cues) {
+			limit = min(limit, cues[i+1].Start-minGap)
+		}
+		if cues[i].End-cues[i].Start < rules.MinDuration {
+			cues[i].End = max(cues[i].End, min(cues[i].Start+rules.MinDuration, limit))
+		}
+		cues[i].End = min(cues[i].End, max(limit, cues[i].Start))
+	}
+	return cues
+}
+
+// wrap breaks words into at most maxLines lines of maxLength, as evenly as
+// possible. fits is false if they don't fit; the lines are still returned.
+func wrap(words []string, maxLength, maxLines int) (lines []string, fits bool) {
+	// A word too long for a line still has to go somewhere
+	text := strings.Join(words, " ")
+	if len([]rune(text)) <= maxLength || len(words) == 1 {
+		return []string{text}, true
+	}
+
+	// Two lines read best balanced, so pick the break closest to the middle
+	if maxLines == 2 {
+		best, bestLength := -1, 0
+		for i := 1; i < len(words); i++ {
+			longest := max(len([]rune(strings.Join(words[:i], " "))), len([]rune(strings.Join(words[i:], " "))))
+			if best == -1 || longest < bestLength {
+				best, bestLength = i, longest
+			}
+		}
+		lines = []string{strings.Join(words[:best], " "), strings.Join(words[best:], " ")}
+		return lines, bestLength <= maxLength
+	}
+
+	line := ""
+	for _, word := range words {
+		if line != "" && len([]rune(line))+1+len([]rune(word)) > maxLength {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	lines = append(lines, line)
+
+	fits = len(lines) <= maxLines
+	for _, l := range lines {
+		fits = fits && len([]rune(l)) <= maxLength
+	}
+	return lines, fits
+}
+
+func texts(words []Word) []string {
+	out := make([]string, len(words))
+	for i, w := range words {
+		out[i] = strings.TrimSpace(w.Text)
+	}
+	return out
+}
+
+// endsSentence reports whether word closes a sentence, ignoring closing quotes
+func endsSentence(word string) bool {
+	word = strings.TrimRightFunc(word, func(r rune) bool {
+		return r == '"' || r == '\'' || r == ')' || r == '”' || r == '’'
+	})
+	return strings.HasSuffix(word, ".") || strings.HasSuffix(word, "!") || strings.HasSuffix(word, "?") || strings.HasSuffix(word, "…")
+}
+
+// FromCharacters groups per-character timings, as reported by providers like
+// ElevenLabs, into words.
+func FromCharacters(characters []string, starts, ends []float64) []Word {
+	words := []Word{}
+	inWord := false
+	for i, c := range characters {
+		if i >= len(starts) || i >= len(ends) {
+			break
+		}
+		if strings.TrimSpace(c) == "" {
+			inWord = false
+			continue
+		}
+		if !inWord {
+			words = append(words, Word{Start: starts[i]})
+			inWord = true
+		}
+		words[len(words)-1].Text += c
+		words[len(words)-1].End = ends[i]
+	}
+	return words
+}
+
+// Scale stretches word timings by factor, e.g. 1/tempo for audio that was
+// sped up.
+func Scale(words []Word, factor float64) []Word {
+	scaled := make([]Word, len(words))
+	for i, w := range words {
+		scaled[i] = Word{Text: w.Text, Start: w.Start * factor, End: w.End * factor}
+	}
+	return scaled
+}
+
+// Shift moves word timings later by offset seconds, e.g. for audio that
+// starts after a lead-in.
+func Shift(words []Word, offset float64) []Word {
+	shifted := make([]Word, len(words))
+	for i, w := range words {
+		shifted[i] = Word{Text: w.Text, Start: w.Start + offset, End: w.End + offset}
+	}
+	return shifted
+}
+
+// weight is how long a word takes to say relative to others: its letters and
+// digits, with a floor for numbers and symbols that are spoken out in full
+func weight(word string) float64 {
+	n := 0
+	for _, r := range word {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			n++
+		}
+	}
+	return float64(max(n, 2))
+}
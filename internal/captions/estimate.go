@@ -0,0 +1,205 @@
+package captions
+
+import (
+	"math"
+	"strings"
+)
+
+// Span is a stretch of audio, in seconds.
+type Span struct {
+	Start float64
+	End   float64
+}
+
+const (
+	// frameLength is the analysis window of SpeechSpans, in seconds
+	frameLength = 0.02
+	// minPause is the shortest silence that separates two chunks of speech
+	minPause = 0.2
+	// speechRange is how far below the loudest frame speech may be, in dB
+	speechRange = 35
+	// noiseFloor is the level under which nothing counts as speech, in dBFS
+	noiseFloor = -55
+	// minSpan is the shortest chunk that can hold a word, in seconds
+	minSpan = 0.1
+)
+
+// SpeechSpans finds the chunks of speech in mono samples, split at pauses of
+// at least 200 ms.
+func SpeechSpans(samples []float32, sampleRate int) []Span {
+	frame := int(frameLength * float64(sampleRate))
+	if frame == 0 || len(samples) < frame {
+		return nil
+	}
+
+	levels := make([]float64, len(samples)/frame)
+	loudest := math.Inf(-1)
+	for i := range levels {
+		sum := 0.0
+		for _, s := range samples[i*frame : (i+1)*frame] {
+			sum += float64(s) * float64(s)
+		}
+		levels[i] = 10 * math.Log10(sum/float64(frame)+1e-12)
+		loudest = max(loudest, levels[i])
+	}
+	threshold := max(loudest-speechRange, noiseFloor)
+
+	spans := []Span{}
+	pauseFrames := int(math.Ceil(minPause / frameLength))
+	start, silent := -1, 0
+	for i, level := range levels {
+		if level >= threshold {
+			if start < 0 {
+				start = i
+			}
+			silent = 0
+			continue
+		}
+		if start < 0 {
+			continue
+		}
+		silent++
+		if silent >= pauseFrames {
+			spans = append(spans, Span{Start: float64(start) * frameLength, End: float64(i-silent+1) * frameLength})
+			start, silent = -1, 0
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, Span{Start: float64(start) * frameLength, End: float64(len(levels)-silent) * frameLength})
+	}
+	return spans
+}
+
+// Estimate times the words of text against spans, the chunks of speech in
+// the audio, as a forced aligner would without a model: the pauses between
+// chunks are matched to the breaks in the text (punctuation first) so that
+// each chunk gets about as much text as its length calls for, then words are
+// spread over their chunk in proportion to their length.
+func Estimate(text string, spans []Span) []Word {
+	fields := strings.Fields(text)
+	spans = mergeSpans(spans, len(fields))
+	if len(fields) == 0 || len(spans) == 0 {
+		return nil
+	}
+
+	starts := assignSpans(fields, spans)
+	words := make([]Word, 0, len(fields))
+	for j, span := range spans {
+		end := len(fields)
+		if j+1 < len(spans) {
+			end = starts[j+1]
+		}
+		group := fields[starts[j]:end]
+
+		total := 0.0
+		for _, field := range group {
+			total += weight(field)
+		}
+		at := span.Start
+		for _, field := range group {
+			length := weight(field) / total * (span.End - span.Start)
+			words = append(words, Word{Text: field, Start: at, End: at + length})
+			at += length
+		}
+	}
+	return words
+}
+
+const (
+	// Costs of ending a chunk after a word, in the squared seconds the
+	// aligner weighs mismatched chunk lengths in
+	breakSentence = 0
+	breakClause   = 0.02
+	breakNone     = 0.25
+)
+
+// assignSpans splits fields into one non-empty run per span and returns the
+// index of the first field of each. It minimises the squared difference
+// between each run's expected and actual duration plus the cost of the
+// breaks it picks.
+func assignSpans(fields []string, spans []Span) []int {
+	n, k := len(fields), len(spans)
+	prefix := make([]float64, n+1)
+	for i, field := range fields {
+		prefix[i+1] = prefix[i] + weight(field)
+	}
+	voiced := 0.0
+	for _, span := range spans {
+		voiced += span.End - span.Start
+	}
+	secondsPerWeight := voiced / prefix[n]
+
+	breakCost := func(i int) float64 {
+		// Ending a chunk after field i-1
+		if i == n {
+			return 0
+		}
+		word := fields[i-1]
+		switch {
+		case endsSentence(word):
+			return breakSentence
+		case strings.HasSuffix(word, ",") || strings.HasSuffix(word, ";") || strings.HasSuffix(word, ":") || strings.HasSuffix(word, "—"):
+			return breakClause
+		}
+		return breakNone
+	}
+
+	// cost[j][i] is the best cost of putting fields[:i] into spans[:j]
+	cost := make([][]float64, k+1)
+	from := make([][]int, k+1)
+	for j := range cost {
+		cost[j] = make([]float64, n+1)
+		from[j] = make([]int, n+1)
+		for i := range cost[j] {
+			cost[j][i] = math.Inf(1)
+		}
+	}
+	cost[0][0] = 0
+	for j := 1; j <= k; j++ {
+		length := spans[j-1].End - spans[j-1].Start
+		// Every earlier span needs a field, and so does every later one
+		for i := j; i <= n-(k-j); i++ {
+			for start := j - 1; start < i; start++ {
+				if math.IsInf(cost[j-1][start], 1) {
+					continue
+				}
+				miss := (prefix[i]-prefix[start])*secondsPerWeight - length
+				c := cost[j-1][start] + miss*miss + breakCost(i)
+				if c < cost[j][i] {
+					cost[j][i] = c
+					from[j][i] = start
+				}
+			}
+		}
+	}
+
+	starts := make([]int, k)
+	i := n
+	for j := k; j > 0; j-- {
+		i = from[j][i]
+		starts[j-1] = i
+	}
+	return starts
+}
+
+// mergeSpans drops blips too short to hold a word and closes the shortest
+// pauses until there are no more chunks than words
+func mergeSpans(spans []Span, words int) []Span {
+	kept := []Span{}
+	for _, span := range spans {
+		if span.End-span.Start >= minSpan {
+			kept = append(kept, span)
+		}
+	}
+	for len(kept) > max(words, 1) {
+		shortest := 0
+		for i := 1; i < len(kept)-1; i++ {
+			if kept[i+1].Start-kept[i].End < kept[shortest+1].Start-kept[shortest].End {
+				shortest = i
+			}
+		}
+		kept[shortest].End = kept[shortest+1].End
+		kept = append(kept[:shortest+1], kept[shortest+2:]...)
+	}
+	return kept
+}
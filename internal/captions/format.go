@@ -0,0 +1,61 @@
+package captions
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Formats captions can be written in, by file extension
+const (
+	FormatSRT = "srt"
+	FormatVTT = "vtt"
+)
+
+// ContentTypes maps each format to its MIME type.
+var ContentTypes = map[string]string{
+	FormatSRT: "application/x-subrip",
+	FormatVTT: "text/vtt",
+}
+
+// SRT formats cues as SubRip.
+func SRT(cues []Cue) []byte {
+	var b bytes.Buffer
+	for i, cue := range cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, timestamp(cue.Start, ','), timestamp(cue.End, ','), strings.Join(cue.Lines, "\n"))
+	}
+	return b.Bytes()
+}
+
+// VTT formats cues as WebVTT.
+func VTT(cues []Cue) []byte {
+	var b bytes.Buffer
+	b.WriteString("WEBVTT\n\n")
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	for _, cue := range cues {
+		fmt.Fprintf(&b, "%s --> %s\n", timestamp(cue.Start, '.'), timestamp(cue.End, '.'))
+		for _, line := range cue.Lines {
+			b.WriteString(escape.Replace(line))
+			b.WriteByte('\n')
+		}
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// Format formats cues as SRT or VTT.
+func Format(cues []Cue, format string) ([]byte, error) {
+	switch format {
+	case FormatSRT:
+		return SRT(cues), nil
+	case FormatVTT:
+		return VTT(cues), nil
+	}
+	return nil, fmt.Errorf("unknown caption format %q", format)
+}
+
+// timestamp formats seconds as HH:MM:SS with milliseconds after sep
+func timestamp(seconds float64, sep byte) string {
+	ms := int64(max(seconds, 0)*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
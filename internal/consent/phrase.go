@@ -0,0 +1,115 @@
+// Package consent checks that a voice's owner agreed to it being cloned: they
+// read a freshly generated phrase aloud, and the transcript of that recording
+// has to match it.
+package consent
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// Statement opens every phrase, so the recording says what is being agreed to.
+const Statement = "I own this voice and I agree to it being cloned."
+
+const (
+	// codeWords random words follow the statement, so an old recording or a
+	// clip cut from a video can't be replayed
+	codeWords = 4
+
+	// MinScore is the share of the phrase's words that has to be heard,
+	// allowing for a transcriber's small slips
+	MinScore = 0.8
+)
+
+// wordList avoids homophones and numbers, which transcribers spell unpredictably
+var wordList = []string{
+	"amber", "anchor", "apple", "arrow", "autumn", "banana", "basket", "bicycle",
+	"blanket", "bottle", "bridge", "bucket", "butter", "cabin", "camera", "candle",
+	"canyon", "carpet", "castle", "cherry", "circus", "cobalt", "copper", "cotton",
+	"dolphin", "dragon", "eagle", "engine", "falcon", "feather", "forest", "garden",
+	"ginger", "glacier", "guitar", "hammer", "harbor", "helmet", "island", "jacket",
+	"jungle", "kettle", "ladder", "lantern", "lemon", "magnet", "marble", "meadow",
+	"mirror", "monkey", "orange", "orchid", "oyster", "pencil", "pepper", "pillow",
+	"planet", "pocket", "rabbit", "ribbon", "river", "rocket", "saddle", "silver",
+	"spider", "statue", "sunset", "tiger", "tomato", "tunnel", "turtle", "velvet",
+	"violin", "wagon", "walnut", "window", "winter", "yellow", "zebra",
+}
+
+// NewPhrase returns the statement followed by random code words.
+func NewPhrase() (string, error) {
+	code := make([]string, codeWords)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(wordList))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = wordList[n.Int64()]
+	}
+	return Statement + " My code is " + strings.Join(code, " ") + ".", nil
+}
+
+// Match scores how much of phrase was heard in transcript: the share of its
+// words left after the fewest insertions, deletions and substitutions turning
+// one into the other. It only passes if every code word was heard as well.
+func Match(phrase, transcript string) (score float64, ok bool) {
+	expected := Words(phrase)
+	heard := Words(transcript)
+	if len(expected) == 0 {
+		return 0, false
+	}
+
+	score = 1 - float64(editDistance(expected, heard))/float64(len(expected))
+	if score < 0 {
+		score = 0
+	}
+
+	found := map[string]bool{}
+	for _, word := range heard {
+		found[word] = true
+	}
+	for _, word := range expected[max(len(expected)-codeWords, 0):] {
+		if !found[word] {
+			return score, false
+		}
+	}
+	return score, score >= MinScore
+}
+
+// Words lowercases text and splits it into words, dropping punctuation.
+// Apostrophes are dropped rather than split on, so "I'm" stays one word.
+func Words(text string) []string {
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\'' || r == '’':
+			return -1
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return unicode.ToLower(r)
+		default:
+			return ' '
+		}
+	}, text)
+	return strings.Fields(text)
+}
+
+// editDistance is the word-level Levenshtein distance between a and b
+func editDistance(a, b []string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,66 @@
+package consent
+
+import (
+	"strings"
+	"testing"
+)
+
+const phrase = Statement + " My code is amber falcon ribbon walnut."
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		transcript string
+		ok         bool
+	}{
+		{"exact", phrase, true},
+		{"case and punctuation", "i own this voice, and i agree to it being cloned! my code is: Amber. Falcon. Ribbon. Walnut", true},
+		{"extra spaces", "  I own this voice   and I agree to it being cloned   My code is amber falcon ribbon walnut  ", true},
+		{"one misheard word", "I own this voice and I agree to it being closed. My code is amber falcon ribbon walnut.", true},
+		{"three slips", "I own the voice and agree to it being clone. My code is amber falcon ribbon walnut.", true},
+		{"a code word misheard", "I own this voice and I agree to it being cloned. My code is amber falcon ribbon walrus.", false},
+		{"too many slips", "I own the voice and agree to it being clone. My code's amber falcon ribbon walnut.", false},
+		{"wrong phrase", "The quick brown fox jumps over the lazy dog.", false},
+		{"the statement without the code", Statement, false},
+		{"nothing heard", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			score, ok := Match(phrase, test.transcript)
+			if ok != test.ok {
+				t.Errorf("Match(%q) = %.2f, %v; want ok %v", test.transcript, score, ok, test.ok)
+			}
+			if score < 0 || score > 1 {
+				t.Errorf("score %.2f is outside 0 to 1", score)
+			}
+		})
+	}
+}
+
+func TestMatchScoresExactAsOne(t *testing.T) {
+	if score, _ := Match(phrase, phrase); score != 1 {
+		t.Errorf("score = %.2f, want 1", score)
+	}
+}
+
+func TestMatchEmptyPhrase(t *testing.T) {
+	if _, ok := Match("", "anything"); ok {
+		t.Error("an empty phrase matched")
+	}
+}
+
+func TestNewPhrase(t *testing.T) {
+	p, err := NewPhrase()
+	if err != nil {
+		t.Fatalf("NewPhrase: %v", err)
+	}
+	if !strings.HasPrefix(p, Statement+" My code is ") {
+		t.Errorf("phrase %q doesn't open with the statement", p)
+	}
+	if score, ok := Match(p, p); !ok || score != 1 {
+		t.Errorf("a phrase doesn't match itself: %.2f, %v", score, ok)
+	}
+	if code := Words(p)[len(Words(Statement))+3:]; len(code) != codeWords {
+		t.Errorf("code %v has %d words, want %d", code, len(code), codeWords)
+	}
+}
@@ -0,0 +1,79 @@
+package cookies
+
+import (
+	"fmt"
+	"time"
+)
+
+// Jar is the set of cookies used when downloading from one platform.
+type Jar struct {
+	Platform  string
+	Source    string
+	Cookies   []Cookie
+	UpdatedAt time.Time
+}
+
+// Status summarises a jar for health output. Warnings is empty when the jar is fine.
+type Status struct {
+	Platform       string     `json:"platform"`
+	Source         string     `json:"source,omitempty"`
+	Cookies        int        `json:"cookies"`
+	Expired        int        `json:"expired"`
+	ExpiringSoon   int        `json:"expiring_soon"`
+	EarliestExpiry *time.Time `json:"earliest_expiry,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+	Warnings       []string   `json:"warnings,omitempty"`
+}
+
+// Status reports expired cookies and cookies expiring within warnWithin of now.
+func (j *Jar) Status(now time.Time, warnWithin time.Duration) Status {
+	status := Status{
+		Platform: j.Platform,
+		Source:   j.Source,
+		Cookies:  len(j.Cookies),
+	}
+	if !j.UpdatedAt.IsZero() {
+		updatedAt := j.UpdatedAt
+		status.UpdatedAt = &updatedAt
+	}
+
+	for _, c := range j.Cookies {
+		if c.Expires.IsZero() {
+			continue
+		}
+		switch {
+		case !c.Expires.After(now):
+			status.Expired++
+		case c.Expires.Before(now.Add(warnWithin)):
+			status.ExpiringSoon++
+		}
+		if c.Expires.After(now) && (status.EarliestExpiry == nil || c.Expires.Before(*status.EarliestExpiry)) {
+			expires := c.Expires
+			status.EarliestExpiry = &expires
+		}
+	}
+
+	if status.Expired > 0 {
+		if status.Expired == status.Cookies {
+			status.Warnings = append(status.Warnings, "all cookies have expired")
+		} else {
+			status.Warnings = append(status.Warnings, fmt.Sprintf("%d of %d cookies have expired", status.Expired, status.Cookies))
+		}
+	}
+	if status.ExpiringSoon > 0 {
+		status.Warnings = append(status.Warnings, fmt.Sprintf("%d cookies expire before %s", status.ExpiringSoon, now.Add(warnWithin).Format(time.RFC3339)))
+	}
+
+	return status
+}
+
+// Valid drops expired cookies so they aren't sent to the platform.
+func (j *Jar) Valid(now time.Time) []Cookie {
+	valid := make([]Cookie, 0, len(j.Cookies))
+	for _, c := range j.Cookies {
+		if c.Expires.IsZero() || c.Expires.After(now) {
+			valid = append(valid, c)
+		}
+	}
+	return valid
+}
@@ -0,0 +1,170 @@
+package cookies
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const httpOnlyPrefix = "#HttpOnly_"
+
+// Cookie is one line of a Netscape cookie file.
+type Cookie struct {
+	Domain            string
+	IncludeSubdomains bool
+	Path              string
+	Secure            bool
+	HTTPOnly          bool
+	// Expires is zero for session cookies
+	Expires time.Time
+	Name    string
+	Value   string
+}
+
+// ParseError points at the offending line of a cookie file.
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Parse reads a Netscape cookie file. It also accepts the single-line form
+// produced by convert_cookies.py, where newlines are escaped as `\n` and the
+// whole blob may be wrapped in quotes.
+func Parse(data []byte) ([]Cookie, error) {
+	data = bytes.Trim(bytes.TrimSpace(data), `"`)
+	if !bytes.Contains(data, []byte("\n")) && bytes.Contains(data, []byte(`\n`)) {
+		data = bytes.ReplaceAll(data, []byte(`\n`), []byte("\n"))
+	}
+
+	var cookies []Cookie
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		httpOnly := false
+		if strings.HasPrefix(line, httpOnlyPrefix) {
+			httpOnly = true
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		} else if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		cookie, err := parseLine(line)
+		if err != nil {
+			return nil, &ParseError{Line: lineNo, Msg: err.Error()}
+		}
+		cookie.HTTPOnly = httpOnly
+		cookies = append(cookies, cookie)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading cookies: %w", err)
+	}
+
+	if len(cookies) == 0 {
+		return nil, fmt.Errorf("no cookies found")
+	}
+
+	return cookies, nil
+}
+
+func parseLine(line string) (Cookie, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != 7 {
+		return Cookie{}, fmt.Errorf("expected 7 tab-separated fields, got %d", len(fields))
+	}
+
+	domain := fields[0]
+	if domain == "" {
+		return Cookie{}, fmt.Errorf("empty domain")
+	}
+
+	includeSubdomains, err := parseBool(fields[1])
+	if err != nil {
+		return Cookie{}, fmt.Errorf("include subdomains: %v", err)
+	}
+
+	path := fields[2]
+	if !strings.HasPrefix(path, "/") {
+		return Cookie{}, fmt.Errorf("path %q must start with /", path)
+	}
+
+	secure, err := parseBool(fields[3])
+	if err != nil {
+		return Cookie{}, fmt.Errorf("secure: %v", err)
+	}
+
+	expiresUnix, err := strconv.ParseInt(fields[4], 10, 64)
+	if err != nil || expiresUnix < 0 {
+		return Cookie{}, fmt.Errorf("invalid expiry %q", fields[4])
+	}
+	var expires time.Time
+	if expiresUnix > 0 {
+		expires = time.Unix(expiresUnix, 0)
+	}
+
+	if fields[5] == "" {
+		return Cookie{}, fmt.Errorf("empty cookie name")
+	}
+
+	return Cookie{
+		Domain:            domain,
+		IncludeSubdomains: includeSubdomains,
+		Path:              path,
+		Secure:            secure,
+		Expires:           expires,
+		Name:              fields[5],
+		Value:             fields[6],
+	}, nil
+}
+
+func parseBool(s string) (bool, error) {
+	switch strings.ToUpper(s) {
+	case "TRUE":
+		return true, nil
+	case "FALSE":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected TRUE or FALSE, got %q", s)
+}
+
+// Format writes cookies back out in the Netscape format yt-dlp expects.
+func Format(cookies []Cookie) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# Netscape HTTP Cookie File\n")
+
+	for _, c := range cookies {
+		domain := c.Domain
+		if c.HTTPOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		var expires int64
+		if !c.Expires.IsZero() {
+			expires = c.Expires.Unix()
+		}
+		fmt.Fprintf(&buf, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, formatBool(c.IncludeSubdomains), c.Path, formatBool(c.Secure), expires, c.Name, c.Value)
+	}
+
+	return buf.Bytes()
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
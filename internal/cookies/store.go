@@ -0,0 +1,227 @@
+package cookies
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store keeps one cookie jar per platform. Jars are looked up, in order, from
+// a file uploaded through the admin endpoint (<Dir>/<platform>.txt), the
+// <PLATFORM>_COOKIES environment variable and an optional legacy file.
+type Store struct {
+	Dir        string
+	WarnWithin time.Duration
+	// Fallbacks maps a platform to a legacy cookie file path
+	Fallbacks map[string]string
+
+	mu   sync.RWMutex
+	jars map[string]*Jar
+}
+
+var (
+	defaultOnce  sync.Once
+	defaultStore *Store
+)
+
+func NewStore(dir string) *Store {
+	return &Store{
+		Dir:        dir,
+		WarnWithin: 7 * 24 * time.Hour,
+		Fallbacks:  map[string]string{},
+		jars:       map[string]*Jar{},
+	}
+}
+
+// Default returns the process-wide store rooted at COOKIES_DIR (./cookies by default).
+// The old cookies.txt in the working directory is still honoured for YouTube.
+func Default() *Store {
+	defaultOnce.Do(func() {
+		dir := os.Getenv("COOKIES_DIR")
+		if dir == "" {
+			dir = "./cookies"
+		}
+		defaultStore = NewStore(dir)
+		defaultStore.Fallbacks["youtube"] = "cookies.txt"
+	})
+	return defaultStore
+}
+
+// Jar returns the jar for platform, or nil if none is configured.
+func (s *Store) Jar(platform string) (*Jar, error) {
+	s.mu.RLock()
+	jar, ok := s.jars[platform]
+	s.mu.RUnlock()
+	if ok {
+		return jar, nil
+	}
+
+	jar, err := s.load(platform)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.jars[platform] = jar
+	s.mu.Unlock()
+
+	return jar, nil
+}
+
+func (s *Store) load(platform string) (*Jar, error) {
+	path := s.path(platform)
+	if data, err := os.ReadFile(path); err == nil {
+		return s.parseJar(platform, "upload", data, modTime(path))
+	}
+
+	envName := strings.ToUpper(platform) + "_COOKIES"
+	if data := os.Getenv(envName); data != "" {
+		return s.parseJar(platform, "env:"+envName, []byte(data), time.Time{})
+	}
+
+	if fallback := s.Fallbacks[platform]; fallback != "" {
+		if data, err := os.ReadFile(fallback); err == nil {
+			return s.parseJar(platform, "file:"+fallback, data, modTime(fallback))
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *Store) parseJar(platform, source string, data []byte, updatedAt time.Time) (*Jar, error) {
+	cookies, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s cookies from %s: %w", platform, source, err)
+	}
+
+	jar := &Jar{
+		Platform:  platform,
+		Source:    source,
+		Cookies:   cookies,
+		UpdatedAt: updatedAt,
+	}
+
+	for _, warning := range jar.Status(time.Now(), s.WarnWithin).Warnings {
+		log.Printf("Cookies for %s (%s): %s", platform, source, warning)
+	}
+
+	return jar, nil
+}
+
+// Set validates data and stores it as the jar for platform, replacing any previous one.
+func (s *Store) Set(platform string, data []byte) (Status, error) {
+	jar, err := s.parseJar(platform, "upload", data, time.Now())
+	if err != nil {
+		return Status{}, err
+	}
+
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return Status{}, fmt.Errorf("failed to create cookies directory: %w", err)
+	}
+
+	// Write to a temp file first so a concurrent download never sees half a jar
+	tmpFile, err := os.CreateTemp(s.Dir, platform+"_*.tmp")
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to create cookies file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(Format(jar.Cookies)); err != nil {
+		tmpFile.Close()
+		return Status{}, fmt.Errorf("failed to write cookies file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return Status{}, fmt.Errorf("failed to write cookies file: %w", err)
+	}
+	if err := os.Rename(tmpFile.Name(), s.path(platform)); err != nil {
+		return Status{}, fmt.Errorf("failed to replace cookies file: %w", err)
+	}
+
+	s.mu.Lock()
+	s.jars[platform] = jar
+	s.mu.Unlock()
+
+	log.Printf("Cookies for %s rotated: %d cookies", platform, len(jar.Cookies))
+	return jar.Status(time.Now(), s.WarnWithin), nil
+}
+
+// Delete removes an uploaded jar. The environment or legacy file is used again afterwards.
+func (s *Store) Delete(platform string) error {
+	if err := os.Remove(s.path(platform)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove cookies file: %w", err)
+	}
+
+	s.mu.Lock()
+	delete(s.jars, platform)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// WriteTempFile writes the unexpired cookies for platform to a temporary file
+// for yt-dlp, which rewrites the file it is given. It returns "" if the
+// platform has no cookies. The caller must remove the file.
+func (s *Store) WriteTempFile(platform string) (string, error) {
+	jar, err := s.Jar(platform)
+	if err != nil {
+		return "", err
+	}
+	if jar == nil {
+		return "", nil
+	}
+
+	valid := jar.Valid(time.Now())
+	if len(valid) == 0 {
+		log.Printf("All %s cookies have expired - proceeding without authentication", platform)
+		return "", nil
+	}
+
+	tmpFile, err := os.CreateTemp("", "cookies_*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary cookies file: %w", err)
+	}
+	defer tmpFile.Close()
+
+	if _, err := tmpFile.Write(Format(valid)); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("failed to write cookies to temporary file: %w", err)
+	}
+
+	return tmpFile.Name(), nil
+}
+
+// Statuses reports the state of the jar of every given platform.
+func (s *Store) Statuses(platforms []string) []Status {
+	now := time.Now()
+	statuses := make([]Status, 0, len(platforms))
+
+	for _, platform := range platforms {
+		jar, err := s.Jar(platform)
+		switch {
+		case err != nil:
+			statuses = append(statuses, Status{Platform: platform, Warnings: []string{err.Error()}})
+		case jar == nil:
+			statuses = append(statuses, Status{Platform: platform})
+		default:
+			statuses = append(statuses, jar.Status(now, s.WarnWithin))
+		}
+	}
+
+	return statuses
+}
+
+func (s *Store) path(platform string) string {
+	return filepath.Join(s.Dir, platform+".txt")
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
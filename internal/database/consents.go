@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Consent statuses. A consent starts pending and is completed exactly once.
+const (
+	ConsentPending  = "pending"
+	ConsentVerified = "verified"
+	ConsentRejected = "rejected"
+)
+
+// Consent is one attempt by a voice's owner to agree to it being cloned by
+// reading Phrase aloud.
+type Consent struct {
+	ID        string `json:"id"`
+	ProfileID string `json:"profile_id"`
+	Phrase    string `json:"phrase"`
+	Status    string `json:"status"`
+	// Transcript is what was heard in the recording, and Score how well it
+	// matched Phrase from 0 to 1
+	Transcript    string     `json:"transcript,omitempty"`
+	Score         float64    `json:"score"`
+	RecordingPath string     `json:"-"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+}
+
+// Expired reports whether the phrase can no longer be recorded.
+func (c *Consent) Expired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+const consentColumns = `id, profile_id, phrase, status, transcript, score, recording_path, expires_at, created_at, completed_at`
+
+// CreateConsent inserts a pending consent, filling in its creation time.
+func (s *service) CreateConsent(ctx context.Context, c *Consent) error {
+	c.Status = ConsentPending
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO consents (id, profile_id, phrase, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`,
+		c.ID, c.ProfileID, c.Phrase, c.Status, c.ExpiresAt,
+	).Scan(&c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create consent: %w", err)
+	}
+	return nil
+}
+
+// GetConsent returns ErrNotFound if there is no consent with this ID.
+func (s *service) GetConsent(ctx context.Context, id string) (*Consent, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+consentColumns+` FROM consents WHERE id = $1`, id)
+	c, err := scanConsent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consent: %w", err)
+	}
+	return c, nil
+}
+
+// ListConsents returns every attempt for a profile, newest first.
+func (s *service) ListConsents(ctx context.Context, profileID string) ([]Consent, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+consentColumns+` FROM consents WHERE profile_id = $1 ORDER BY created_at DESC`, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consents: %w", err)
+	}
+	defer rows.Close()
+
+	consents := []Consent{}
+	for rows.Next() {
+		c, err := scanConsent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list consents: %w", err)
+		}
+		consents = append(consents, *c)
+	}
+	return consents, rows.Err()
+}
+
+// CompleteConsent records the outcome of a pending consent. In the same
+// transaction a verified consent becomes its profile's ConsentID and its
+// recording the profile's reference, dropping what was made from the old one
+// (the preview and provider voices). Returns ErrNotFound if the consent
+// doesn't exist or was already completed.
+func (s *service) CompleteConsent(ctx context.Context, c *Consent) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to complete consent: %w", err)
+	}
+	defer tx.Rollback()
+
+	var completedAt time.Time
+	err = tx.QueryRowContext(ctx,
+		`UPDATE consents SET status = $2, transcript = $3, score = $4, recording_path = $5, completed_at = now()
+		WHERE id = $1 AND status = $6
+		RETURNING completed_at`,
+		c.ID, c.Status, c.Transcript, c.Score, c.RecordingPath, ConsentPending,
+	).Scan(&completedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to complete consent: %w", err)
+	}
+
+	if c.Status == ConsentVerified {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE voice_profiles SET consent_id = $2, source_type = 'microphone', source = '',
+				reference_path = $3, reference_text = $4, sample_path = '', provider_voice_ids = '{}', updated_at = now()
+			WHERE id = $1`,
+			c.ProfileID, c.ID, c.RecordingPath, c.Transcript)
+		if err != nil {
+			return fmt.Errorf("failed to link consent to voice profile: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to complete consent: %w", err)
+	}
+	c.CompletedAt = &completedAt
+	return nil
+}
+
+func scanConsent(row scanner) (*Consent, error) {
+	var c Consent
+	var completedAt sql.NullTime
+	err := row.Scan(&c.ID, &c.ProfileID, &c.Phrase, &c.Status, &c.Transcript, &c.Score, &c.RecordingPath,
+		&c.ExpiresAt, &c.CreatedAt, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		c.CompletedAt = &completedAt.Time
+	}
+	return &c, nil
+}
@@ -22,6 +22,37 @@ type Service interface {
 	// Close terminates the database connection.
 	// It returns an error if the connection cannot be closed.
 	Close() error
+
+	// Migrate creates any tables that don't exist yet.
+	Migrate(ctx context.Context) error
+
+	CreateVoiceProfile(ctx context.Context, p *VoiceProfile) error
+	GetVoiceProfile(ctx context.Context, id string) (*VoiceProfile, error)
+	ListVoiceProfiles(ctx context.Context) ([]VoiceProfile, error)
+	RenameVoiceProfile(ctx context.Context, id, name string) error
+	SetProviderVoiceID(ctx context.Context, id, provider, voiceID string) error
+	SetReferenceText(ctx context.Context, id, text string) error
+	SetVoiceProfileSample(ctx context.Context, id, samplePath string) error
+	DeleteVoiceProfile(ctx context.Context, id string) error
+
+	CreateConsent(ctx context.Context, c *Consent) error
+	GetConsent(ctx context.Context, id string) (*Consent, error)
+	ListConsents(ctx context.Context, profileID string) ([]Consent, error)
+	CompleteConsent(ctx context.Context, c *Consent) error
+
+	CreateGeneration(ctx context.Context, g *Generation) error
+	GetGeneration(ctx context.Context, id string) (*Generation, error)
+	ListGenerations(ctx context.Context, limit, offset int) ([]Generation, error)
+	FindGenerationsByPrefix(ctx context.Context, prefix string) ([]Generation, error)
+	UpdateGeneration(ctx context.Context, g *Generation) error
+	CountGenerationsWithParam(ctx context.Context, key, value string) (int, error)
+	DeleteGeneration(ctx context.Context, id string) error
+
+	CreateShare(ctx context.Context, share *Share) error
+	GetShare(ctx context.Context, token string) (*Share, error)
+	RecordShareView(ctx context.Context, token string) (*Share, error)
+	ListShares(ctx context.Context, generationID string) ([]Share, error)
+	DeleteShare(ctx context.Context, token string) error
 }
 
 type service struct {
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Generation is one piece of generated speech kept in the history.
+type Generation struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	// SourceType is the input mode the voice came from: url, file, microphone or profile
+	SourceType string `json:"source_type"`
+	// Source identifies the voice within its type: the video URL, upload,
+	// recording or profile ID
+	Source   string `json:"source,omitempty"`
+	Provider string `json:"provider"`
+	// Params holds whatever the provider needs to repeat the generation,
+	// e.g. the uploaded reference URL for zonos
+	Params    map[string]string `json:"params"`
+	AudioPath string            `json:"-"`
+	// Duration is the length of the generated audio in seconds, or 0 if unknown
+	Duration  float64   `json:"duration"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const generationColumns = `id, text, source_type, source, provider, params, audio_path, duration_seconds, created_at`
+
+// CreateGeneration inserts g, filling in its creation time.
+func (s *service) CreateGeneration(ctx context.Context, g *Generation) error {
+	if g.Params == nil {
+		g.Params = map[string]string{}
+	}
+	params, err := json.Marshal(g.Params)
+	if err != nil {
+		return fmt.Errorf("failed to encode generation params: %w", err)
+	}
+
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO generations (id, text, source_type, source, provider, params, audio_path, duration_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at`,
+		g.ID, g.Text, g.SourceType, g.Source, g.Provider, params, g.AudioPath, g.Duration,
+	).Scan(&g.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create generation: %w", err)
+	}
+	return nil
+}
+
+// GetGeneration returns ErrNotFound if there is no generation with this ID.
+func (s *service) GetGeneration(ctx context.Context, id string) (*Generation, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+generationColumns+` FROM generations WHERE id = $1`, id)
+	g, err := scanGeneration(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get generation: %w", err)
+	}
+	return g, nil
+}
+
+// ListGenerations returns a page of the history, newest first.
+func (s *service) ListGenerations(ctx context.Context, limit, offset int) ([]Generation, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+generationColumns+` FROM generations ORDER BY created_at DESC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list generations: %w", err)
+	}
+	defer rows.Close()
+
+	generations := []Generation{}
+	for rows.Next() {
+		g, err := scanGeneration(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list generations: %w", err)
+		}
+		generations = append(generations, *g)
+	}
+	return generations, rows.Err()
+}
+
+// FindGenerationsByPrefix returns the generations whose ID starts with
+// prefix, the first group of a UUID as carried by audio watermarks.
+func (s *service) FindGenerationsByPrefix(ctx context.Context, prefix string) ([]Generation, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+generationColumns+` FROM generations WHERE id BETWEEN $1 AND $2 ORDER BY created_at DESC`,
+		prefix+"-0000-0000-0000-000000000000", prefix+"-ffff-ffff-ffff-ffffffffffff")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find generations: %w", err)
+	}
+	defer rows.Close()
+
+	generations := []Generation{}
+	for rows.Next() {
+		g, err := scanGeneration(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find generations: %w", err)
+		}
+		generations = append(generations, *g)
+	}
+	return generations, rows.Err()
+}
+
+// UpdateGeneration saves g's params and duration, e.g. after its audio was
+// re-rendered.
+func (s *service) UpdateGeneration(ctx context.Context, g *Generation) error {
+	params, err := json.Marshal(g.Params)
+	if err != nil {
+		return fmt.Errorf("failed to encode generation params: %w", err)
+	}
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE generations SET params = $2, duration_seconds = $3 WHERE id = $1`, g.ID, params, g.Duration)
+	if err != nil {
+		return fmt.Errorf("failed to update generation: %w", err)
+	}
+	return expectOneRow(result)
+}
+
+// CountGenerationsWithParam counts the generations whose Params[key] is
+// value, e.g. those still mixed over an uploaded music file.
+func (s *service) CountGenerationsWithParam(ctx context.Context, key, value string) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM generations WHERE params ->> $1 = $2`, key, value).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count generations: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteGeneration removes the row; the caller cleans up its audio file.
+func (s *service) DeleteGeneration(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM generations WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete generation: %w", err)
+	}
+	return expectOneRow(result)
+}
+
+func scanGeneration(row scanner) (*Generation, error) {
+	var g Generation
+	var params []byte
+	err := row.Scan(&g.ID, &g.Text, &g.SourceType, &g.Source, &g.Provider, &params, &g.AudioPath, &g.Duration, &g.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(params, &g.Params); err != nil {
+		return nil, fmt.Errorf("failed to decode generation params: %w", err)
+	}
+	return &g, nil
+}
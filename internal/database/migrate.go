@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrations are applied in order by Migrate. Every statement must be safe to run
+// again against an existing database.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS voice_profiles (
+		id UUID PRIMARY KEY,
+		name TEXT NOT NULL,
+		source_type TEXT NOT NULL,
+		source TEXT NOT NULL DEFAULT '',
+		reference_path TEXT NOT NULL,
+		sample_path TEXT NOT NULL DEFAULT '',
+		provider_voice_ids JSONB NOT NULL DEFAULT '{}',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE TABLE IF NOT EXISTS generations (
+		id UUID PRIMARY KEY,
+		text TEXT NOT NULL,
+		source_type TEXT NOT NULL,
+		source TEXT NOT NULL DEFAULT '',
+		provider TEXT NOT NULL,
+		params JSONB NOT NULL DEFAULT '{}',
+		audio_path TEXT NOT NULL,
+		duration_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE INDEX IF NOT EXISTS generations_created_at_idx ON generations (created_at DESC)`,
+	`CREATE TABLE IF NOT EXISTS shares (
+		token TEXT PRIMARY KEY,
+		generation_id UUID NOT NULL REFERENCES generations (id) ON DELETE CASCADE,
+		allow_download BOOLEAN NOT NULL DEFAULT false,
+		views INTEGER NOT NULL DEFAULT 0,
+		expires_at TIMESTAMPTZ NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`ALTER TABLE voice_profiles ADD COLUMN IF NOT EXISTS consent_id UUID`,
+	`CREATE TABLE IF NOT EXISTS consents (
+		id UUID PRIMARY KEY,
+		profile_id UUID NOT NULL REFERENCES voice_profiles (id) ON DELETE CASCADE,
+		phrase TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		transcript TEXT NOT NULL DEFAULT '',
+		score DOUBLE PRECISION NOT NULL DEFAULT 0,
+		recording_path TEXT NOT NULL DEFAULT '',
+		expires_at TIMESTAMPTZ NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		completed_at TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS consents_profile_id_idx ON consents (profile_id, created_at DESC)`,
+	`ALTER TABLE voice_profiles ADD COLUMN IF NOT EXISTS reference_text TEXT NOT NULL DEFAULT ''`,
+}
+
+// Migrate creates any tables that don't exist yet.
+func (s *service) Migrate(ctx context.Context) error {
+	for _, statement := range migrations {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to apply schema: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Share is a public link to one generation.
+type Share struct {
+	Token         string    `json:"token"`
+	GenerationID  string    `json:"generation_id"`
+	AllowDownload bool      `json:"allow_download"`
+	Views         int       `json:"views"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Expired reports whether the link has stopped working.
+func (s *Share) Expired() bool {
+	return !time.Now().Before(s.ExpiresAt)
+}
+
+const shareColumns = `token, generation_id, allow_download, views, expires_at, created_at`
+
+// CreateShare inserts share, filling in its creation time.
+func (s *service) CreateShare(ctx context.Context, share *Share) error {
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO shares (token, generation_id, allow_download, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at`,
+		share.Token, share.GenerationID, share.AllowDownload, share.ExpiresAt,
+	).Scan(&share.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create share: %w", err)
+	}
+	return nil
+}
+
+// GetShare returns ErrNotFound if there is no link with this token. Expired
+// links are returned too, so callers can tell them apart.
+func (s *service) GetShare(ctx context.Context, token string) (*Share, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+shareColumns+` FROM shares WHERE token = $1`, token)
+	share, err := scanShare(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share: %w", err)
+	}
+	return share, nil
+}
+
+// RecordShareView counts a visit to a link that hasn't expired and returns
+// the updated link. Expired and unknown links return ErrNotFound.
+func (s *service) RecordShareView(ctx context.Context, token string) (*Share, error) {
+	row := s.db.QueryRowContext(ctx,
+		`UPDATE shares SET views = views + 1 WHERE token = $1 AND expires_at > now() RETURNING `+shareColumns, token)
+	share, err := scanShare(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record share view: %w", err)
+	}
+	return share, nil
+}
+
+// ListShares returns every link to a generation, newest first.
+func (s *service) ListShares(ctx context.Context, generationID string) ([]Share, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+shareColumns+` FROM shares WHERE generation_id = $1 ORDER BY created_at DESC`, generationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shares: %w", err)
+	}
+	defer rows.Close()
+
+	shares := []Share{}
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list shares: %w", err)
+		}
+		shares = append(shares, *share)
+	}
+	return shares, rows.Err()
+}
+
+// DeleteShare revokes a link.
+func (s *service) DeleteShare(ctx context.Context, token string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM shares WHERE token = $1`, token)
+	if err != nil {
+		return fmt.Errorf("failed to delete share: %w", err)
+	}
+	return expectOneRow(result)
+}
+
+func scanShare(row scanner) (*Share, error) {
+	var share Share
+	err := row.Scan(&share.Token, &share.GenerationID, &share.AllowDownload, &share.Views, &share.ExpiresAt, &share.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// VoiceProfile is a saved reference voice that can be generated from without
+// processing its source again.
+type VoiceProfile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// SourceType is the input mode the reference came from: url, file or microphone
+	SourceType string `json:"source_type"`
+	// Source is the video URL for url profiles and empty otherwise
+	Source        string `json:"source,omitempty"`
+	ReferencePath string `json:"-"`
+	// ReferenceText is what is said in the uploaded reference, if it was transcribed
+	ReferenceText string `json:"reference_text,omitempty"`
+	SamplePath    string `json:"-"`
+	// ProviderVoiceIDs maps a provider name to whatever it uses to find this
+	// voice again, e.g. the uploaded reference URL for zonos
+	ProviderVoiceIDs map[string]string `json:"provider_voice_ids"`
+	// ConsentID is the verified consent recording of the voice's owner, if any
+	ConsentID string    `json:"consent_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const voiceProfileColumns = `id, name, source_type, source, reference_path, reference_text, sample_path, provider_voice_ids,
+	coalesce(consent_id::text, ''), created_at, updated_at`
+
+// CreateVoiceProfile inserts p, filling in its timestamps.
+func (s *service) CreateVoiceProfile(ctx context.Context, p *VoiceProfile) error {
+	if p.ProviderVoiceIDs == nil {
+		p.ProviderVoiceIDs = map[string]string{}
+	}
+	ids, err := json.Marshal(p.ProviderVoiceIDs)
+	if err != nil {
+		return fmt.Errorf("failed to encode provider voice IDs: %w", err)
+	}
+
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO voice_profiles (id, name, source_type, source, reference_path, sample_path, provider_voice_ids)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, updated_at`,
+		p.ID, p.Name, p.SourceType, p.Source, p.ReferencePath, p.SamplePath, ids,
+	).Scan(&p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create voice profile: %w", err)
+	}
+	return nil
+}
+
+// GetVoiceProfile returns ErrNotFound if there is no profile with this ID.
+func (s *service) GetVoiceProfile(ctx context.Context, id string) (*VoiceProfile, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+voiceProfileColumns+` FROM voice_profiles WHERE id = $1`, id)
+	p, err := scanVoiceProfile(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get voice profile: %w", err)
+	}
+	return p, nil
+}
+
+// ListVoiceProfiles returns every profile, newest first.
+func (s *service) ListVoiceProfiles(ctx context.Context) ([]VoiceProfile, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+voiceProfileColumns+` FROM voice_profiles ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list voice profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := []VoiceProfile{}
+	for rows.Next() {
+		p, err := scanVoiceProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list voice profiles: %w", err)
+		}
+		profiles = append(profiles, *p)
+	}
+	return profiles, rows.Err()
+}
+
+// RenameVoiceProfile changes a profile's display name.
+func (s *service) RenameVoiceProfile(ctx context.Context, id, name string) error {
+	return s.updateVoiceProfile(ctx, id, `name = $2`, name)
+}
+
+// SetProviderVoiceID records the ID a provider knows this voice by.
+func (s *service) SetProviderVoiceID(ctx context.Context, id, provider, voiceID string) error {
+	return s.updateVoiceProfile(ctx, id,
+		`provider_voice_ids = provider_voice_ids || jsonb_build_object($2::text, $3::text)`, provider, voiceID)
+}
+
+// SetReferenceText records the transcript of the profile's uploaded reference.
+func (s *service) SetReferenceText(ctx context.Context, id, text string) error {
+	return s.updateVoiceProfile(ctx, id, `reference_text = $2`, text)
+}
+
+// SetVoiceProfileSample records where the profile's preview clip is stored.
+func (s *service) SetVoiceProfileSample(ctx context.Context, id, samplePath string) error {
+	return s.updateVoiceProfile(ctx, id, `sample_path = $2`, samplePath)
+}
+
+// DeleteVoiceProfile removes the row; the caller cleans up its files.
+func (s *service) DeleteVoiceProfile(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM voice_profiles WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete voice profile: %w", err)
+	}
+	return expectOneRow(result)
+}
+
+func (s *service) updateVoiceProfile(ctx context.Context, id, set string, args ...interface{}) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE voice_profiles SET `+set+`, updated_at = now() WHERE id = $1`,
+		append([]interface{}{id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update voice profile: %w", err)
+	}
+	return expectOneRow(result)
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVoiceProfile(row scanner) (*VoiceProfile, error) {
+	var p VoiceProfile
+	var ids []byte
+	err := row.Scan(&p.ID, &p.Name, &p.SourceType, &p.Source, &p.ReferencePath, &p.ReferenceText, &p.SamplePath, &ids, &p.ConsentID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(ids, &p.ProviderVoiceIDs); err != nil {
+		return nil, fmt.Errorf("failed to decode provider voice IDs: %w", err)
+	}
+	return &p, nil
+}
+
+func expectOneRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Package dsp holds signal processing shared by the packages that analyse
+// audio in Go.
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// FFT transforms x in place. len(x) must be a power of two.
+func FFT(x []complex128) {
+	transform(x, -1)
+}
+
+// IFFT inverts FFT in place, scaling by 1/len(x). len(x) must be a power of
+// two.
+func IFFT(x []complex128) {
+	transform(x, 1)
+	scale := complex(1/float64(len(x)), 0)
+	for i := range x {
+		x[i] *= scale
+	}
+}
+
+// transform is the radix-2 FFT with the given sign of the exponent
+func transform(x []complex128, sign float64) {
+	n := len(x)
+
+	// Bit-reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], w*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+}
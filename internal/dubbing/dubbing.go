@@ -0,0 +1,317 @@
+// Package dubbing re-voices a video in another language: it transcribes the
+// original speech with timestamps, translates each segment, speaks the
+// translations in a cloned voice, fits every clip into the slot its original
+// occupied and muxes the new track back into the video.
+//
+// Every provider sits behind an interface (stt.Transcriber, Translator,
+// Synthesizer and Media), and each has a fake, so a whole run works offline
+// and without ffmpeg.
+package dubbing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/stt"
+)
+
+// DefaultMaxTempo is how much faster than its natural pace a clip may be
+// played to fit its slot before the rest is cut off.
+const DefaultMaxTempo = 1.5
+
+// fadeOut is the length of the fade applied where a clip is cut off, in seconds
+const fadeOut = 0.05
+
+// Stages reported through Pipeline.Progress, in order.
+const (
+	StageExtracting   = "extracting"
+	StageTranscribing = "transcribing"
+	StageTranslating  = "translating"
+	StageSynthesizing = "synthesizing"
+	StageMixing       = "mixing"
+)
+
+// ErrNoSpeech is returned when the transcript has nothing to dub.
+var ErrNoSpeech = errors.New("no speech found in the video")
+
+// Pipeline holds the providers a dub goes through.
+type Pipeline struct {
+	Transcriber stt.Transcriber
+	Translator  Translator
+	Media       Media
+
+	// MaxTempo caps the speed-up applied to fit a clip into its slot
+	MaxTempo float64
+
+	// Progress, when set, is told about every stage, and about every
+	// segment while synthesizing
+	Progress func(Progress)
+}
+
+// NewPipeline sets up a pipeline with the configured transcriber and
+// translator (see stt.Default and DefaultTranslator) and ffmpeg.
+func NewPipeline() (*Pipeline, error) {
+	transcriber, err := stt.Default()
+	if err != nil {
+		return nil, err
+	}
+	translator, err := DefaultTranslator()
+	if err != nil {
+		return nil, err
+	}
+	return &Pipeline{
+		Transcriber: transcriber,
+		Translator:  translator,
+		Media:       FFmpeg{},
+		MaxTempo:    DefaultMaxTempo,
+	}, nil
+}
+
+// Progress says how far a run has got.
+type Progress struct {
+	Stage string `json:"stage"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+}
+
+// Job is one video to dub.
+type Job struct {
+	VideoPath  string
+	OutputPath string
+	// WorkDir holds the intermediate files; the caller removes it
+	WorkDir string
+
+	// SourceLanguage may be empty to use the language the transcriber detected
+	SourceLanguage string
+	TargetLanguage string
+
+	// Voice speaks the translations
+	Voice Synthesizer
+
+	// TrackPath is where the new track is kept as WAV, in WorkDir if empty
+	TrackPath string
+	// SaveTrack, when set, writes the new track instead of a plain file
+	// write, e.g. to watermark it before it goes into the video
+	SaveTrack func(ctx context.Context, path string, data []byte) error
+}
+
+// Segment is one dubbed stretch of speech, with times in seconds.
+type Segment struct {
+	Start       float64 `json:"start"`
+	End         float64 `json:"end"`
+	Text        string  `json:"text"`
+	Translation string  `json:"translation"`
+	// Tempo is the speed-up applied to the spoken translation
+	Tempo float64 `json:"tempo"`
+	// Cut is how much of the sped up clip did not fit its slot
+	Cut float64 `json:"cut,omitempty"`
+}
+
+// Result describes a finished dub.
+type Result struct {
+	OutputPath     string    `json:"output_path"`
+	TrackPath      string    `json:"track_path,omitempty"`
+	SourceLanguage string    `json:"source_language,omitempty"`
+	TargetLanguage string    `json:"target_language"`
+	Duration       float64   `json:"duration"`
+	Segments       []Segment `json:"segments"`
+}
+
+// Run dubs job.VideoPath and writes the result to job.OutputPath.
+func (p *Pipeline) Run(ctx context.Context, job Job) (*Result, error) {
+	if job.TargetLanguage == "" {
+		return nil, fmt.Errorf("no target language")
+	}
+
+	p.report(Progress{Stage: StageExtracting})
+	sourcePath := filepath.Join(job.WorkDir, "source"+audio.CanonicalExt)
+	if err := p.Media.ExtractAudio(ctx, job.VideoPath, sourcePath); err != nil {
+		return nil, fmt.Errorf("failed to extract audio: %w", err)
+	}
+	source, err := p.Media.Samples(ctx, sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode audio: %w", err)
+	}
+	duration := float64(len(source)) / SampleRate
+
+	p.report(Progress{Stage: StageTranscribing})
+	transcript, err := p.Transcriber.Transcribe(ctx, sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transcribe: %w", err)
+	}
+	segments := segmentsFrom(transcript, duration)
+	if len(segments) == 0 {
+		return nil, ErrNoSpeech
+	}
+
+	sourceLanguage := job.SourceLanguage
+	if sourceLanguage == "" {
+		sourceLanguage = transcript.Language
+	}
+
+	p.report(Progress{Stage: StageTranslating, Total: len(segments)})
+	texts := make([]string, len(segments))
+	for i, segment := range segments {
+		texts[i] = segment.Text
+	}
+	translations, err := p.Translator.Translate(ctx, texts, sourceLanguage, job.TargetLanguage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to translate: %w", err)
+	}
+	if len(translations) != len(segments) {
+		return nil, fmt.Errorf("translator returned %d texts for %d segments", len(translations), len(segments))
+	}
+
+	track := make([]float32, len(source))
+	for i := range segments {
+		p.report(Progress{Stage: StageSynthesizing, Done: i, Total: len(segments)})
+		segments[i].Translation = strings.TrimSpace(translations[i])
+		if segments[i].Translation == "" {
+			continue
+		}
+
+		// A clip may run on into the pause after its segment, up to the next one
+		slotEnd := duration
+		if i+1 < len(segments) {
+			slotEnd = segments[i+1].Start
+		}
+		if err := p.dubSegment(ctx, job, i, &segments[i], track, slotEnd); err != nil {
+			return nil, fmt.Errorf("segment %d: %w", i+1, err)
+		}
+	}
+
+	p.report(Progress{Stage: StageMixing, Done: len(segments), Total: len(segments)})
+	trackPath := job.TrackPath
+	if trackPath == "" {
+		trackPath = filepath.Join(job.WorkDir, "dub"+audio.CanonicalExt)
+	}
+	saveTrack := job.SaveTrack
+	if saveTrack == nil {
+		saveTrack = func(ctx context.Context, path string, data []byte) error {
+			return os.WriteFile(path, data, 0644)
+		}
+	}
+	if err := saveTrack(ctx, trackPath, audio.EncodeWAV(track, SampleRate)); err != nil {
+		return nil, fmt.Errorf("failed to write the new track: %w", err)
+	}
+	if err := p.Media.Mux(ctx, job.VideoPath, trackPath, job.OutputPath, job.TargetLanguage); err != nil {
+		return nil, fmt.Errorf("failed to mux: %w", err)
+	}
+
+	return &Result{
+		OutputPath:     job.OutputPath,
+		TrackPath:      trackPath,
+		SourceLanguage: sourceLanguage,
+		TargetLanguage: job.TargetLanguage,
+		Duration:       duration,
+		Segments:       segments,
+	}, nil
+}
+
+// dubSegment speaks segment's translation, fits it between its start and
+// slotEnd and writes it into track.
+func (p *Pipeline) dubSegment(ctx context.Context, job Job, index int, segment *Segment, track []float32, slotEnd float64) error {
+	speech, err := job.Voice.Synthesize(ctx, segment.Translation)
+	if err != nil {
+		return fmt.Errorf("failed to synthesize: %w", err)
+	}
+	speechPath := filepath.Join(job.WorkDir, fmt.Sprintf("segment-%03d", index))
+	if err := os.WriteFile(speechPath, speech, 0644); err != nil {
+		return fmt.Errorf("failed to save speech: %w", err)
+	}
+	clip, err := p.Media.Samples(ctx, speechPath)
+	if err != nil {
+		return fmt.Errorf("failed to decode speech: %w", err)
+	}
+
+	slot := slotEnd - segment.Start
+	natural := float64(len(clip)) / SampleRate
+	segment.Tempo = fitTempo(natural, slot, p.maxTempo())
+	if segment.Tempo != 1 {
+		fittedPath := speechPath + ".fitted" + audio.CanonicalExt
+		if err := p.Media.ChangeTempo(ctx, speechPath, fittedPath, segment.Tempo); err != nil {
+			return fmt.Errorf("failed to fit speech: %w", err)
+		}
+		if clip, err = p.Media.Samples(ctx, fittedPath); err != nil {
+			return fmt.Errorf("failed to decode fitted speech: %w", err)
+		}
+	}
+
+	start := int(segment.Start * SampleRate)
+	end := min(int(slotEnd*SampleRate), len(track))
+	if start >= end {
+		return nil
+	}
+	if len(clip) > end-start {
+		segment.Cut = float64(len(clip)-(end-start)) / SampleRate
+		clip = clip[:end-start]
+		fade(clip)
+	}
+	for i, s := range clip {
+		track[start+i] += s
+	}
+	return nil
+}
+
+// fitTempo is the speed-up that makes natural seconds of speech fit in slot
+// seconds, never slowing down and never above maxTempo.
+func fitTempo(natural, slot, maxTempo float64) float64 {
+	if slot <= 0 || natural <= slot {
+		return 1
+	}
+	return min(natural/slot, maxTempo)
+}
+
+// fade ramps the end of a clip that was cut off down to silence
+func fade(clip []float32) {
+	n := min(int(fadeOut*SampleRate), len(clip))
+	for i := 0; i < n; i++ {
+		clip[len(clip)-1-i] *= float32(i) / float32(n)
+	}
+}
+
+// segmentsFrom turns a transcript into the segments to dub, sorted and with
+// empty ones dropped. Without timestamps the whole text becomes one segment
+// spanning the video.
+func segmentsFrom(transcript *stt.Transcript, duration float64) []Segment {
+	if len(transcript.Segments) == 0 {
+		text := strings.TrimSpace(transcript.Text)
+		if text == "" {
+			return nil
+		}
+		log.Printf("Dubbing: transcript has no timestamps, dubbing it as one segment")
+		return []Segment{{Start: 0, End: duration, Text: text}}
+	}
+
+	segments := []Segment{}
+	for _, s := range transcript.Segments {
+		text := strings.TrimSpace(s.Text)
+		if text == "" || s.Start >= duration {
+			continue
+		}
+		segments = append(segments, Segment{Start: max(s.Start, 0), End: min(max(s.End, s.Start), duration), Text: text})
+	}
+	// Providers report segments in order, but a stray one would break the slots
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].Start < segments[j].Start })
+	return segments
+}
+
+func (p *Pipeline) maxTempo() float64 {
+	if p.MaxTempo > 0 {
+		return p.MaxTempo
+	}
+	return DefaultMaxTempo
+}
+
+func (p *Pipeline) report(progress Progress) {
+	if p.Progress != nil {
+		p.Progress(progress)
+	}
+}
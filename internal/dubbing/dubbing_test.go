@@ -0,0 +1,223 @@
+package dubbing
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/stt"
+)
+
+// recordingMedia is WAVMedia that remembers how it was asked to mux
+type recordingMedia struct {
+	WAVMedia
+	muxes [][]string
+}
+
+func (m *recordingMedia) Mux(ctx context.Context, videoPath, audioPath, outputPath, language string) error {
+	m.muxes = append(m.muxes, []string{videoPath, audioPath, outputPath, language})
+	return m.WAVMedia.Mux(ctx, videoPath, audioPath, outputPath, language)
+}
+
+// testJob writes a silent "video" of seconds to a fresh work directory
+func testJob(t *testing.T, seconds float64) (Job, *FakeSynthesizer) {
+	t.Helper()
+	dir := t.TempDir()
+	video := filepath.Join(dir, "video.wav")
+	if err := os.WriteFile(video, audio.EncodeWAV(make([]float32, int(seconds*SampleRate)), SampleRate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	voice := &FakeSynthesizer{}
+	return Job{
+		VideoPath:      video,
+		OutputPath:     filepath.Join(dir, "dubbed.wav"),
+		WorkDir:        dir,
+		TargetLanguage: "fr",
+		Voice:          voice,
+	}, voice
+}
+
+func TestRun(t *testing.T) {
+	job, voice := testJob(t, 6)
+	transcriber := &stt.Fake{Segments: []stt.Segment{
+		// Out of order, and with an empty segment, as providers sometimes report them
+		{Start: 4, End: 5, Text: "a b c d e f g h i"},
+		{Start: 0.5, End: 1.5, Text: "hello world"},
+		{Start: 1.6, End: 1.8, Text: "  "},
+		{Start: 2, End: 3, Text: "one two three four five six"},
+	}}
+	media := &recordingMedia{}
+	var progress []Progress
+	pipeline := &Pipeline{
+		Transcriber: transcriber,
+		Translator:  &FakeTranslator{},
+		Media:       media,
+		Progress:    func(p Progress) { progress = append(progress, p) },
+	}
+
+	result, err := pipeline.Run(context.Background(), job)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	wantProgress := []Progress{
+		{Stage: StageExtracting},
+		{Stage: StageTranscribing},
+		{Stage: StageTranslating, Total: 3},
+		{Stage: StageSynthesizing, Done: 0, Total: 3},
+		{Stage: StageSynthesizing, Done: 1, Total: 3},
+		{Stage: StageSynthesizing, Done: 2, Total: 3},
+		{Stage: StageMixing, Done: 3, Total: 3},
+	}
+	if !reflect.DeepEqual(progress, wantProgress) {
+		t.Errorf("progress = %v, want %v", progress, wantProgress)
+	}
+
+	// The fake voice speaks 0.4 s per word, and a clip may run on to the next segment
+	wantSegments := []Segment{
+		// 3 words, 1.2 s in a 1.5 s slot: played as is
+		{Start: 0.5, End: 1.5, Text: "hello world", Translation: "[fr] hello world", Tempo: 1},
+		// 7 words, 2.8 s in a 2 s slot: sped up to fit
+		{Start: 2, End: 3, Text: "one two three four five six", Translation: "[fr] one two three four five six", Tempo: 1.4},
+		// 10 words, 4 s in the 2 s left: sped up as far as allowed, the rest cut
+		{Start: 4, End: 5, Text: "a b c d e f g h i", Translation: "[fr] a b c d e f g h i", Tempo: DefaultMaxTempo, Cut: 4/DefaultMaxTempo - 2},
+	}
+	if len(result.Segments) != len(wantSegments) {
+		t.Fatalf("segments = %+v, want %+v", result.Segments, wantSegments)
+	}
+	for i, want := range wantSegments {
+		got := result.Segments[i]
+		if got.Start != want.Start || got.End != want.End || got.Text != want.Text || got.Translation != want.Translation ||
+			math.Abs(got.Tempo-want.Tempo) > 1e-3 || math.Abs(got.Cut-want.Cut) > 1e-3 {
+			t.Errorf("segment %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	wantTexts := []string{wantSegments[0].Translation, wantSegments[1].Translation, wantSegments[2].Translation}
+	if !reflect.DeepEqual(voice.Texts, wantTexts) {
+		t.Errorf("synthesized %q, want %q", voice.Texts, wantTexts)
+	}
+	if len(transcriber.Paths) != 1 || filepath.Dir(transcriber.Paths[0]) != job.WorkDir {
+		t.Errorf("transcribed %v, want the extracted audio", transcriber.Paths)
+	}
+
+	if result.Duration != 6 || result.TargetLanguage != "fr" || result.OutputPath != job.OutputPath {
+		t.Errorf("result = %+v", result)
+	}
+	wantMux := [][]string{{job.VideoPath, result.TrackPath, job.OutputPath, "fr"}}
+	if !reflect.DeepEqual(media.muxes, wantMux) {
+		t.Errorf("muxed %v, want %v", media.muxes, wantMux)
+	}
+
+	// The track is as long as the video, with speech only where it was placed
+	track, err := WAVMedia{}.Samples(context.Background(), job.OutputPath)
+	if err != nil {
+		t.Fatalf("reading the dubbed output: %v", err)
+	}
+	if len(track) != 6*SampleRate {
+		t.Errorf("track is %d samples, want %d", len(track), 6*SampleRate)
+	}
+	for _, span := range []struct {
+		from, to float64
+		speech   bool
+	}{
+		{0, 0.5, false},
+		{0.5, 1.7, true},
+		{1.71, 2, false},
+		{2, 4, true},
+		{4, 5.9, true},
+	} {
+		if got := peak(track, span.from, span.to) > 0.05; got != span.speech {
+			t.Errorf("speech between %.2f and %.2f s = %v, want %v", span.from, span.to, got, span.speech)
+		}
+	}
+	// The cut clip fades out into the end of the video
+	if last := math.Abs(float64(track[len(track)-1])); last > 0.01 {
+		t.Errorf("last sample is %.3f, want the cut faded out", last)
+	}
+}
+
+func TestRunSaveTrack(t *testing.T) {
+	job, _ := testJob(t, 2)
+	job.TrackPath = filepath.Join(job.WorkDir, "kept.wav")
+	var saved string
+	job.SaveTrack = func(ctx context.Context, path string, data []byte) error {
+		saved = path
+		return os.WriteFile(path, data, 0644)
+	}
+	pipeline := &Pipeline{
+		Transcriber: &stt.Fake{Text: "hello"},
+		Translator:  &FakeTranslator{},
+		Media:       WAVMedia{},
+	}
+
+	result, err := pipeline.Run(context.Background(), job)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if saved != job.TrackPath || result.TrackPath != job.TrackPath {
+		t.Errorf("track saved to %q and reported as %q, want %q", saved, result.TrackPath, job.TrackPath)
+	}
+	// Without timestamps the whole transcript is one segment across the video
+	if len(result.Segments) != 1 || result.Segments[0].Start != 0 || result.Segments[0].End != 2 {
+		t.Errorf("segments = %+v, want one spanning the video", result.Segments)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	failure := errors.New("provider down")
+	tests := []struct {
+		name       string
+		transcript *stt.Fake
+		translator *FakeTranslator
+		want       error
+	}{
+		{"no speech", &stt.Fake{Text: " "}, &FakeTranslator{}, ErrNoSpeech},
+		{"transcriber fails", &stt.Fake{Err: failure}, &FakeTranslator{}, failure},
+		{"translator fails", &stt.Fake{Text: "hello"}, &FakeTranslator{Err: failure}, failure},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			job, _ := testJob(t, 2)
+			media := &recordingMedia{}
+			pipeline := &Pipeline{Transcriber: test.transcript, Translator: test.translator, Media: media}
+			if _, err := pipeline.Run(context.Background(), job); !errors.Is(err, test.want) {
+				t.Fatalf("Run = %v, want %v", err, test.want)
+			}
+			if len(media.muxes) != 0 {
+				t.Error("a failed run still muxed")
+			}
+		})
+	}
+}
+
+func TestFitTempo(t *testing.T) {
+	tests := []struct {
+		natural, slot, want float64
+	}{
+		{1, 2, 1},
+		{2, 2, 1},
+		{3, 2, 1.5},
+		{5, 2, 1.5},
+		{1, 0, 1},
+	}
+	for _, test := range tests {
+		if got := fitTempo(test.natural, test.slot, 1.5); got != test.want {
+			t.Errorf("fitTempo(%g, %g) = %g, want %g", test.natural, test.slot, got, test.want)
+		}
+	}
+}
+
+// peak is the loudest sample between from and to seconds
+func peak(samples []float32, from, to float64) float64 {
+	loudest := 0.0
+	for _, s := range samples[int(from*SampleRate):min(int(to*SampleRate), len(samples))] {
+		loudest = max(loudest, math.Abs(float64(s)))
+	}
+	return loudest
+}
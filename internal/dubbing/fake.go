@@ -0,0 +1,120 @@
+package dubbing
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+)
+
+// FakeTranslator "translates" by tagging every text with the target language,
+// or fails with Err.
+type FakeTranslator struct {
+	Err error
+}
+
+func (f *FakeTranslator) Translate(ctx context.Context, texts []string, source, target string) ([]string, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	translations := make([]string, len(texts))
+	for i, text := range texts {
+		translations[i] = fmt.Sprintf("[%s] %s", target, text)
+	}
+	return translations, nil
+}
+
+// FakeSynthesizer speaks a quiet tone as WAV at SampleRate, WordDuration
+// seconds per word (0.4 if unset), or fails with Err. It records the texts
+// it was asked to speak.
+type FakeSynthesizer struct {
+	WordDuration float64
+	Err          error
+
+	mu    sync.Mutex
+	Texts []string
+}
+
+func (f *FakeSynthesizer) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	f.mu.Lock()
+	f.Texts = append(f.Texts, text)
+	f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	perWord := f.WordDuration
+	if perWord <= 0 {
+		perWord = 0.4
+	}
+	samples := make([]float32, int(perWord*float64(len(strings.Fields(text)))*SampleRate))
+	for i := range samples {
+		samples[i] = float32(0.1 * math.Sin(2*math.Pi*220*float64(i)/SampleRate))
+	}
+	return audio.EncodeWAV(samples, SampleRate), nil
+}
+
+// WAVMedia stands in for FFmpeg without any external tools. It treats every
+// input, the "video" included, as 16-bit PCM WAV at SampleRate, changes tempo
+// by plain resampling (which shifts the pitch too) and "muxes" by writing
+// the new track as the output.
+type WAVMedia struct{}
+
+func (WAVMedia) ExtractAudio(ctx context.Context, videoPath, outputPath string) error {
+	samples, err := WAVMedia{}.Samples(ctx, videoPath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, audio.EncodeWAV(samples, SampleRate), 0644)
+}
+
+func (WAVMedia) Samples(ctx context.Context, path string) ([]float32, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := audio.ParseWAV(data)
+	if err != nil {
+		return nil, err
+	}
+	if layout.SampleRate != SampleRate {
+		return nil, fmt.Errorf("%s is at %d Hz, not %d", path, layout.SampleRate, SampleRate)
+	}
+
+	pcm := data[layout.DataOffset : layout.DataOffset+layout.DataSize]
+	samples := make([]float32, len(pcm)/(2*layout.Channels))
+	for i := range samples {
+		sum := 0
+		for c := 0; c < layout.Channels; c++ {
+			sum += int(int16(binary.LittleEndian.Uint16(pcm[2*(i*layout.Channels+c):])))
+		}
+		samples[i] = float32(sum) / float32(layout.Channels) / 32768
+	}
+	return samples, nil
+}
+
+func (WAVMedia) ChangeTempo(ctx context.Context, inputPath, outputPath string, tempo float64) error {
+	samples, err := WAVMedia{}.Samples(ctx, inputPath)
+	if err != nil {
+		return err
+	}
+	out := make([]float32, int(float64(len(samples))/tempo))
+	for i := range out {
+		out[i] = samples[min(int(float64(i)*tempo), len(samples)-1)]
+	}
+	return os.WriteFile(outputPath, audio.EncodeWAV(out, SampleRate), 0644)
+}
+
+func (WAVMedia) Mux(ctx context.Context, videoPath, audioPath, outputPath, language string) error {
+	data, err := os.ReadFile(audioPath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, data, 0644)
+}
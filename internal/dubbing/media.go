@@ -0,0 +1,54 @@
+package dubbing
+
+import (
+	"context"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+)
+
+// SampleRate is the rate the new track is assembled at.
+const SampleRate = audio.CanonicalSampleRate
+
+// Synthesizer speaks text in one voice and returns the audio in any format
+// Media can read.
+type Synthesizer interface {
+	Synthesize(ctx context.Context, text string) ([]byte, error)
+}
+
+// SynthesizerFunc adapts a function to Synthesizer.
+type SynthesizerFunc func(ctx context.Context, text string) ([]byte, error)
+
+func (f SynthesizerFunc) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	return f(ctx, text)
+}
+
+// Media is the audio and video plumbing the pipeline needs.
+type Media interface {
+	// ExtractAudio writes the sound of videoPath to outputPath as WAV
+	ExtractAudio(ctx context.Context, videoPath, outputPath string) error
+	// Samples decodes path to mono samples at SampleRate
+	Samples(ctx context.Context, path string) ([]float32, error)
+	// ChangeTempo speeds inputPath up by tempo without changing its pitch
+	ChangeTempo(ctx context.Context, inputPath, outputPath string, tempo float64) error
+	// Mux writes videoPath with audioPath as its main sound track to outputPath
+	Mux(ctx context.Context, videoPath, audioPath, outputPath, language string) error
+}
+
+// FFmpeg does the pipeline's media work with ffmpeg.
+type FFmpeg struct{}
+
+func (FFmpeg) ExtractAudio(ctx context.Context, videoPath, outputPath string) error {
+	return audio.Normalize(ctx, videoPath, outputPath)
+}
+
+func (FFmpeg) Samples(ctx context.Context, path string) ([]float32, error) {
+	return audio.DecodeSamples(ctx, path, SampleRate)
+}
+
+func (FFmpeg) ChangeTempo(ctx context.Context, inputPath, outputPath string, tempo float64) error {
+	return audio.ChangeTempo(ctx, inputPath, outputPath, tempo)
+}
+
+func (FFmpeg) Mux(ctx context.Context, videoPath, audioPath, outputPath, language string) error {
+	return audio.ReplaceAudio(ctx, videoPath, audioPath, outputPath, language)
+}
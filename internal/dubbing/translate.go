@@ -0,0 +1,189 @@
+package dubbing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Translator translates texts from source to target, both language codes
+// such as "en" or "de". An empty source asks the provider to detect it. The
+// result holds one translation per text, in order.
+type Translator interface {
+	Translate(ctx context.Context, texts []string, source, target string) ([]string, error)
+}
+
+// ErrNoTranslator is returned by DefaultTranslator when no provider is set up.
+var ErrNoTranslator = errors.New("translation is not configured")
+
+var (
+	defaultTranslator    Translator
+	defaultTranslatorErr error
+	defaultTranslateOnce sync.Once
+)
+
+// DefaultTranslator returns the translator chosen by TRANSLATOR: "deepl" (the
+// default when DEEPL_API_KEY is set), "libretranslate" for the server at
+// LIBRETRANSLATE_URL, or "fake", which tags texts with the target language
+// instead of translating them and is meant for local development.
+func DefaultTranslator() (Translator, error) {
+	defaultTranslateOnce.Do(func() {
+		provider := os.Getenv("TRANSLATOR")
+		if provider == "" && os.Getenv("DEEPL_API_KEY") != "" {
+			provider = "deepl"
+		}
+
+		switch provider {
+		case "deepl":
+			defaultTranslator = NewDeepL(os.Getenv("DEEPL_API_KEY"))
+		case "libretranslate":
+			if url := os.Getenv("LIBRETRANSLATE_URL"); url != "" {
+				defaultTranslator = NewLibreTranslate(url, os.Getenv("LIBRETRANSLATE_API_KEY"))
+			} else {
+				defaultTranslatorErr = fmt.Errorf("TRANSLATOR is libretranslate but LIBRETRANSLATE_URL is not set")
+			}
+		case "fake":
+			defaultTranslator = &FakeTranslator{}
+		case "":
+			defaultTranslatorErr = ErrNoTranslator
+		default:
+			defaultTranslatorErr = fmt.Errorf("unknown TRANSLATOR %q", provider)
+		}
+	})
+	return defaultTranslator, defaultTranslatorErr
+}
+
+// DeepL translates with the DeepL API. Free-tier keys (ending in ":fx") go
+// to the free endpoint.
+type DeepL struct {
+	APIKey  string
+	BaseURL string
+	Timeout time.Duration
+}
+
+func NewDeepL(apiKey string) *DeepL {
+	baseURL := "https://api.deepl.com/v2"
+	if strings.HasSuffix(apiKey, ":fx") {
+		baseURL = "https://api-free.deepl.com/v2"
+	}
+	return &DeepL{APIKey: apiKey, BaseURL: baseURL, Timeout: time.Minute}
+}
+
+type deeplRequest struct {
+	Text       []string `json:"text"`
+	SourceLang string   `json:"source_lang,omitempty"`
+	TargetLang string   `json:"target_lang"`
+}
+
+type deeplResponse struct {
+	Translations []struct {
+		Text string `json:"text"`
+	} `json:"translations"`
+}
+
+func (d *DeepL) Translate(ctx context.Context, texts []string, source, target string) ([]string, error) {
+	if d.APIKey == "" {
+		return nil, ErrNoTranslator
+	}
+
+	// DeepL wants upper case codes, and only the base language for the source
+	request := deeplRequest{Text: texts, TargetLang: strings.ToUpper(target)}
+	if source != "" {
+		request.SourceLang = strings.ToUpper(strings.SplitN(source, "-", 2)[0])
+	}
+
+	var response deeplResponse
+	err := postJSON(ctx, d.Timeout, d.BaseURL+"/translate", "DeepL-Auth-Key "+d.APIKey, request, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	translations := make([]string, len(response.Translations))
+	for i, t := range response.Translations {
+		translations[i] = t.Text
+	}
+	return translations, nil
+}
+
+// LibreTranslate translates with a (usually self-hosted) LibreTranslate server.
+type LibreTranslate struct {
+	BaseURL string
+	APIKey  string
+	Timeout time.Duration
+}
+
+func NewLibreTranslate(baseURL, apiKey string) *LibreTranslate {
+	return &LibreTranslate{BaseURL: strings.TrimSuffix(baseURL, "/"), APIKey: apiKey, Timeout: 2 * time.Minute}
+}
+
+type libreRequest struct {
+	Q      []string `json:"q"`
+	Source string   `json:"source"`
+	Target string   `json:"target"`
+	Format string   `json:"format"`
+	APIKey string   `json:"api_key,omitempty"`
+}
+
+type libreResponse struct {
+	TranslatedText []string `json:"translatedText"`
+}
+
+func (l *LibreTranslate) Translate(ctx context.Context, texts []string, source, target string) ([]string, error) {
+	if source == "" {
+		source = "auto"
+	}
+	request := libreRequest{Q: texts, Source: source, Target: target, Format: "text", APIKey: l.APIKey}
+
+	var response libreResponse
+	if err := postJSON(ctx, l.Timeout, l.BaseURL+"/translate", "", request, &response); err != nil {
+		return nil, err
+	}
+	return response.TranslatedText, nil
+}
+
+// postJSON sends request to url and decodes a 200 response into response
+func postJSON(ctx context.Context, timeout time.Duration, url, authorization string, request, response interface{}) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("error marshalling payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("translation failed with status code %d: %s", resp.StatusCode, body)
+	}
+
+	if err := json.Unmarshal(body, response); err != nil {
+		return fmt.Errorf("error unmarshalling response: %w", err)
+	}
+	return nil
+}
@@ -2,27 +2,38 @@ package elevenlabs
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
+// ErrVoiceNotFound is returned when a request names a voice the account no
+// longer has, such as one deleted on the ElevenLabs site
+var ErrVoiceNotFound = errors.New("voice not found")
+
 type Client struct {
 	APIKey  string
 	BaseURL string
+
+	// Timeout bounds each API call on top of the caller's context
+	Timeout time.Duration
 }
 
 func NewClient(apiKey string) *Client {
 	return &Client{
 		APIKey:  apiKey,
 		BaseURL: "https://api.elevenlabs.io/v1",
+		Timeout: 60 * time.Second,
 	}
 }
 
-func (c *Client) postJSON(endpoint string, payload []byte) ([]byte, error) {
+func (c *Client) postJSON(ctx context.Context, endpoint string, payload []byte) ([]byte, error) {
 	url := fmt.Sprintf("%s/%s", c.BaseURL, endpoint)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payload))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payload))
 
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
@@ -30,45 +41,48 @@ func (c *Client) postJSON(endpoint string, payload []byte) ([]byte, error) {
 
 	req.Header.Set("Content-Type", "application/json")
 
-	return c.doRequest(req)
+	return c.doRequest(ctx, req)
 }
 
-func (c *Client) postFormData(endpoint string, formData *bytes.Buffer, contentType string) ([]byte, error) {
+func (c *Client) postFormData(ctx context.Context, endpoint string, formData *bytes.Buffer, contentType string) ([]byte, error) {
 	url := fmt.Sprintf("%s/%s", c.BaseURL, endpoint)
-	req, err := http.NewRequest("POST", url, formData)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, formData)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 	req.Header.Set("Content-Type", contentType)
 
-	return c.doRequest(req)
+	return c.doRequest(ctx, req)
 }
 
-func (c *Client) getRequest(endpoint string) ([]byte, error) {
+func (c *Client) getRequest(ctx context.Context, endpoint string) ([]byte, error) {
 	url := fmt.Sprintf("%s/%s", c.BaseURL, endpoint)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
-	return c.doRequest(req)
+	return c.doRequest(ctx, req)
 }
 
-func (c *Client) deleteRequest(endpoint string) ([]byte, error) {
+func (c *Client) deleteRequest(ctx context.Context, endpoint string) ([]byte, error) {
 	url := fmt.Sprintf("%s/%s", c.BaseURL, endpoint)
-	req, err := http.NewRequest("DELETE", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
-	return c.doRequest(req)
+	return c.doRequest(ctx, req)
 }
 
-func (c *Client) doRequest(req *http.Request) ([]byte, error) {
+func (c *Client) doRequest(ctx context.Context, req *http.Request) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	req = req.WithContext(ctx)
 	req.Header.Set("xi-api-key", c.APIKey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := http.DefaultClient.Do(req)
 
 	if err != nil {
 		return nil, fmt.Errorf("error sending request: %w", err)
@@ -91,6 +105,9 @@ func (c *Client) doRequest(req *http.Request) ([]byte, error) {
 		if err := json.Unmarshal(body, &errorResponse); err != nil {
 			return nil, fmt.Errorf("API request failed with status code: %d\nBody: %s", resp.StatusCode, body)
 		}
+		if errorResponse.Detail.Status == "voice_not_found" {
+			return nil, fmt.Errorf("%w\nMessage: %s", ErrVoiceNotFound, errorResponse.Detail.Message)
+		}
 		return nil, fmt.Errorf("API request failed with status code: %d\nMessage: %s", resp.StatusCode, errorResponse.Detail.Message)
 	}
 
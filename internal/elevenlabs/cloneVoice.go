@@ -2,6 +2,7 @@ package elevenlabs
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,7 +14,7 @@ import (
 // CloneVoice clones a voice by uploading an audio to the elevenlabs API, a voice id is returned.
 // It takes a YouTube ID as input and returns the response from the server as a string (voice id).
 // If the YouTube ID is empty or if there is an error during the process, an error is returned.
-func (c *Client) cloneVoice(youtubeID string) (string, error) {
+func (c *Client) cloneVoice(ctx context.Context, youtubeID string) (string, error) {
 	if youtubeID == "" {
 		return "", fmt.Errorf("youtubeID is empty")
 	}
@@ -24,30 +25,49 @@ func (c *Client) cloneVoice(youtubeID string) (string, error) {
 		return "", fmt.Errorf("failed to get path or audio does not exist: %v", err)
 	}
 
+	return c.AddVoice(ctx, youtubeID, []string{audioFilePath})
+}
+
+// Limits of the clips an instant voice clone is made from
+const (
+	MaxVoiceFiles = 25
+	// MaxVoiceFileSeconds keeps a 16-bit 44.1 kHz mono WAV under the 10 MB
+	// a file may be
+	MaxVoiceFileSeconds = 110
+)
+
+// AddVoice clones a voice named name from the audio files at paths, each
+// sent as its own files part, and returns its voice ID. The voice is labelled
+// as added by this app.
+func (c *Client) AddVoice(ctx context.Context, name string, paths []string) (string, error) {
+	if len(paths) == 0 || len(paths) > MaxVoiceFiles {
+		return "", fmt.Errorf("a voice needs between 1 and %d files, got %d", MaxVoiceFiles, len(paths))
+	}
+
 	// Perpare the multipart form data
 	var formData bytes.Buffer
 	writer := multipart.NewWriter(&formData)
 
 	// Add `name` part
-	err = writer.WriteField("name", youtubeID)
+	err := writer.WriteField("name", name)
 	if err != nil {
 		return "", fmt.Errorf("failed to write 'name' field: %v", err)
 	}
 
-	// Add 'files' part
-	file, err := os.Open(audioFilePath)
+	// Add `labels` part, a JSON object sent as a string
+	labels, err := json.Marshal(map[string]string{appLabelKey: appLabelValue})
 	if err != nil {
-		return "", fmt.Errorf("failed to open audio file: %v", err)
+		return "", fmt.Errorf("failed to marshal labels: %v", err)
 	}
-	defer file.Close()
-
-	part, err := writer.CreateFormFile("files", filepath.Base(audioFilePath))
-	if err != nil {
-		return "", fmt.Errorf("failed to create form file: %v", err)
+	if err := writer.WriteField("labels", string(labels)); err != nil {
+		return "", fmt.Errorf("failed to write 'labels' field: %v", err)
 	}
-	_, err = io.Copy(part, file)
-	if err != nil {
-		return "", fmt.Errorf("failed to copy file to part: %v", err)
+
+	// Add one 'files' part per clip
+	for _, path := range paths {
+		if err := writeFilePart(writer, "files", path); err != nil {
+			return "", err
+		}
 	}
 
 	err = writer.Close()
@@ -60,7 +80,7 @@ func (c *Client) cloneVoice(youtubeID string) (string, error) {
 	endpoint := "voices/add"
 	contentType := writer.FormDataContentType()
 
-	response, err := c.postFormData(endpoint, &formData, contentType)
+	response, err := c.postFormData(ctx, endpoint, &formData, contentType)
 	if err != nil {
 		return "", fmt.Errorf("failed to post form data: %v", err)
 	}
@@ -76,3 +96,22 @@ func (c *Client) cloneVoice(youtubeID string) (string, error) {
 
 	return voiceResponse.VoiceID, nil
 }
+
+// writeFilePart adds the file at path to writer as a field part
+func writeFilePart(writer *multipart.Writer, field, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open audio file: %v", err)
+	}
+	defer file.Close()
+
+	part, err := writer.CreateFormFile(field, filepath.Base(path))
+	if err != nil {
+		return fmt.Errorf("failed to create form file: %v", err)
+	}
+	_, err = io.Copy(part, file)
+	if err != nil {
+		return fmt.Errorf("failed to copy file to part: %v", err)
+	}
+	return nil
+}
@@ -1,18 +1,25 @@
 package elevenlabs
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
 
 const MAX_VOICES int = 10
 
-func (c *Client) GetVoiceID(youtubeID string) (string, error) {
+// The label marking the voices this app added, the only ones it removes again
+const (
+	appLabelKey   = "added_by"
+	appLabelValue = "youtube-voice-go"
+)
+
+func (c *Client) GetVoiceID(ctx context.Context, youtubeID string) (string, error) {
 	if youtubeID == "" {
 		return "", fmt.Errorf("youtubeID is empty")
 	}
 
-	voiceID, err := c.getSavedVoiceID(youtubeID)
+	voiceID, err := c.getSavedVoiceID(ctx, youtubeID)
 	if err != nil {
 		return "", fmt.Errorf("failed to get saved voice ID: %v", err)
 	}
@@ -21,33 +28,37 @@ func (c *Client) GetVoiceID(youtubeID string) (string, error) {
 		return voiceID, nil
 	}
 
-	err = c.removeVoiceIfMaxReached()
+	err = c.removeVoiceIfMaxReached(ctx, nil)
 
 	if err != nil {
 		return "", fmt.Errorf("failed to remove voice: %v", err)
 	}
 
-	return c.cloneVoice(youtubeID)
+	return c.cloneVoice(ctx, youtubeID)
 
 	// return "4srV5pKnTwmwqQLucA8p", nil
 }
 
 type VoicesResponse struct {
 	Voices []struct {
-		VoiceID  string `json:"voice_id"`
-		Name     string `json:"name"`
-		Category string `json:"category"`
+		VoiceID  string            `json:"voice_id"`
+		Name     string            `json:"name"`
+		Category string            `json:"category"`
+		Labels   map[string]string `json:"labels"`
 	} `json:"voices"`
 }
 
 type Voice struct {
 	VoiceID string
 	Name    string
+
+	// addedByApp is set for voices labelled as added by AddVoice
+	addedByApp bool
 }
 
-func (c *Client) getVoices() ([]Voice, error) {
+func (c *Client) getVoices(ctx context.Context) ([]Voice, error) {
 	endpoint := "voices"
-	body, err := c.getRequest(endpoint)
+	body, err := c.getRequest(ctx, endpoint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get voices: %v", err)
 	}
@@ -62,8 +73,9 @@ func (c *Client) getVoices() ([]Voice, error) {
 	for _, voice := range response.Voices {
 		if voice.Category == "cloned" {
 			voices = append(voices, Voice{
-				VoiceID: voice.VoiceID,
-				Name:    voice.Name,
+				VoiceID:    voice.VoiceID,
+				Name:       voice.Name,
+				addedByApp: voice.Labels[appLabelKey] == appLabelValue,
 			})
 		}
 	}
@@ -71,8 +83,8 @@ func (c *Client) getVoices() ([]Voice, error) {
 	return voices, nil
 }
 
-func (c *Client) getSavedVoiceID(youtubeID string) (string, error) {
-	voices, err := c.getVoices()
+func (c *Client) getSavedVoiceID(ctx context.Context, youtubeID string) (string, error) {
+	voices, err := c.getVoices(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get voices: %v", err)
 	}
@@ -86,27 +98,41 @@ func (c *Client) getSavedVoiceID(youtubeID string) (string, error) {
 	return "", nil
 }
 
-func (c *Client) removeVoiceIfMaxReached() error {
-	voices, err := c.getVoices()
+// removeVoiceIfMaxReached makes room when the account already has MAX_VOICES
+// cloned voices by removing one this app added and inUse doesn't hold.
+// Voices added any other way are left alone.
+func (c *Client) removeVoiceIfMaxReached(ctx context.Context, inUse map[string]bool) error {
+	voices, err := c.getVoices(ctx)
 
 	if err != nil {
 		return fmt.Errorf("failed to get voices: %v", err)
 	}
 
-	if len(voices) >= MAX_VOICES {
-		voiceID := voices[0].VoiceID
-		err = c.removeVoice(voiceID)
-		if err != nil {
-			return fmt.Errorf("failed to remove voice: %v", err)
+	if len(voices) < MAX_VOICES {
+		return nil
+	}
+
+	for _, voice := range voices {
+		if voice.addedByApp && !inUse[voice.VoiceID] {
+			return c.RemoveVoice(ctx, voice.VoiceID)
 		}
 	}
+	return fmt.Errorf("the account already has %d cloned voices and all of them are in use", len(voices))
+}
 
-	return nil
+// AddVoiceWithinLimit adds a voice like AddVoice, first removing a voice this
+// app added and inUse doesn't hold if the account already has MAX_VOICES.
+func (c *Client) AddVoiceWithinLimit(ctx context.Context, name string, paths []string, inUse map[string]bool) (string, error) {
+	if err := c.removeVoiceIfMaxReached(ctx, inUse); err != nil {
+		return "", fmt.Errorf("failed to remove voice: %v", err)
+	}
+	return c.AddVoice(ctx, name, paths)
 }
 
-func (c *Client) removeVoice(voiceID string) error {
+// RemoveVoice deletes a cloned voice
+func (c *Client) RemoveVoice(ctx context.Context, voiceID string) error {
 	endpoint := fmt.Sprintf("voices/%s", voiceID)
-	_, err := c.deleteRequest(endpoint)
+	_, err := c.deleteRequest(ctx, endpoint)
 	if err != nil {
 		return fmt.Errorf("failed to delete voice: %v", err)
 	}
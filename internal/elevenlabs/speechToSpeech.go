@@ -0,0 +1,34 @@
+package elevenlabs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+)
+
+// SpeechToSpeechModel is the model performances are converted with
+const SpeechToSpeechModel = "eleven_multilingual_sts_v2"
+
+// SpeechToSpeech re-voices the performance at audioPath in voiceID, keeping
+// its delivery and timing, and returns the result as MP3.
+func (c *Client) SpeechToSpeech(ctx context.Context, voiceID, audioPath string) ([]byte, error) {
+	if voiceID == "" {
+		return nil, fmt.Errorf("voiceID is empty")
+	}
+
+	var formData bytes.Buffer
+	writer := multipart.NewWriter(&formData)
+	if err := writer.WriteField("model_id", SpeechToSpeechModel); err != nil {
+		return nil, fmt.Errorf("failed to write 'model_id' field: %v", err)
+	}
+	if err := writeFilePart(writer, "audio", audioPath); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close writer: %v", err)
+	}
+
+	endpoint := fmt.Sprintf("speech-to-speech/%s", voiceID)
+	return c.postFormData(ctx, endpoint, &formData, writer.FormDataContentType())
+}
@@ -1,12 +1,14 @@
 package elevenlabs
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
 )
 
-func (c *Client) TextToSpeech(voiceID, text string) ([]byte, error) {
+func (c *Client) TextToSpeech(ctx context.Context, voiceID, text string) ([]byte, error) {
 	endpoint := fmt.Sprintf("text-to-speech/%s", voiceID)
 
 	payload := map[string]interface{}{
@@ -24,9 +26,54 @@ func (c *Client) TextToSpeech(voiceID, text string) ([]byte, error) {
 		return nil, fmt.Errorf("error marshalling payload: %w", err)
 	}
 
-	return c.postJSON(endpoint, jsonPayload)
+	return c.postJSON(ctx, endpoint, jsonPayload)
 }
 
 func (c *Client) SaveAudioFile(audio []byte, filename string) error {
 	return os.WriteFile(filename, audio, 0644)
 }
+
+// Alignment gives the time span of every character of the spoken text, in seconds.
+type Alignment struct {
+	Characters []string  `json:"characters"`
+	StartTimes []float64 `json:"character_start_times_seconds"`
+	EndTimes   []float64 `json:"character_end_times_seconds"`
+}
+
+// TextToSpeechWithTimestamps is TextToSpeech that also returns when each
+// character of text is spoken, for captions.
+func (c *Client) TextToSpeechWithTimestamps(ctx context.Context, voiceID, text string) ([]byte, *Alignment, error) {
+	endpoint := fmt.Sprintf("text-to-speech/%s/with-timestamps", voiceID)
+
+	payload := map[string]interface{}{
+		"text":     text,
+		"model_id": "eleven_monolingual_v1",
+		"voice_settings": map[string]float64{
+			"stability":        0.5,
+			"similarity_boost": 0.5,
+		},
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error marshalling payload: %w", err)
+	}
+
+	body, err := c.postJSON(ctx, endpoint, jsonPayload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response struct {
+		AudioBase64 string     `json:"audio_base64"`
+		Alignment   *Alignment `json:"alignment"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, nil, fmt.Errorf("error unmarshalling response: %w", err)
+	}
+	audio, err := base64.StdEncoding.DecodeString(response.AudioBase64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decoding audio: %w", err)
+	}
+	return audio, response.Alignment, nil
+}
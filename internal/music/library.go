@@ -0,0 +1,85 @@
+// Package music is the library of background tracks speech can be mixed
+// over. Tracks are audio files in a directory on disk, listed by file name.
+package music
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/henrik392/youtube-voice-go/internal/storage"
+)
+
+// ErrNotFound is returned for a track that isn't in the library.
+var ErrNotFound = errors.New("track not found")
+
+// trackExts are the file types listed as tracks
+var trackExts = map[string]bool{
+	".mp3": true, ".wav": true, ".m4a": true, ".ogg": true, ".opus": true, ".flac": true,
+}
+
+// Library lists and resolves the tracks in Dir.
+type Library struct {
+	Dir string
+}
+
+var (
+	defaultLibrary *Library
+	defaultOnce    sync.Once
+)
+
+// Default returns the library in MUSIC_LIBRARY_DIR, ./music by default.
+func Default() *Library {
+	defaultOnce.Do(func() {
+		dir := os.Getenv("MUSIC_LIBRARY_DIR")
+		if dir == "" {
+			dir = "music"
+		}
+		defaultLibrary = &Library{Dir: dir}
+	})
+	return defaultLibrary
+}
+
+// Tracks returns the names of the library's tracks in alphabetical order. A
+// missing directory is an empty library.
+func (l *Library) Tracks() ([]string, error) {
+	entries, err := os.ReadDir(l.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list music library: %w", err)
+	}
+
+	tracks := []string{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && trackExts[strings.ToLower(filepath.Ext(entry.Name()))] {
+			tracks = append(tracks, entry.Name())
+		}
+	}
+	sort.Strings(tracks)
+	return tracks, nil
+}
+
+// Path returns where the track called name is on disk. Only plain names of
+// tracks in the library are accepted.
+func (l *Library) Path(name string) (string, error) {
+	if name != filepath.Base(name) || !trackExts[strings.ToLower(filepath.Ext(name))] {
+		return "", ErrNotFound
+	}
+	path, err := (&storage.Local{Root: l.Dir}).Resolve(name)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return "", ErrNotFound
+	}
+	return path, nil
+}
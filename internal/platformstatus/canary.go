@@ -0,0 +1,87 @@
+package platformstatus
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// ProbeFunc checks that url can still be fetched from its platform.
+type ProbeFunc func(ctx context.Context, url string) error
+
+// CanaryConfigFromEnv reads CANARY_INTERVAL and one CANARY_URL_<PLATFORM> per
+// platform. The canary is disabled when the interval or all URLs are missing.
+func CanaryConfigFromEnv(platforms []string) (time.Duration, map[string]string) {
+	raw := os.Getenv("CANARY_INTERVAL")
+	if raw == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval <= 0 {
+		log.Printf("Invalid CANARY_INTERVAL %q, canary disabled", raw)
+		return 0, nil
+	}
+
+	targets := map[string]string{}
+	for _, platform := range platforms {
+		if url := os.Getenv("CANARY_URL_" + strings.ToUpper(platform)); url != "" {
+			targets[platform] = url
+		}
+	}
+	return interval, targets
+}
+
+// StartCanary probes every target once per interval until ctx is done,
+// recording each result as a canary attempt.
+func (t *Tracker) StartCanary(ctx context.Context, interval time.Duration, targets map[string]string, probe ProbeFunc) {
+	if interval <= 0 || len(targets) == 0 {
+		return
+	}
+
+	log.Printf("Platform canary: probing %d platforms every %s", len(targets), interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			for platform, url := range targets {
+				t.runCanary(ctx, platform, url, probe, interval)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (t *Tracker) runCanary(ctx context.Context, platform, url string, probe ProbeFunc, interval time.Duration) {
+	probeCtx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+
+	if err := probe(probeCtx, url); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Platform canary: %s failed: %v", platform, err)
+		t.RecordFailure(platform, SourceCanary, firstLine(err.Error()))
+		return
+	}
+
+	t.RecordSuccess(platform, SourceCanary)
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	if len(s) > 200 {
+		s = s[:200]
+	}
+	return s
+}
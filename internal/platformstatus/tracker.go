@@ -0,0 +1,165 @@
+package platformstatus
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	StateUnknown  = "unknown"
+	StateOK       = "ok"
+	StateDegraded = "degraded"
+	StateDown     = "down"
+)
+
+const (
+	SourceUser   = "user"
+	SourceCanary = "canary"
+)
+
+type attempt struct {
+	at      time.Time
+	success bool
+	source  string
+	reason  string
+}
+
+// Status is the availability of one platform derived from recent download attempts.
+type Status struct {
+	Platform    string     `json:"platform"`
+	State       string     `json:"state"`
+	SuccessRate float64    `json:"success_rate"`
+	Attempts    int        `json:"attempts"`
+	Failures    int        `json:"failures"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastCanary  *time.Time `json:"last_canary,omitempty"`
+}
+
+// Disabled reports whether new downloads from the platform should be refused.
+func (s Status) Disabled() bool {
+	return s.State == StateDown
+}
+
+// Tracker keeps a sliding window of download attempts per platform.
+type Tracker struct {
+	// Window is how far back attempts are considered
+	Window time.Duration
+	// MinAttempts is how many attempts are needed before a platform leaves the unknown state
+	MinAttempts int
+	// DegradedBelow and DownBelow are success-rate thresholds
+	DegradedBelow float64
+	DownBelow     float64
+
+	mu       sync.Mutex
+	attempts map[string][]attempt
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultTracker *Tracker
+)
+
+func New() *Tracker {
+	return &Tracker{
+		Window:        30 * time.Minute,
+		MinAttempts:   3,
+		DegradedBelow: 0.7,
+		DownBelow:     0.2,
+		attempts:      map[string][]attempt{},
+	}
+}
+
+// Default returns the process-wide tracker fed by every download.
+func Default() *Tracker {
+	defaultOnce.Do(func() {
+		defaultTracker = New()
+	})
+	return defaultTracker
+}
+
+func (t *Tracker) RecordSuccess(platform, source string) {
+	t.record(platform, attempt{at: time.Now(), success: true, source: source})
+}
+
+func (t *Tracker) RecordFailure(platform, source, reason string) {
+	t.record(platform, attempt{at: time.Now(), success: false, source: source, reason: reason})
+}
+
+func (t *Tracker) record(platform string, a attempt) {
+	if platform == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.attempts[platform] = append(t.prune(platform, a.at), a)
+}
+
+// prune drops attempts that fell out of the window. Callers must hold t.mu.
+func (t *Tracker) prune(platform string, now time.Time) []attempt {
+	attempts := t.attempts[platform]
+	cutoff := now.Add(-t.Window)
+
+	i := 0
+	for i < len(attempts) && attempts[i].at.Before(cutoff) {
+		i++
+	}
+	attempts = attempts[i:]
+	t.attempts[platform] = attempts
+	return attempts
+}
+
+func (t *Tracker) Status(platform string) Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	attempts := t.prune(platform, time.Now())
+	status := Status{Platform: platform, State: StateUnknown, Attempts: len(attempts)}
+
+	successes := 0
+	for _, a := range attempts {
+		at := a.at
+		if a.success {
+			successes++
+			status.LastSuccess = &at
+		} else {
+			status.Failures++
+			status.LastFailure = &at
+			status.LastError = a.reason
+		}
+		if a.source == SourceCanary {
+			status.LastCanary = &at
+		}
+	}
+
+	if len(attempts) == 0 {
+		return status
+	}
+	status.SuccessRate = float64(successes) / float64(len(attempts))
+
+	// The most recent attempt wins over the rate when it's a success: the platform works again
+	latest := attempts[len(attempts)-1]
+	switch {
+	case len(attempts) < t.MinAttempts:
+		status.State = StateUnknown
+	case status.SuccessRate < t.DownBelow && !latest.success:
+		status.State = StateDown
+	case status.SuccessRate < t.DegradedBelow:
+		status.State = StateDegraded
+	default:
+		status.State = StateOK
+	}
+
+	return status
+}
+
+func (t *Tracker) Statuses(platforms []string) []Status {
+	statuses := make([]Status, 0, len(platforms))
+	for _, platform := range platforms {
+		statuses = append(statuses, t.Status(platform))
+	}
+	return statuses
+}
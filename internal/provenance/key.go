@@ -0,0 +1,100 @@
+package provenance
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Signer signs manifests with this instance's ed25519 key.
+type Signer struct {
+	key ed25519.PrivateKey
+	// KeyID is a short fingerprint of the public key, recorded in every
+	// manifest so a verifier can tell which instance signed it
+	KeyID string
+}
+
+var (
+	defaultSigner *Signer
+	defaultErr    error
+	defaultOnce   sync.Once
+)
+
+// Default returns the signer configured by PROVENANCE_KEY (a base64 ed25519
+// seed or private key) or, failing that, the key in PROVENANCE_KEY_FILE
+// (default ./provenance.key), which is generated on first use.
+func Default() (*Signer, error) {
+	defaultOnce.Do(func() {
+		if encoded := os.Getenv("PROVENANCE_KEY"); encoded != "" {
+			defaultSigner, defaultErr = ParseKey(encoded)
+			return
+		}
+
+		path := os.Getenv("PROVENANCE_KEY_FILE")
+		if path == "" {
+			path = "provenance.key"
+		}
+		defaultSigner, defaultErr = LoadOrCreateKey(path)
+		if defaultErr == nil {
+			log.Printf("Provenance signing key %s loaded from %s", defaultSigner.KeyID, path)
+		}
+	})
+	return defaultSigner, defaultErr
+}
+
+// NewSigner wraps an existing private key.
+func NewSigner(key ed25519.PrivateKey) *Signer {
+	public := key.Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(public)
+	return &Signer{key: key, KeyID: hex.EncodeToString(sum[:8])}
+}
+
+// ParseKey decodes a base64 32-byte seed or 64-byte private key.
+func ParseKey(encoded string) (*Signer, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid provenance key: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return NewSigner(ed25519.NewKeyFromSeed(raw)), nil
+	case ed25519.PrivateKeySize:
+		return NewSigner(ed25519.PrivateKey(raw)), nil
+	}
+	return nil, fmt.Errorf("invalid provenance key: expected %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+}
+
+// LoadOrCreateKey reads a key file written by a previous run, or creates one.
+func LoadOrCreateKey(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return ParseKey(string(data))
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read provenance key: %w", err)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate provenance key: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(key.Seed()) + "\n"
+	if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return nil, fmt.Errorf("failed to save provenance key: %w", err)
+	}
+	log.Printf("Generated a new provenance signing key in %s", path)
+	return NewSigner(key), nil
+}
+
+// PublicKey returns the key verifiers need, base64 encoded.
+func (s *Signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
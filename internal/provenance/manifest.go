@@ -0,0 +1,118 @@
+package provenance
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Generator names this software in tags and manifests.
+const Generator = "youtube-voice-go"
+
+// ManifestVersion is bumped whenever the signed fields change.
+const ManifestVersion = 1
+
+// SidecarExt is appended to an audio file's path for its manifest.
+const SidecarExt = ".provenance.json"
+
+// Manifest describes one generated file. Every field is covered by the signature.
+type Manifest struct {
+	Version      int       `json:"version"`
+	Generator    string    `json:"generator"`
+	AIGenerated  bool      `json:"ai_generated"`
+	GenerationID string    `json:"generation_id"`
+	Provider     string    `json:"provider"`
+	CreatedAt    time.Time `json:"created_at"`
+	// SHA256 is the hex digest of the audio file exactly as stored, tags included
+	SHA256 string `json:"sha256"`
+	KeyID  string `json:"key_id"`
+}
+
+// SignedManifest is the sidecar written next to every generated file.
+type SignedManifest struct {
+	Manifest  Manifest `json:"manifest"`
+	Signature string   `json:"signature"`
+}
+
+// NewManifest describes data, the final bytes of a generated file.
+func NewManifest(generationID, provider string, createdAt time.Time, data []byte) Manifest {
+	return Manifest{
+		Version:      ManifestVersion,
+		Generator:    Generator,
+		AIGenerated:  true,
+		GenerationID: generationID,
+		Provider:     provider,
+		CreatedAt:    createdAt.UTC().Truncate(time.Second),
+		SHA256:       Hash(data),
+	}
+}
+
+// Hash is the digest recorded in manifests.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign fills in the key ID and signs the manifest's JSON encoding.
+func (s *Signer) Sign(m Manifest) (*SignedManifest, error) {
+	m.KeyID = s.KeyID
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return &SignedManifest{
+		Manifest:  m,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, payload)),
+	}, nil
+}
+
+// Verify checks that the manifest was signed by this signer's key.
+func (s *Signer) Verify(sm *SignedManifest) bool {
+	if sm.Manifest.KeyID != s.KeyID {
+		return false
+	}
+	signature, err := base64.StdEncoding.DecodeString(sm.Signature)
+	if err != nil {
+		return false
+	}
+	payload, err := json.Marshal(sm.Manifest)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(s.key.Public().(ed25519.PublicKey), payload, signature)
+}
+
+// WriteSidecar saves sm as audioPath + SidecarExt.
+func WriteSidecar(audioPath string, sm *SignedManifest) error {
+	data, err := json.MarshalIndent(sm, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(audioPath+SidecarExt, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// ReadSidecar loads the manifest saved next to audioPath.
+func ReadSidecar(audioPath string) (*SignedManifest, error) {
+	data, err := os.ReadFile(audioPath + SidecarExt)
+	if err != nil {
+		return nil, err
+	}
+	return ParseManifest(data)
+}
+
+// ParseManifest decodes a sidecar's contents.
+func ParseManifest(data []byte) (*SignedManifest, error) {
+	var sm SignedManifest
+	if err := json.Unmarshal(data, &sm); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return &sm, nil
+}
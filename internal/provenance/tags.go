@@ -0,0 +1,189 @@
+package provenance
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+)
+
+// generationIDKey prefixes the generation ID inside the embedded comment, so
+// it can be found again without a full tag parser.
+const generationIDKey = "generation_id="
+
+// uuidLen is the length of a generation ID in its canonical text form
+const uuidLen = 36
+
+// Tag embeds provenance metadata in data: RIFF INFO chunks for WAV and an
+// ID3v2.3 tag for MP3. Other formats are returned unchanged, since the
+// sidecar manifest still covers them.
+func Tag(data []byte, generationID string, createdAt time.Time) ([]byte, error) {
+	comment := fmt.Sprintf("AI-generated speech. generator=%s %s%s", Generator, generationIDKey, generationID)
+
+	switch audio.Sniff(head(data)) {
+	case audio.FormatWAV:
+		return tagWAV(data, map[string]string{
+			"ISFT": Generator,
+			"ICMT": comment,
+			"ICRD": createdAt.UTC().Format("2006-01-02"),
+		})
+	case audio.FormatMP3:
+		return tagMP3(data, comment), nil
+	}
+	return data, nil
+}
+
+// ReadGenerationID finds the generation ID embedded by Tag, if it survived.
+func ReadGenerationID(data []byte) (string, bool) {
+	var region []byte
+	switch audio.Sniff(head(data)) {
+	case audio.FormatWAV:
+		region = wavInfo(data)
+	case audio.FormatMP3:
+		region = data[:id3Size(data)]
+	}
+
+	i := bytes.Index(region, []byte(generationIDKey))
+	if i < 0 || len(region) < i+len(generationIDKey)+uuidLen {
+		return "", false
+	}
+	start := i + len(generationIDKey)
+	return string(region[start : start+uuidLen]), true
+}
+
+func head(data []byte) []byte {
+	if len(data) > audio.SniffLen {
+		return data[:audio.SniffLen]
+	}
+	return data
+}
+
+type riffChunk struct {
+	id   string
+	body []byte
+}
+
+// riffChunks splits a WAV file into its top-level chunks. A data chunk whose
+// size runs past the end, as streamed encoders write it, is cut to fit.
+func riffChunks(data []byte) ([]riffChunk, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("truncated WAV header")
+	}
+
+	var chunks []riffChunk
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		start := offset + 8
+		if size < 0 || start+size > len(data) {
+			size = len(data) - start
+		}
+		chunks = append(chunks, riffChunk{id: id, body: data[start : start+size]})
+		offset = start + size + size%2
+	}
+	return chunks, nil
+}
+
+// tagWAV replaces any LIST/INFO chunk with one holding tags.
+func tagWAV(data []byte, tags map[string]string) ([]byte, error) {
+	chunks, err := riffChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	info := bytes.NewBufferString("INFO")
+	for _, id := range []string{"ISFT", "ICMT", "ICRD"} {
+		value, ok := tags[id]
+		if !ok {
+			continue
+		}
+		writeRIFFChunk(info, id, append([]byte(value), 0))
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)+info.Len()+8))
+	out.WriteString("RIFF")
+	out.Write([]byte{0, 0, 0, 0}) // patched below
+	out.WriteString("WAVE")
+	for _, chunk := range chunks {
+		if chunk.id == "LIST" && bytes.HasPrefix(chunk.body, []byte("INFO")) {
+			continue
+		}
+		writeRIFFChunk(out, chunk.id, chunk.body)
+	}
+	writeRIFFChunk(out, "LIST", info.Bytes())
+
+	tagged := out.Bytes()
+	binary.LittleEndian.PutUint32(tagged[4:8], uint32(len(tagged)-8))
+	return tagged, nil
+}
+
+func writeRIFFChunk(buf *bytes.Buffer, id string, body []byte) {
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(body)))
+	buf.WriteString(id)
+	buf.Write(size[:])
+	buf.Write(body)
+	if len(body)%2 == 1 {
+		buf.WriteByte(0)
+	}
+}
+
+// wavInfo returns the body of the LIST/INFO chunk, or nil
+func wavInfo(data []byte) []byte {
+	chunks, err := riffChunks(data)
+	if err != nil {
+		return nil
+	}
+	for _, chunk := range chunks {
+		if chunk.id == "LIST" && bytes.HasPrefix(chunk.body, []byte("INFO")) {
+			return chunk.body
+		}
+	}
+	return nil
+}
+
+// tagMP3 replaces any leading ID3v2 tag with an ID3v2.3 tag holding the
+// encoder (TSSE), a comment (COMM) and a "provenance" TXXX frame.
+func tagMP3(data []byte, comment string) []byte {
+	frames := &bytes.Buffer{}
+	writeID3Frame(frames, "TSSE", append([]byte{0}, Generator...))
+	writeID3Frame(frames, "COMM", append([]byte{0, 'e', 'n', 'g', 0}, comment...))
+	writeID3Frame(frames, "TXXX", append([]byte("\x00provenance\x00"), comment...))
+
+	size := frames.Len()
+	header := []byte{'I', 'D', '3', 3, 0, 0,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+
+	rest := data[id3Size(data):]
+	out := make([]byte, 0, len(header)+size+len(rest))
+	out = append(out, header...)
+	out = append(out, frames.Bytes()...)
+	return append(out, rest...)
+}
+
+func writeID3Frame(buf *bytes.Buffer, id string, body []byte) {
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(body)))
+	buf.WriteString(id)
+	buf.Write(size[:])
+	buf.Write([]byte{0, 0})
+	buf.Write(body)
+}
+
+// id3Size is the length of a leading ID3v2 tag, or 0 without one
+func id3Size(data []byte) int {
+	if len(data) < 10 || !bytes.HasPrefix(data, []byte("ID3")) {
+		return 0
+	}
+	size := int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F)
+	size += 10
+	if data[5]&0x10 != 0 {
+		size += 10 // footer
+	}
+	if size > len(data) {
+		return len(data)
+	}
+	return size
+}
@@ -0,0 +1,231 @@
+package proxypool
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Proxy is one upstream HTTP or SOCKS proxy and its recent track record.
+type Proxy struct {
+	URL string
+	// Platforms the proxy may be used for; empty means every platform
+	Platforms []string
+
+	successes     int
+	failures      int
+	consecutive   int
+	lastUsed      time.Time
+	lastError     string
+	cooldownUntil time.Time
+}
+
+// Redacted returns the proxy URL with any password masked, for logs and health output.
+func (p *Proxy) Redacted() string {
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return "invalid proxy URL"
+	}
+	return u.Redacted()
+}
+
+func (p *Proxy) servesPlatform(platform string) bool {
+	if len(p.Platforms) == 0 {
+		return true
+	}
+	for _, pl := range p.Platforms {
+		if pl == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// Status is the health of one proxy as reported by /health.
+type Status struct {
+	Proxy         string     `json:"proxy"`
+	Platforms     []string   `json:"platforms,omitempty"`
+	Successes     int        `json:"successes"`
+	Failures      int        `json:"failures"`
+	LastError     string     `json:"last_error,omitempty"`
+	CooldownUntil *time.Time `json:"cooldown_until,omitempty"`
+}
+
+// Pool hands out proxies per platform, skipping those that are cooling down.
+type Pool struct {
+	// BotCooldown is how long a proxy rests after the platform flagged it as a bot
+	BotCooldown time.Duration
+	// FailureCooldown is how long a proxy rests after MaxConsecutiveFailures other errors
+	FailureCooldown        time.Duration
+	MaxConsecutiveFailures int
+
+	mu      sync.Mutex
+	proxies []*Proxy
+}
+
+var (
+	defaultOnce sync.Once
+	defaultPool *Pool
+)
+
+func New(proxies []*Proxy) *Pool {
+	return &Pool{
+		BotCooldown:            30 * time.Minute,
+		FailureCooldown:        5 * time.Minute,
+		MaxConsecutiveFailures: 3,
+		proxies:                proxies,
+	}
+}
+
+// Default returns the pool configured by DOWNLOAD_PROXIES (used for every
+// platform) and DOWNLOAD_PROXIES_<PLATFORM> (used only for that platform).
+// Both hold comma-separated http://, https://, socks5:// or socks5h:// URLs.
+func Default(platforms []string) *Pool {
+	defaultOnce.Do(func() {
+		var proxies []*Proxy
+		proxies = append(proxies, parseList(os.Getenv("DOWNLOAD_PROXIES"), nil)...)
+		for _, platform := range platforms {
+			env := os.Getenv("DOWNLOAD_PROXIES_" + strings.ToUpper(platform))
+			proxies = append(proxies, parseList(env, []string{platform})...)
+		}
+
+		defaultPool = New(proxies)
+		if v := os.Getenv("DOWNLOAD_PROXY_COOLDOWN"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil {
+				defaultPool.BotCooldown = d
+			} else {
+				log.Printf("Invalid DOWNLOAD_PROXY_COOLDOWN %q: %v", v, err)
+			}
+		}
+
+		if len(proxies) > 0 {
+			log.Printf("Proxy pool: %d proxies configured", len(proxies))
+		}
+	})
+	return defaultPool
+}
+
+func parseList(list string, platforms []string) []*Proxy {
+	var proxies []*Proxy
+	for _, raw := range strings.Split(list, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if err := validate(raw); err != nil {
+			log.Printf("Ignoring proxy: %v", err)
+			continue
+		}
+		proxies = append(proxies, &Proxy{URL: raw, Platforms: platforms})
+	}
+	return proxies
+}
+
+func validate(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks4", "socks4a", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q in %s", u.Scheme, u.Redacted())
+	}
+	if u.Host == "" {
+		return fmt.Errorf("proxy URL %s has no host", u.Redacted())
+	}
+	return nil
+}
+
+// HasProxies reports whether any proxy is configured for platform.
+func (p *Pool) HasProxies(platform string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, proxy := range p.proxies {
+		if proxy.servesPlatform(platform) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the least recently used healthy proxy for platform that is not
+// in tried. It returns nil when every eligible proxy is cooling down or was tried.
+func (p *Pool) Next(platform string, tried map[*Proxy]bool) *Proxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var best *Proxy
+	for _, proxy := range p.proxies {
+		if !proxy.servesPlatform(platform) || tried[proxy] || proxy.cooldownUntil.After(now) {
+			continue
+		}
+		if best == nil || proxy.lastUsed.Before(best.lastUsed) {
+			best = proxy
+		}
+	}
+
+	if best != nil {
+		best.lastUsed = now
+	}
+	return best
+}
+
+func (p *Pool) ReportSuccess(proxy *Proxy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	proxy.successes++
+	proxy.consecutive = 0
+	proxy.lastError = ""
+}
+
+// ReportFailure records a failed download through proxy. Bot detection puts the
+// proxy on cooldown immediately; other errors only after several in a row.
+func (p *Pool) ReportFailure(proxy *Proxy, botDetected bool, reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	proxy.failures++
+	proxy.consecutive++
+	proxy.lastError = reason
+
+	switch {
+	case botDetected:
+		proxy.cooldownUntil = time.Now().Add(p.BotCooldown)
+		log.Printf("Proxy %s flagged by platform, cooling down until %s", proxy.Redacted(), proxy.cooldownUntil.Format(time.RFC3339))
+	case proxy.consecutive >= p.MaxConsecutiveFailures:
+		proxy.cooldownUntil = time.Now().Add(p.FailureCooldown)
+		proxy.consecutive = 0
+		log.Printf("Proxy %s failed %d times in a row, cooling down until %s", proxy.Redacted(), p.MaxConsecutiveFailures, proxy.cooldownUntil.Format(time.RFC3339))
+	}
+}
+
+func (p *Pool) Statuses() []Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]Status, 0, len(p.proxies))
+	for _, proxy := range p.proxies {
+		status := Status{
+			Proxy:     proxy.Redacted(),
+			Platforms: proxy.Platforms,
+			Successes: proxy.successes,
+			Failures:  proxy.failures,
+			LastError: proxy.lastError,
+		}
+		if proxy.cooldownUntil.After(now) {
+			until := proxy.cooldownUntil
+			status.CooldownUntil = &until
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
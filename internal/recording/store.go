@@ -0,0 +1,316 @@
+package recording
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+)
+
+var (
+	ErrNotFound      = errors.New("recording not found")
+	ErrInUse         = errors.New("recording is already being streamed")
+	ErrTooLong       = errors.New("recording is too long")
+	ErrBadSampleRate = errors.New("unsupported sample rate")
+	ErrEmpty         = errors.New("recording is empty")
+)
+
+// Sample rates a client may stream at. Browsers capture at 44.1 or 48 kHz
+// and may downsample before sending.
+const (
+	MinSampleRate = 16000
+	MaxSampleRate = 48000
+)
+
+// Level is the loudness of one streamed chunk, reported back to the client
+// as a live meter.
+type Level struct {
+	RMS      float64 `json:"rms"`
+	Peak     float64 `json:"peak"`
+	Clipping bool    `json:"clipping"`
+	// Elapsed is the length of the whole recording so far, in seconds
+	Elapsed float64 `json:"elapsed"`
+}
+
+type info struct {
+	ID         string    `json:"id"`
+	SampleRate int       `json:"sample_rate"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Store keeps recordings that are being streamed as raw 16-bit mono PCM
+// (<id>.pcm) plus a small JSON file (<id>.json), so a recording survives a
+// dropped connection and can be resumed or finished later.
+type Store struct {
+	Dir         string
+	MaxDuration time.Duration
+	// Expiry is how long an unfinished recording is kept after its last chunk
+	Expiry time.Duration
+
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+var (
+	defaultStore *Store
+	defaultOnce  sync.Once
+)
+
+// Default returns the shared store under downloads/recordings.
+func Default() *Store {
+	defaultOnce.Do(func() {
+		defaultStore = NewStore(filepath.Join("downloads", "recordings"), audio.UploadLimits.MaxDuration, time.Hour)
+	})
+	return defaultStore
+}
+
+func NewStore(dir string, maxDuration, expiry time.Duration) *Store {
+	return &Store{
+		Dir:         dir,
+		MaxDuration: maxDuration,
+		Expiry:      expiry,
+		active:      map[string]bool{},
+	}
+}
+
+// Session is one connection's handle on a recording.
+type Session struct {
+	ID         string
+	SampleRate int
+
+	store *Store
+	file  *os.File
+	bytes int64
+}
+
+// Create starts a new recording streamed at sampleRate.
+func (s *Store) Create(sampleRate int) (*Session, error) {
+	if sampleRate < MinSampleRate || sampleRate > MaxSampleRate {
+		return nil, ErrBadSampleRate
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recordings directory: %w", err)
+	}
+
+	meta := info{ID: uuid.New().String(), SampleRate: sampleRate, CreatedAt: time.Now()}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode recording info: %w", err)
+	}
+	if err := os.WriteFile(s.infoPath(meta.ID), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write recording info: %w", err)
+	}
+
+	return s.open(meta)
+}
+
+// Resume reopens an unfinished recording so more chunks can be appended.
+func (s *Store) Resume(id string) (*Session, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	data, err := os.ReadFile(s.infoPath(id))
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var meta info
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse recording info: %w", err)
+	}
+
+	return s.open(meta)
+}
+
+func (s *Store) open(meta info) (*Session, error) {
+	s.mu.Lock()
+	if s.active[meta.ID] {
+		s.mu.Unlock()
+		return nil, ErrInUse
+	}
+	s.active[meta.ID] = true
+	s.mu.Unlock()
+
+	file, err := os.OpenFile(s.dataPath(meta.ID), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		s.release(meta.ID)
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+
+	// A connection that died mid-sample leaves an odd byte behind
+	size, err := file.Seek(0, io.SeekEnd)
+	if err == nil && size%2 != 0 {
+		size--
+		err = file.Truncate(size)
+		if err == nil {
+			_, err = file.Seek(size, io.SeekStart)
+		}
+	}
+	if err != nil {
+		file.Close()
+		s.release(meta.ID)
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+
+	return &Session{ID: meta.ID, SampleRate: meta.SampleRate, store: s, file: file, bytes: size}, nil
+}
+
+// Write appends a chunk of 16-bit little-endian mono PCM and measures it.
+func (sess *Session) Write(chunk []byte) (Level, error) {
+	// Chunks should hold whole samples; drop a stray trailing byte
+	chunk = chunk[:len(chunk)&^1]
+
+	if sess.store.MaxDuration > 0 && sess.duration(sess.bytes+int64(len(chunk))) > sess.store.MaxDuration {
+		return Level{Elapsed: sess.Elapsed()}, ErrTooLong
+	}
+	if _, err := sess.file.Write(chunk); err != nil {
+		return Level{}, fmt.Errorf("failed to write recording: %w", err)
+	}
+	sess.bytes += int64(len(chunk))
+
+	samples := make([]float32, len(chunk)/2)
+	for i := range samples {
+		samples[i] = float32(int16(binary.LittleEndian.Uint16(chunk[2*i:]))) / 32768
+	}
+	rms, peak, clipped := audio.Levels(samples)
+
+	return Level{RMS: rms, Peak: peak, Clipping: clipped > 0, Elapsed: sess.Elapsed()}, nil
+}
+
+// Elapsed is the length of the recording so far in seconds.
+func (sess *Session) Elapsed() float64 {
+	return sess.duration(sess.bytes).Seconds()
+}
+
+func (sess *Session) duration(bytes int64) time.Duration {
+	return time.Duration(bytes / 2 * int64(time.Second) / int64(sess.SampleRate))
+}
+
+// Close detaches the connection but keeps the recording for Resume or Finish.
+func (sess *Session) Close() error {
+	err := sess.file.Close()
+	sess.store.release(sess.ID)
+	return err
+}
+
+// Finish closes the session and wraps the PCM in a WAV file. The caller owns
+// the returned file and should Discard the recording once it is stored.
+func (sess *Session) Finish() (string, error) {
+	if err := sess.file.Close(); err != nil {
+		sess.store.release(sess.ID)
+		return "", fmt.Errorf("failed to close recording: %w", err)
+	}
+	defer sess.store.release(sess.ID)
+
+	if sess.bytes == 0 {
+		return "", ErrEmpty
+	}
+
+	wavPath := filepath.Join(sess.store.Dir, sess.ID+".wav")
+	if err := writeWAV(wavPath, sess.store.dataPath(sess.ID), sess.SampleRate, sess.bytes); err != nil {
+		os.Remove(wavPath)
+		return "", err
+	}
+	return wavPath, nil
+}
+
+// Discard deletes every file belonging to a recording.
+func (s *Store) Discard(id string) {
+	os.Remove(s.dataPath(id))
+	os.Remove(s.infoPath(id))
+	os.Remove(filepath.Join(s.Dir, id+".wav"))
+}
+
+// Sweep deletes unfinished recordings nobody has written to within Expiry.
+func (s *Store) Sweep() {
+	infos, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-s.Expiry)
+	for _, infoPath := range infos {
+		id := strings.TrimSuffix(filepath.Base(infoPath), ".json")
+
+		s.mu.Lock()
+		active := s.active[id]
+		s.mu.Unlock()
+		if active {
+			continue
+		}
+
+		// The data file's modification time is the last chunk received
+		lastWrite, err := os.Stat(s.dataPath(id))
+		if err != nil {
+			lastWrite, err = os.Stat(infoPath)
+		}
+		if err == nil && lastWrite.ModTime().Before(cutoff) {
+			log.Printf("Removing abandoned recording %s", id)
+			s.Discard(id)
+		}
+	}
+}
+
+// StartSweeper runs Sweep every interval until ctx is done.
+func (s *Store) StartSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Sweep()
+			}
+		}
+	}()
+}
+
+func (s *Store) release(id string) {
+	s.mu.Lock()
+	delete(s.active, id)
+	s.mu.Unlock()
+}
+
+func (s *Store) dataPath(id string) string {
+	return filepath.Join(s.Dir, id+".pcm")
+}
+
+func (s *Store) infoPath(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
+
+func writeWAV(wavPath, pcmPath string, sampleRate int, size int64) error {
+	pcm, err := os.Open(pcmPath)
+	if err != nil {
+		return fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer pcm.Close()
+
+	wav, err := os.Create(wavPath)
+	if err != nil {
+		return fmt.Errorf("failed to create recording WAV: %w", err)
+	}
+	defer wav.Close()
+
+	if _, err := wav.Write(audio.WAVHeader(sampleRate, 1, uint32(size))); err != nil {
+		return fmt.Errorf("failed to write recording WAV: %w", err)
+	}
+	if _, err := io.CopyN(wav, pcm, size); err != nil {
+		return fmt.Errorf("failed to write recording WAV: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,383 @@
+package resumable
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound         = errors.New("upload not found")
+	ErrOffsetMismatch   = errors.New("upload offset does not match")
+	ErrTooLarge         = errors.New("upload exceeds maximum size")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrBadChecksum      = errors.New("unsupported checksum")
+	ErrCompleted        = errors.New("upload already completed")
+)
+
+// DefaultMaxSize matches the limit on single-request uploads.
+const DefaultMaxSize = 50 << 20
+
+// ChecksumAlgorithms lists the algorithms accepted in Upload-Checksum headers.
+var ChecksumAlgorithms = []string{"sha256", "sha1", "md5"}
+
+// Upload is the server-side state of one resumable upload.
+type Upload struct {
+	ID        string            `json:"id"`
+	Length    int64             `json:"length"`
+	Offset    int64             `json:"offset"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+func (u *Upload) Complete() bool {
+	return u.Offset == u.Length
+}
+
+// Store keeps partially uploaded files on disk. Each upload is a data file
+// (<id>.bin) and a JSON info file (<id>.info) so uploads survive restarts.
+type Store struct {
+	Dir     string
+	MaxSize int64
+	// Expiry is how long an upload may sit idle before it is swept
+	Expiry time.Duration
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+var (
+	defaultStore *Store
+	defaultOnce  sync.Once
+)
+
+// Default returns the shared upload store under UPLOADS_DIR (default
+// downloads/uploads). Idle uploads expire after UPLOAD_EXPIRY (default 24h).
+func Default() *Store {
+	defaultOnce.Do(func() {
+		dir := os.Getenv("UPLOADS_DIR")
+		if dir == "" {
+			dir = filepath.Join("downloads", "uploads")
+		}
+
+		expiry := 24 * time.Hour
+		if value := os.Getenv("UPLOAD_EXPIRY"); value != "" {
+			if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+				expiry = parsed
+			} else {
+				log.Printf("Ignoring invalid UPLOAD_EXPIRY %q", value)
+			}
+		}
+
+		defaultStore = NewStore(dir, DefaultMaxSize, expiry)
+	})
+	return defaultStore
+}
+
+func NewStore(dir string, maxSize int64, expiry time.Duration) *Store {
+	return &Store{
+		Dir:     dir,
+		MaxSize: maxSize,
+		Expiry:  expiry,
+		locks:   map[string]*sync.Mutex{},
+	}
+}
+
+// Create registers a new upload of length bytes.
+func (s *Store) Create(length int64, metadata map[string]string) (*Upload, error) {
+	if length <= 0 {
+		return nil, fmt.Errorf("upload length must be positive")
+	}
+	if length > s.MaxSize {
+		return nil, ErrTooLarge
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create uploads directory: %w", err)
+	}
+
+	now := time.Now()
+	upload := &Upload{
+		ID:        uuid.New().String(),
+		Length:    length,
+		Metadata:  metadata,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.Expiry),
+	}
+
+	file, err := os.Create(s.dataPath(upload.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	file.Close()
+
+	if err := s.saveInfo(upload); err != nil {
+		os.Remove(s.dataPath(upload.ID))
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// Get loads an upload, treating expired uploads as missing.
+func (s *Store) Get(id string) (*Upload, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	data, err := os.ReadFile(s.infoPath(id))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload info: %w", err)
+	}
+
+	var upload Upload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, fmt.Errorf("failed to parse upload info: %w", err)
+	}
+	if time.Now().After(upload.ExpiresAt) && !upload.Complete() {
+		return nil, ErrNotFound
+	}
+
+	return &upload, nil
+}
+
+// WriteChunk appends the body of one PATCH request at offset. checksum is the
+// raw Upload-Checksum header ("<algorithm> <base64 digest>") and may be empty.
+// A chunk that fails its checksum is discarded and the offset left unchanged.
+func (s *Store) WriteChunk(id string, offset int64, body io.Reader, checksum string) (*Upload, error) {
+	lock := s.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	upload, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Complete() {
+		return upload, ErrCompleted
+	}
+	if offset != upload.Offset {
+		return upload, ErrOffsetMismatch
+	}
+
+	var verify hash.Hash
+	var expected []byte
+	if checksum != "" {
+		verify, expected, err = parseChecksum(checksum)
+		if err != nil {
+			return upload, err
+		}
+	}
+
+	file, err := os.OpenFile(s.dataPath(id), os.O_WRONLY, 0644)
+	if err != nil {
+		return upload, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return upload, fmt.Errorf("failed to seek upload file: %w", err)
+	}
+
+	// Never accept more than the declared length; the extra byte detects overflow
+	remaining := upload.Length - upload.Offset
+	reader := io.LimitReader(body, remaining+1)
+	var writer io.Writer = file
+	if verify != nil {
+		writer = io.MultiWriter(file, verify)
+	}
+
+	written, copyErr := io.Copy(writer, reader)
+	if written > remaining {
+		file.Truncate(offset)
+		return upload, ErrTooLarge
+	}
+	if verify != nil && copyErr == nil && !equalDigest(verify.Sum(nil), expected) {
+		file.Truncate(offset)
+		return upload, ErrChecksumMismatch
+	}
+
+	// Keep whatever arrived before a dropped connection so the client can resume from there.
+	// A checksummed chunk is all or nothing.
+	if copyErr != nil && verify != nil {
+		file.Truncate(offset)
+		return upload, copyErr
+	}
+
+	upload.Offset += written
+	upload.ExpiresAt = time.Now().Add(s.Expiry)
+	if err := s.saveInfo(upload); err != nil {
+		return upload, err
+	}
+
+	return upload, copyErr
+}
+
+// VerifyFile compares the assembled upload against a whole-file SHA-256 given in hex.
+func (s *Store) VerifyFile(id, sha256Hex string) error {
+	expected, err := hex.DecodeString(strings.TrimSpace(sha256Hex))
+	if err != nil || len(expected) != sha256.Size {
+		return ErrBadChecksum
+	}
+
+	file, err := os.Open(s.dataPath(id))
+	if err != nil {
+		return fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return fmt.Errorf("failed to hash upload file: %w", err)
+	}
+	if !equalDigest(h.Sum(nil), expected) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// Open returns the assembled data of an upload for reading.
+func (s *Store) Open(id string) (*os.File, error) {
+	return os.Open(s.dataPath(id))
+}
+
+// Delete removes an upload and its data.
+func (s *Store) Delete(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+
+	lock := s.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	os.Remove(s.dataPath(id))
+	err := os.Remove(s.infoPath(id))
+
+	s.mu.Lock()
+	delete(s.locks, id)
+	s.mu.Unlock()
+
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Sweep deletes uploads that have not been touched since their expiry.
+func (s *Store) Sweep() {
+	infos, err := filepath.Glob(filepath.Join(s.Dir, "*.info"))
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	for _, infoPath := range infos {
+		id := strings.TrimSuffix(filepath.Base(infoPath), ".info")
+
+		data, err := os.ReadFile(infoPath)
+		if err != nil {
+			continue
+		}
+		var upload Upload
+		if err := json.Unmarshal(data, &upload); err != nil || now.After(upload.ExpiresAt) {
+			log.Printf("Removing abandoned upload %s", id)
+			s.Delete(id)
+		}
+	}
+}
+
+// StartSweeper runs Sweep every interval until ctx is done.
+func (s *Store) StartSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Sweep()
+			}
+		}
+	}()
+}
+
+func (s *Store) lock(id string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, ok := s.locks[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.locks[id] = lock
+	}
+	return lock
+}
+
+func (s *Store) saveInfo(upload *Upload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return fmt.Errorf("failed to encode upload info: %w", err)
+	}
+
+	tmpPath := s.infoPath(upload.ID) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write upload info: %w", err)
+	}
+	return os.Rename(tmpPath, s.infoPath(upload.ID))
+}
+
+func (s *Store) dataPath(id string) string {
+	return filepath.Join(s.Dir, id+".bin")
+}
+
+func (s *Store) infoPath(id string) string {
+	return filepath.Join(s.Dir, id+".info")
+}
+
+func parseChecksum(header string) (hash.Hash, []byte, error) {
+	algorithm, encoded, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok {
+		return nil, nil, ErrBadChecksum
+	}
+	digest, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, ErrBadChecksum
+	}
+
+	switch strings.ToLower(algorithm) {
+	case "sha256":
+		return sha256.New(), digest, nil
+	case "sha1":
+		return sha1.New(), digest, nil
+	case "md5":
+		return md5.New(), digest, nil
+	}
+	return nil, nil, ErrBadChecksum
+}
+
+func equalDigest(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
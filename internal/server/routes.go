@@ -1,9 +1,16 @@
 package server
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/henrik392/youtube-voice-go/cmd/web"
+	"github.com/henrik392/youtube-voice-go/internal/cookies"
+	"github.com/henrik392/youtube-voice-go/internal/platformstatus"
+	"github.com/henrik392/youtube-voice-go/internal/proxypool"
+	"github.com/henrik392/youtube-voice-go/internal/workerpool"
+	"github.com/henrik392/youtube-voice-go/internal/youtube"
 
 	"github.com/a-h/templ"
 	"github.com/go-chi/chi/v5"
@@ -15,6 +22,7 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r.Use(middleware.Logger)
 
 	r.Get("/health", s.healthHandler)
+	r.Get("/metrics", s.metricsHandler)
 
 	fileServer := http.FileServer(http.FS(web.Files))
 	r.Handle("/assets/*", fileServer)
@@ -29,24 +37,142 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r.Post("/generate-voice-enhanced", web.GenerateVoiceEnhancedHandler)
 	r.Post("/generate-voice-optimized", web.GenerateVoiceOptimizedHandler)
 	r.Get("/serve-audio", web.ServeAudioHandler)
+	r.Get("/api/platform-status", web.PlatformStatusHandler)
 
 	// Component handlers for dynamic loading
 	r.Get("/components/url-input", web.URLInputHandler)
 	r.Get("/components/file-upload", web.FileUploadHandler)
 	r.Get("/components/microphone", web.MicrophoneHandler)
+	r.Get("/components/profiles", web.VoiceProfilesHandler)
+	r.Get("/components/history", web.GenerationHistoryHandler)
+	r.Get("/components/dubbing", web.DubFormHandler)
+	r.Get("/components/music", web.MusicFieldsHandler)
+	r.Get("/convert", web.ConvertPageHandler)
+	r.Post("/convert", web.ConvertSpeechHandler)
+	r.Get("/convert/{id}", web.ConversionStatusHandler)
 
 	// Audio input handlers
 	r.Post("/upload-audio", web.UploadAudioHandler)
 	r.Post("/save-recording", web.SaveRecordingHandler)
+	r.Get("/ws/recording", web.RecordingStreamHandler)
+	r.Get("/api/audio-quality/{id}", web.QualityReportHandler)
+	r.Get("/references/{id}/peaks.json", web.ReferencePeaksHandler)
+	r.Get("/references/{id}/spectrogram.png", web.ReferenceSpectrogramHandler)
+	r.Get("/videos/{id}/peaks.json", web.VideoPeaksHandler)
+	r.Get("/videos/{id}/spectrogram.png", web.VideoSpectrogramHandler)
+
+	// Saved voice profiles
+	r.Post("/profiles", web.CreateVoiceProfileHandler)
+	r.Get("/profiles/{id}/sample", web.VoiceProfileSampleHandler)
+	r.Delete("/profiles/{id}", web.DeleteVoiceProfileHandler)
+	r.Get("/api/profiles", web.ListVoiceProfilesHandler)
+	r.Get("/api/profiles/{id}", web.GetVoiceProfileHandler)
+
+	// Consent of a voice's owner, required for generation when CONSENT_REQUIRED is on
+	r.Post("/profiles/{id}/consent", web.CreateConsentHandler)
+	r.Get("/api/profiles/{id}/consents", web.ListConsentsHandler)
+	r.Get("/consent/{id}", web.ConsentPageHandler)
+	r.Post("/consent/{id}", web.VerifyConsentHandler)
+
+	// Generation history
+	r.Get("/generations/{id}/audio", web.GenerationAudioHandler)
+	r.Get("/generations/{id}/mix", web.GenerationMixHandler)
+	r.Get("/generations/{id}/peaks.json", web.GenerationPeaksHandler)
+	r.Get("/generations/{id}/spectrogram.png", web.GenerationSpectrogramHandler)
+	r.Get("/generations/{id}/captions.{format}", web.GenerationCaptionsHandler)
+	r.Post("/generations/{id}/regenerate", web.RegenerateHandler)
+	r.Post("/generations/{id}/effects", web.GenerationEffectsHandler)
+	r.Delete("/generations/{id}", web.DeleteGenerationHandler)
+	r.Get("/api/generations", web.ListGenerationsHandler)
+	r.Get("/api/generations/{id}", web.GetGenerationHandler)
+	r.Get("/api/generations/{id}/words", web.GetGenerationWordsHandler)
+
+	// Provenance of generated audio
+	r.Get("/generations/{id}/manifest", web.GenerationManifestHandler)
+	r.Post("/api/provenance/verify", web.VerifyProvenanceHandler)
+	r.Get("/api/provenance/key", web.ProvenanceKeyHandler)
+	r.Post("/api/watermark/detect", web.DetectWatermarkHandler)
+
+	// Dubbing videos into another language
+	r.Post("/dub", web.DubVideoHandler)
+	r.Get("/dub/{id}", web.DubStatusHandler)
+	r.Get("/dub/{id}/video", web.DubVideoFileHandler)
+	r.Get("/api/dubs/{id}", web.GetDubHandler)
+
+	// Public share links
+	r.Post("/generations/{id}/share", web.CreateShareHandler)
+	r.Get("/api/generations/{id}/shares", web.ListSharesHandler)
+	r.Delete("/shares/{token}", web.DeleteShareHandler)
+	r.Get("/s/{token}", web.SharePageHandler)
+	r.Get("/s/{token}/audio", web.ShareAudioHandler)
+
+	// Resumable (tus) uploads for large reference files
+	r.Options("/uploads", web.TusOptionsHandler)
+	r.Post("/uploads", web.CreateUploadHandler)
+	r.Head("/uploads/{id}", web.UploadOffsetHandler)
+	r.Patch("/uploads/{id}", web.UploadChunkHandler)
+	r.Delete("/uploads/{id}", web.DeleteUploadHandler)
+
+	// Admin endpoints, disabled unless ADMIN_TOKEN is set
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(requireAdmin)
+		r.Get("/cookies", web.CookieStatusHandler)
+		r.Put("/cookies/{platform}", web.UploadCookiesHandler)
+		r.Delete("/cookies/{platform}", web.DeleteCookiesHandler)
+	})
 
 	return r
 }
 
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
+	// Cookie problems are reported as warnings; they don't make the service unhealthy
+	statuses := cookies.Default().Statuses(youtube.Platforms)
+	warnings := []string{}
+	for _, status := range statuses {
+		for _, warning := range status.Warnings {
+			warnings = append(warnings, fmt.Sprintf("%s cookies: %s", status.Platform, warning))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "OK",
+		"cookies":   statuses,
+		"proxies":   proxypool.Default(youtube.Platforms).Statuses(),
+		"platforms": platformstatus.Default().Statuses(youtube.Platforms),
+		"warnings":  warnings,
+	})
 
 	// For DB health check
 	// jsonResp, _ := json.Marshal(s.db.Health())
 	// _, _ = w.Write(jsonResp)
 }
+
+// metricsHandler exposes worker pool gauges in the Prometheus text format.
+func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	stats := workerpool.All()
+	metrics := []struct {
+		name  string
+		kind  string
+		help  string
+		value func(workerpool.Stats) float64
+	}{
+		{"workerpool_size", "gauge", "Maximum concurrent processes.", func(s workerpool.Stats) float64 { return float64(s.Size) }},
+		{"workerpool_in_flight", "gauge", "Processes currently running.", func(s workerpool.Stats) float64 { return float64(s.InFlight) }},
+		{"workerpool_queue_depth", "gauge", "Requests waiting for a slot.", func(s workerpool.Stats) float64 { return float64(s.Queued) }},
+		{"workerpool_acquired_total", "counter", "Slots handed out.", func(s workerpool.Stats) float64 { return float64(s.Acquired) }},
+		{"workerpool_rejected_total", "counter", "Requests rejected because the pool was saturated.", func(s workerpool.Stats) float64 { return float64(s.Rejected) }},
+		{"workerpool_wait_seconds_total", "counter", "Total time spent waiting for a slot.", func(s workerpool.Stats) float64 { return s.WaitTotal.Seconds() }},
+		{"workerpool_wait_seconds_max", "gauge", "Longest time spent waiting for a slot.", func(s workerpool.Stats) float64 { return s.WaitMax.Seconds() }},
+	}
+
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, st := range stats {
+			fmt.Fprintf(w, "%s{pool=%q} %g\n", m.name, st.Name, m.value(st))
+		}
+	}
+}
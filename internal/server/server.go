@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,6 +12,10 @@ import (
 	_ "github.com/joho/godotenv/autoload"
 
 	"github.com/henrik392/youtube-voice-go/internal/database"
+	"github.com/henrik392/youtube-voice-go/internal/platformstatus"
+	"github.com/henrik392/youtube-voice-go/internal/recording"
+	"github.com/henrik392/youtube-voice-go/internal/resumable"
+	"github.com/henrik392/youtube-voice-go/internal/youtube"
 )
 
 type Server struct {
@@ -32,6 +37,25 @@ func NewServer() *http.Server {
 		db:   database.New(),
 	}
 
+	// Create any missing tables. The database is optional for plain generation,
+	// so a failure only disables the features that need it.
+	migrateCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := NewServer.db.Migrate(migrateCtx); err != nil {
+		log.Printf("Database migration failed: %v", err)
+	}
+	cancel()
+
+	// Periodically check that every platform still downloads, if configured.
+	// The canary records its own results, so its processor doesn't report to the tracker.
+	interval, targets := platformstatus.CanaryConfigFromEnv(youtube.Platforms)
+	canary := youtube.NewProcessor(os.TempDir())
+	canary.Status = nil
+	platformstatus.Default().StartCanary(context.Background(), interval, targets, canary.Probe)
+
+	// Clean up uploads and streamed recordings the client never finished
+	resumable.Default().StartSweeper(context.Background(), 10*time.Minute)
+	recording.Default().StartSweeper(context.Background(), 10*time.Minute)
+
 	// Declare Server config
 	server := &http.Server{
 		Addr:         fmt.Sprintf("0.0.0.0:%d", NewServer.port),
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var ErrNotFound = errors.New("file not found")
+
+// Local serves stored files from a directory on disk. Files are looked up by
+// the path they were saved under, but only ever inside Root, so a path that
+// came from a database row or a link can't be used to read anything else.
+type Local struct {
+	Root string
+}
+
+var (
+	defaultLocal *Local
+	defaultOnce  sync.Once
+)
+
+// Default returns the store for everything under ./downloads.
+func Default() *Local {
+	defaultOnce.Do(func() {
+		defaultLocal = &Local{Root: "downloads"}
+	})
+	return defaultLocal
+}
+
+// Resolve cleans name and checks it lies inside Root. name may be relative
+// to Root ("speech_<id>.wav") or include it ("./downloads/speech_<id>.wav").
+func (l *Local) Resolve(name string) (string, error) {
+	root, err := filepath.Abs(l.Root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve storage root: %w", err)
+	}
+
+	path := name
+	if !filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(l.Root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		path = filepath.Join(root, path)
+	}
+
+	rel, err := filepath.Rel(root, filepath.Clean(path))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrNotFound
+	}
+	return filepath.Join(root, rel), nil
+}
+
+// Open returns a stored file and its info. Directories count as not found.
+func (l *Local) Open(name string) (*os.File, os.FileInfo, error) {
+	path, err := l.Resolve(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open stored file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to open stored file: %w", err)
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, nil, ErrNotFound
+	}
+	return file, info, nil
+}
@@ -0,0 +1,28 @@
+package stt
+
+import (
+	"context"
+	"sync"
+)
+
+// Fake is a Transcriber that hears Text, split into Segments if set, in
+// every file, or fails with Err. It records the paths it was asked about.
+type Fake struct {
+	Text     string
+	Segments []Segment
+	Err      error
+
+	mu    sync.Mutex
+	Paths []string
+}
+
+func (f *Fake) Transcribe(ctx context.Context, path string) (*Transcript, error) {
+	f.mu.Lock()
+	f.Paths = append(f.Paths, path)
+	f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	return &Transcript{Text: f.Text, Segments: f.Segments}, nil
+}
@@ -0,0 +1,126 @@
+package stt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+)
+
+// maxFalInput bounds what is sent inline; fal takes the audio as a data URI
+const maxFalInput = 25 << 20
+
+// Fal transcribes with Whisper on fal.ai.
+type Fal struct {
+	APIKey  string
+	BaseURL string
+	Timeout time.Duration
+}
+
+func NewFal(apiKey string) *Fal {
+	return &Fal{
+		APIKey:  apiKey,
+		BaseURL: "https://fal.run/fal-ai/whisper",
+		Timeout: 2 * time.Minute,
+	}
+}
+
+type falRequest struct {
+	AudioURL   string `json:"audio_url"`
+	Task       string `json:"task"`
+	ChunkLevel string `json:"chunk_level"`
+}
+
+type falResponse struct {
+	Text   string `json:"text"`
+	Chunks []struct {
+		Timestamp []float64 `json:"timestamp"`
+		Text      string    `json:"text"`
+	} `json:"chunks"`
+	InferredLanguages []string `json:"inferred_languages"`
+}
+
+func (f *Fal) Transcribe(ctx context.Context, path string) (*Transcript, error) {
+	if f.APIKey == "" {
+		return nil, ErrNotConfigured
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading audio: %w", err)
+	}
+	if len(data) > maxFalInput {
+		return nil, fmt.Errorf("audio is too large to transcribe (%d bytes)", len(data))
+	}
+
+	mimeType := "audio/" + audio.FormatWAV
+	switch format := audio.Sniff(data[:min(len(data), audio.SniffLen)]); format {
+	case audio.FormatUnknown, audio.FormatWAV:
+	case audio.FormatMP3:
+		mimeType = "audio/mpeg"
+	default:
+		mimeType = "audio/" + format
+	}
+
+	payload, err := json.Marshal(falRequest{
+		AudioURL:   "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data),
+		Task:       "transcribe",
+		ChunkLevel: "segment",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", f.BaseURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Key "+f.APIKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("transcription failed with status code %d: %s", resp.StatusCode, body)
+	}
+
+	var response falResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response: %w", err)
+	}
+
+	transcript := &Transcript{Text: strings.TrimSpace(response.Text)}
+	if len(response.InferredLanguages) > 0 {
+		transcript.Language = response.InferredLanguages[0]
+	}
+	for _, chunk := range response.Chunks {
+		if len(chunk.Timestamp) != 2 {
+			continue
+		}
+		transcript.Segments = append(transcript.Segments, Segment{
+			Start: chunk.Timestamp[0],
+			End:   chunk.Timestamp[1],
+			Text:  strings.TrimSpace(chunk.Text),
+		})
+	}
+	return transcript, nil
+}
@@ -0,0 +1,72 @@
+// Package stt turns recorded speech back into text. Providers sit behind the
+// Transcriber interface so they can be swapped, or faked in tests.
+package stt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Transcriber transcribes the audio file at path.
+type Transcriber interface {
+	Transcribe(ctx context.Context, path string) (*Transcript, error)
+}
+
+// Transcript is what a Transcriber heard.
+type Transcript struct {
+	Text string `json:"text"`
+	// Language is the detected language code, if the provider reports one
+	Language string `json:"language,omitempty"`
+	// Segments are timed pieces of Text, if the provider reports them
+	Segments []Segment `json:"segments,omitempty"`
+}
+
+// Segment is a stretch of the transcript with its position in seconds.
+type Segment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// ErrNotConfigured is returned by Default when no provider is set up.
+var ErrNotConfigured = errors.New("speech-to-text is not configured")
+
+var (
+	defaultTranscriber Transcriber
+	defaultErr         error
+	defaultOnce        sync.Once
+)
+
+// Default returns the transcriber chosen by STT_PROVIDER: "fal" (the default
+// when FAL_KEY is set), "whispercpp" for the whisper.cpp server at
+// WHISPER_CPP_URL, or "fake", which answers every file with STT_FAKE_TEXT
+// and is meant for local development.
+func Default() (Transcriber, error) {
+	defaultOnce.Do(func() {
+		provider := os.Getenv("STT_PROVIDER")
+		if provider == "" && os.Getenv("FAL_KEY") != "" {
+			provider = "fal"
+		}
+
+		switch provider {
+		case "fal":
+			defaultTranscriber = NewFal(os.Getenv("FAL_KEY"))
+		case "whispercpp":
+			if url := os.Getenv("WHISPER_CPP_URL"); url != "" {
+				defaultTranscriber = NewWhisperCpp(url)
+			} else {
+				defaultErr = fmt.Errorf("STT_PROVIDER is whispercpp but WHISPER_CPP_URL is not set")
+			}
+		case "fake":
+			defaultTranscriber = &Fake{Text: os.Getenv("STT_FAKE_TEXT")}
+		case "":
+			defaultErr = ErrNotConfigured
+		default:
+			defaultErr = fmt.Errorf("unknown STT_PROVIDER %q", provider)
+		}
+	})
+	return defaultTranscriber, defaultErr
+}
@@ -0,0 +1,112 @@
+package stt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+)
+
+// whisperSampleRate is the only rate whisper.cpp's server reads without
+// being started with --convert
+const whisperSampleRate = 16000
+
+// WhisperCpp transcribes with a whisper.cpp server (examples/server) on the
+// local network, so audio never leaves the machine.
+type WhisperCpp struct {
+	BaseURL string
+	Timeout time.Duration
+}
+
+func NewWhisperCpp(baseURL string) *WhisperCpp {
+	return &WhisperCpp{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Timeout: 5 * time.Minute,
+	}
+}
+
+type whisperCppResponse struct {
+	Text     string `json:"text"`
+	Language string `json:"language"`
+	Segments []struct {
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+		Text  string  `json:"text"`
+	} `json:"segments"`
+}
+
+func (c *WhisperCpp) Transcribe(ctx context.Context, path string) (*Transcript, error) {
+	if c.BaseURL == "" {
+		return nil, ErrNotConfigured
+	}
+
+	samples, err := audio.DecodeSamples(ctx, path, whisperSampleRate)
+	if err != nil {
+		return nil, err
+	}
+	pcm := make([]byte, 2*len(samples))
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(pcm[2*i:], uint16(int16(sample*32767)))
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "audio.wav")
+	if err == nil {
+		part.Write(audio.WAVHeader(whisperSampleRate, 1, uint32(len(pcm))))
+		part.Write(pcm)
+		err = form.WriteField("response_format", "verbose_json")
+	}
+	if err == nil {
+		err = form.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error building request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/inference", &body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("transcription failed with status code %d: %s", resp.StatusCode, respBody)
+	}
+
+	var response whisperCppResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response: %w", err)
+	}
+
+	transcript := &Transcript{Text: strings.TrimSpace(response.Text), Language: response.Language}
+	for _, segment := range response.Segments {
+		transcript.Segments = append(transcript.Segments, Segment{
+			Start: segment.Start,
+			End:   segment.End,
+			Text:  strings.TrimSpace(segment.Text),
+		})
+	}
+	return transcript, nil
+}
@@ -0,0 +1,190 @@
+package watermark
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os/exec"
+	"testing"
+)
+
+const testID = "0123abcd-4567-89ef-0123-456789abcdef"
+
+// speechLike is seconds of noise shaped like speech: a few syllables a
+// second with short pauses between words, at a conversational level
+func speechLike(seconds float64) []float32 {
+	rng := rand.New(rand.NewSource(1))
+	samples := make([]float32, int(seconds*SampleRate))
+	previous := 0.0
+	for i := range samples {
+		t := float64(i) / SampleRate
+		envelope := 0.5 + 0.5*math.Sin(2*math.Pi*4*t)
+		if math.Mod(t, 1.3) > 1.1 {
+			envelope = 0
+		}
+		// A gentle low-pass tilts the spectrum down like a voice
+		previous = 0.6*previous + 0.4*rng.NormFloat64()
+		samples[i] = float32(0.1 * envelope * previous)
+	}
+	return samples
+}
+
+func embedTestMark(t *testing.T, samples []float32) ([]float32, Payload) {
+	t.Helper()
+	p, err := PayloadFromID(testID)
+	if err != nil {
+		t.Fatalf("PayloadFromID: %v", err)
+	}
+	marked, err := Embed(samples, p, DefaultStrength)
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	return marked, p
+}
+
+// quantize rounds samples to 16 bits, as writing a WAV does
+func quantize(samples []float32) []float32 {
+	out := make([]float32, len(samples))
+	for i, s := range samples {
+		out[i] = float32(math.Round(float64(s)*32768) / 32768)
+	}
+	return out
+}
+
+func assertFound(t *testing.T, samples []float32, p Payload) Result {
+	t.Helper()
+	result := Detect(samples)
+	if !result.Found || result.Payload != p {
+		t.Fatalf("Detect = %v, want watermark %s", result, p)
+	}
+	return result
+}
+
+func TestRoundTrip(t *testing.T) {
+	marked, p := embedTestMark(t, speechLike(5))
+	result := assertFound(t, quantize(marked), p)
+	if result.Prefix != "0123abcd" {
+		t.Errorf("Prefix = %q, want the first group of the ID", result.Prefix)
+	}
+}
+
+func TestSurvivesGain(t *testing.T) {
+	marked, p := embedTestMark(t, speechLike(5))
+	for _, gain := range []float32{0.25, 3} {
+		scaled := make([]float32, len(marked))
+		for i, s := range marked {
+			scaled[i] = s * gain
+		}
+		assertFound(t, quantize(scaled), p)
+	}
+}
+
+func TestSurvivesOffset(t *testing.T) {
+	marked, p := embedTestMark(t, speechLike(6))
+
+	// Trimmed from the start, off the frame grid
+	trimmed := marked[777:]
+	assertFound(t, trimmed, p)
+
+	// Delayed by encoder padding
+	padded := append(make([]float32, 1105), marked...)
+	assertFound(t, padded, p)
+}
+
+func TestSurvivesMP3(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg is not installed")
+	}
+	marked, p := embedTestMark(t, speechLike(6))
+	for _, bitrate := range []string{"128k", "64k"} {
+		t.Run(bitrate, func(t *testing.T) {
+			assertFound(t, throughMP3(t, marked, bitrate), p)
+		})
+	}
+}
+
+// throughMP3 encodes samples to MP3 at bitrate and decodes them again, the
+// way a shared clip usually travels
+func throughMP3(t *testing.T, samples []float32, bitrate string) []float32 {
+	t.Helper()
+	pcm := new(bytes.Buffer)
+	for _, s := range samples {
+		binary.Write(pcm, binary.LittleEndian, int16(max(-1, min(1, s))*32767))
+	}
+	rate := fmt.Sprint(SampleRate)
+
+	encode := exec.Command("ffmpeg", "-v", "error", "-f", "s16le", "-ar", rate, "-ac", "1", "-i", "pipe:0",
+		"-c:a", "libmp3lame", "-b:a", bitrate, "-f", "mp3", "pipe:1")
+	encode.Stdin = pcm
+	mp3, err := encode.Output()
+	if err != nil {
+		t.Fatalf("encoding mp3: %v", err)
+	}
+
+	decode := exec.Command("ffmpeg", "-v", "error", "-f", "mp3", "-i", "pipe:0",
+		"-f", "s16le", "-ar", rate, "-ac", "1", "pipe:1")
+	decode.Stdin = bytes.NewReader(mp3)
+	raw, err := decode.Output()
+	if err != nil {
+		t.Fatalf("decoding mp3: %v", err)
+	}
+
+	decoded := make([]float32, len(raw)/2)
+	for i := range decoded {
+		decoded[i] = float32(int16(binary.LittleEndian.Uint16(raw[2*i:]))) / 32768
+	}
+	return decoded
+}
+
+func TestCorruptedChecksumIsRejected(t *testing.T) {
+	samples := speechLike(5)
+	p, err := PayloadFromID(testID)
+	if err != nil {
+		t.Fatalf("PayloadFromID: %v", err)
+	}
+
+	// A strong, cleanly decodable mark whose checksum doesn't match its ID
+	signs := payloadSigns(p)
+	signs[payloadBits-1] = -signs[payloadBits-1]
+	delta, err := signsDelta(samples, signs, DefaultStrength)
+	if err != nil {
+		t.Fatalf("signsDelta: %v", err)
+	}
+	for i := range samples {
+		samples[i] += delta[i]
+	}
+
+	result := Detect(samples)
+	if result.Found {
+		t.Fatalf("Detect = %v, want nothing found for a bad checksum", result)
+	}
+	// The bits themselves came through; only the checksum turned them down
+	if result.Confidence < minConfidence {
+		t.Fatalf("confidence %.2f, want the mark itself to be clear", result.Confidence)
+	}
+}
+
+func TestUnmarkedAudio(t *testing.T) {
+	if result := Detect(speechLike(5)); result.Found {
+		t.Fatalf("Detect = %v on unmarked audio", result)
+	}
+}
+
+func TestTooShort(t *testing.T) {
+	p, err := PayloadFromID(testID)
+	if err != nil {
+		t.Fatalf("PayloadFromID: %v", err)
+	}
+	if _, err := Embed(speechLike(1), p, DefaultStrength); !errors.Is(err, ErrTooShort) {
+		t.Fatalf("Embed on 1 second = %v, want ErrTooShort", err)
+	}
+}
+
+func TestPayloadFromInvalidID(t *testing.T) {
+	if _, err := PayloadFromID("not-an-id"); !errors.Is(err, ErrInvalidID) {
+		t.Fatalf("PayloadFromID = %v, want ErrInvalidID", err)
+	}
+}
@@ -0,0 +1,56 @@
+package watermark
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+)
+
+// ErrUnsupportedFormat is returned by EmbedWAV for anything but 16-bit PCM
+// WAV at SampleRate; convert with audio.Normalize first.
+var ErrUnsupportedFormat = fmt.Errorf("watermarking needs 16-bit PCM WAV at %d Hz", SampleRate)
+
+// EmbedWAV returns a copy of a 16-bit PCM WAV file with p embedded. Every
+// channel gets the mark computed from their mix, and everything outside the
+// data chunk (including tags) is kept as is.
+func EmbedWAV(data []byte, p Payload, strength float64) ([]byte, error) {
+	layout, err := audio.ParseWAV(data)
+	if err != nil || layout.SampleRate != SampleRate {
+		return nil, ErrUnsupportedFormat
+	}
+
+	pcm := data[layout.DataOffset : layout.DataOffset+layout.DataSize]
+	frames := len(pcm) / (2 * layout.Channels)
+	mix := make([]float32, frames)
+	for i := range mix {
+		sum := 0.0
+		for c := 0; c < layout.Channels; c++ {
+			sum += sample(pcm, i*layout.Channels+c)
+		}
+		mix[i] = float32(sum / float64(layout.Channels))
+	}
+
+	delta, err := Delta(mix, p, strength)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, len(data))
+	copy(out, data)
+	outPCM := out[layout.DataOffset : layout.DataOffset+layout.DataSize]
+	for i, d := range delta {
+		for c := 0; c < layout.Channels; c++ {
+			index := i*layout.Channels + c
+			value := math.Round((sample(pcm, index) + float64(d)) * 32768)
+			value = math.Max(math.MinInt16, math.Min(math.MaxInt16, value))
+			binary.LittleEndian.PutUint16(outPCM[2*index:], uint16(int16(value)))
+		}
+	}
+	return out, nil
+}
+
+func sample(pcm []byte, index int) float64 {
+	return float64(int16(binary.LittleEndian.Uint16(pcm[2*index:]))) / 32768
+}
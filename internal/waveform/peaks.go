@@ -0,0 +1,50 @@
+// Package waveform draws audio for people to look at: peak envelopes for
+// waveform displays and spectrogram images. Both work on decoded mono PCM
+// samples in pure Go.
+package waveform
+
+// DefaultBuckets is how many peaks are computed for a waveform display;
+// enough for a full-width player on a large screen.
+const DefaultBuckets = 1000
+
+// Peaks is the envelope of a recording: the lowest and highest sample in
+// each of a run of equally long buckets, from -1 to 1.
+type Peaks struct {
+	Duration float64   `json:"duration"`
+	Min      []float32 `json:"min"`
+	Max      []float32 `json:"max"`
+}
+
+// ComputePeaks splits samples into at most buckets buckets and returns their
+// envelope. Recordings shorter than buckets samples get one per sample.
+func ComputePeaks(samples []float32, sampleRate, buckets int) Peaks {
+	peaks := Peaks{Min: []float32{}, Max: []float32{}}
+	if sampleRate > 0 {
+		peaks.Duration = float64(len(samples)) / float64(sampleRate)
+	}
+	buckets = min(buckets, len(samples))
+	if buckets <= 0 {
+		return peaks
+	}
+
+	peaks.Min = make([]float32, buckets)
+	peaks.Max = make([]float32, buckets)
+	for i := range buckets {
+		// Spread the remainder so every sample lands in a bucket
+		start, end := i*len(samples)/buckets, (i+1)*len(samples)/buckets
+		low, high := samples[start], samples[start]
+		for _, s := range samples[start:end] {
+			low, high = min(low, s), max(high, s)
+		}
+		peaks.Min[i], peaks.Max[i] = round(low), round(high)
+	}
+	return peaks
+}
+
+// round keeps three decimals, all a display can show, to keep the JSON small
+func round(v float32) float32 {
+	if v < 0 {
+		return -float32(int(-v*1000+0.5)) / 1000
+	}
+	return float32(int(v*1000+0.5)) / 1000
+}
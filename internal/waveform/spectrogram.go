@@ -0,0 +1,90 @@
+package waveform
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/cmplx"
+
+	"github.com/henrik392/youtube-voice-go/internal/dsp"
+)
+
+const (
+	// fftSize is the analysis window; at 16 kHz it resolves 31 Hz
+	fftSize = 512
+	// dynamicRange is how far below the loudest bin the colours reach, in dB
+	dynamicRange = 80
+)
+
+// colormap runs from silence to the loudest bin: black through purple, red
+// and orange to pale yellow
+var colormap = []color.RGBA{
+	{0, 0, 4, 255},
+	{87, 16, 110, 255},
+	{188, 55, 84, 255},
+	{249, 142, 9, 255},
+	{252, 255, 164, 255},
+}
+
+// Spectrogram draws a width × height image of how the frequencies in
+// samples change over time: time runs left to right, frequency from 0 at the
+// bottom to half the sample rate at the top, louder is brighter.
+func Spectrogram(samples []float32, width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if width <= 0 || height <= 0 {
+		return img
+	}
+
+	// One windowed frame per column, centred on the column's time
+	window := make([]float64, fftSize)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(fftSize-1))
+	}
+	bins := fftSize / 2
+	levels := make([][]float64, width)
+	loudest := math.Inf(-1)
+	frame := make([]complex128, fftSize)
+	for x := range levels {
+		centre := int((float64(x) + 0.5) * float64(len(samples)) / float64(width))
+		for i := range frame {
+			sample := 0.0
+			if j := centre - fftSize/2 + i; j >= 0 && j < len(samples) {
+				sample = float64(samples[j])
+			}
+			frame[i] = complex(sample*window[i], 0)
+		}
+		dsp.FFT(frame)
+
+		levels[x] = make([]float64, bins)
+		for bin := range levels[x] {
+			levels[x][bin] = 20 * math.Log10(cmplx.Abs(frame[bin])+1e-9)
+			loudest = max(loudest, levels[x][bin])
+		}
+	}
+
+	for x, column := range levels {
+		for y := range height {
+			// Rows can cover several bins or share one; take the loudest
+			low := (height - 1 - y) * bins / height
+			high := max((height-y)*bins/height, low+1)
+			level := math.Inf(-1)
+			for _, l := range column[low:min(high, bins)] {
+				level = max(level, l)
+			}
+			img.SetRGBA(x, y, colorAt((level-loudest+dynamicRange)/dynamicRange))
+		}
+	}
+	return img
+}
+
+// colorAt interpolates the colormap at v, from 0 to 1
+func colorAt(v float64) color.RGBA {
+	v = min(max(v, 0), 1) * float64(len(colormap)-1)
+	i := min(int(v), len(colormap)-2)
+	f := v - float64(i)
+	a, b := colormap[i], colormap[i+1]
+	mix := func(p, q uint8) uint8 {
+		return uint8(float64(p) + (float64(q)-float64(p))*f + 0.5)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
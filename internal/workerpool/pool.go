@@ -0,0 +1,220 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrSaturated is returned by Acquire when the pool cannot take more work,
+// either because the queue is full or because the maximum wait was exceeded.
+var ErrSaturated = errors.New("worker pool saturated")
+
+// SaturatedError carries the pool name and a suggested retry delay.
+type SaturatedError struct {
+	Pool       string
+	RetryAfter time.Duration
+}
+
+func (e *SaturatedError) Error() string {
+	return fmt.Sprintf("%s pool saturated, retry after %s", e.Pool, e.RetryAfter)
+}
+
+func (e *SaturatedError) Unwrap() error {
+	return ErrSaturated
+}
+
+// Pool limits how many subprocesses of one kind may run at the same time.
+// Callers beyond the limit wait in a bounded queue for at most MaxWait.
+type Pool struct {
+	Name     string
+	Size     int
+	MaxQueue int
+	MaxWait  time.Duration
+
+	slots chan struct{}
+
+	mu        sync.Mutex
+	queued    int
+	acquired  uint64
+	rejected  uint64
+	waitTotal time.Duration
+	waitMax   time.Duration
+}
+
+// Stats is a point-in-time snapshot of a pool.
+type Stats struct {
+	Name      string
+	Size      int
+	MaxQueue  int
+	InFlight  int
+	Queued    int
+	Acquired  uint64
+	Rejected  uint64
+	WaitTotal time.Duration
+	WaitMax   time.Duration
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []*Pool
+)
+
+func New(name string, size, maxQueue int, maxWait time.Duration) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	if maxQueue < 0 {
+		maxQueue = 0
+	}
+
+	p := &Pool{
+		Name:     name,
+		Size:     size,
+		MaxQueue: maxQueue,
+		MaxWait:  maxWait,
+		slots:    make(chan struct{}, size),
+	}
+
+	registryMu.Lock()
+	registry = append(registry, p)
+	registryMu.Unlock()
+
+	return p
+}
+
+// NewFromEnv creates a pool configured by <prefix>_MAX_CONCURRENCY,
+// <prefix>_MAX_QUEUE and <prefix>_MAX_WAIT, falling back to the given defaults.
+func NewFromEnv(name, prefix string, size, maxQueue int, maxWait time.Duration) *Pool {
+	if v := os.Getenv(prefix + "_MAX_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			size = n
+		} else {
+			log.Printf("Invalid %s_MAX_CONCURRENCY %q: %v", prefix, v, err)
+		}
+	}
+	if v := os.Getenv(prefix + "_MAX_QUEUE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			maxQueue = n
+		} else {
+			log.Printf("Invalid %s_MAX_QUEUE %q: %v", prefix, v, err)
+		}
+	}
+	if v := os.Getenv(prefix + "_MAX_WAIT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			maxWait = d
+		} else {
+			log.Printf("Invalid %s_MAX_WAIT %q: %v", prefix, v, err)
+		}
+	}
+
+	log.Printf("Worker pool %s: concurrency=%d queue=%d max_wait=%s", name, size, maxQueue, maxWait)
+	return New(name, size, maxQueue, maxWait)
+}
+
+// Acquire blocks until a slot is free and returns a function that releases it.
+// It fails fast with a *SaturatedError when the queue is full, and gives up
+// with the same error once MaxWait has passed. Context cancellation is returned as is.
+func (p *Pool) Acquire(ctx context.Context) (func(), error) {
+	start := time.Now()
+
+	// Fast path: a slot is free right now
+	select {
+	case p.slots <- struct{}{}:
+		p.recordAcquire(0)
+		return p.release, nil
+	default:
+	}
+
+	p.mu.Lock()
+	if p.queued >= p.MaxQueue {
+		p.rejected++
+		p.mu.Unlock()
+		return nil, p.saturated()
+	}
+	p.queued++
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.queued--
+		p.mu.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if p.MaxWait > 0 {
+		timer := time.NewTimer(p.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		p.recordAcquire(time.Since(start))
+		return p.release, nil
+	case <-timeout:
+		p.mu.Lock()
+		p.rejected++
+		p.mu.Unlock()
+		return nil, p.saturated()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *Pool) release() {
+	<-p.slots
+}
+
+func (p *Pool) recordAcquire(wait time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.acquired++
+	p.waitTotal += wait
+	if wait > p.waitMax {
+		p.waitMax = wait
+	}
+}
+
+func (p *Pool) saturated() error {
+	retryAfter := p.MaxWait
+	if retryAfter < time.Second {
+		retryAfter = 5 * time.Second
+	}
+	return &SaturatedError{Pool: p.Name, RetryAfter: retryAfter}
+}
+
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return Stats{
+		Name:      p.Name,
+		Size:      p.Size,
+		MaxQueue:  p.MaxQueue,
+		InFlight:  len(p.slots),
+		Queued:    p.queued,
+		Acquired:  p.acquired,
+		Rejected:  p.rejected,
+		WaitTotal: p.waitTotal,
+		WaitMax:   p.waitMax,
+	}
+}
+
+// All returns the stats of every pool created so far.
+func All() []Stats {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	stats := make([]Stats, 0, len(registry))
+	for _, p := range registry {
+		stats = append(stats, p.Stats())
+	}
+	return stats
+}
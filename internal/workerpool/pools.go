@@ -0,0 +1,14 @@
+package workerpool
+
+import (
+	"sync"
+	"time"
+)
+
+// Shared pools for the external tools we shell out to. Every exec of yt-dlp or
+// ffmpeg must hold a slot from the matching pool while the process runs. They
+// are created, and their settings read from the environment, on first use.
+var (
+	YtDlp  = sync.OnceValue(func() *Pool { return NewFromEnv("yt-dlp", "YTDLP", 2, 8, 30*time.Second) })
+	FFmpeg = sync.OnceValue(func() *Pool { return NewFromEnv("ffmpeg", "FFMPEG", 4, 16, 30*time.Second) })
+)
@@ -0,0 +1,61 @@
+package youtube
+
+import "strings"
+
+// Phrases yt-dlp prints when a platform refuses to serve our IP address.
+var botDetectionMarkers = []string{
+	"sign in to confirm you're not a bot",
+	"sign in to confirm you’re not a bot",
+	"http error 429",
+	"too many requests",
+	"rate-limit reached",
+	"your ip address is blocked",
+	"this content isn't available, try again later",
+	"please log in to continue",
+}
+
+// Phrases that point at the proxy itself rather than the platform or the video.
+var proxyErrorMarkers = []string{
+	"unable to connect to proxy",
+	"proxyerror",
+	"tunnel connection failed",
+	"socks",
+	"connection refused",
+	"connection reset by peer",
+	"timed out",
+}
+
+// Phrases about one particular video; they say nothing about whether the platform works.
+var contentErrorMarkers = []string{
+	"video unavailable",
+	"private video",
+	"has been removed",
+	"not available in your country",
+	"members-only",
+	"this post is unavailable",
+	"unsupported url",
+}
+
+// IsBotDetection reports whether yt-dlp output shows the platform blocked the request.
+func IsBotDetection(output string) bool {
+	return containsAny(strings.ToLower(output), botDetectionMarkers)
+}
+
+// isProxyError reports whether yt-dlp output shows the proxy could not be used.
+func isProxyError(output string) bool {
+	return containsAny(strings.ToLower(output), proxyErrorMarkers)
+}
+
+// isContentError reports whether yt-dlp output blames the video rather than the platform.
+func isContentError(output string) bool {
+	return containsAny(strings.ToLower(output), contentErrorMarkers)
+}
+
+func containsAny(s string, markers []string) bool {
+	for _, marker := range markers {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,25 +1,51 @@
 package youtube
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/henrik392/youtube-voice-go/internal/cookies"
+	"github.com/henrik392/youtube-voice-go/internal/platformstatus"
+	"github.com/henrik392/youtube-voice-go/internal/proxypool"
+	"github.com/henrik392/youtube-voice-go/internal/workerpool"
 )
 
 type Processor struct {
 	OutputDir string
+	Cookies   *cookies.Store
+	Proxies   *proxypool.Pool
+	// Status, when set, is told about every download so the UI can warn about broken platforms
+	Status *platformstatus.Tracker
+
+	// MaxProxyAttempts is how many proxies a download may go through before giving up
+	MaxProxyAttempts int
+
+	// DownloadTimeout bounds a single yt-dlp run, not counting time spent queued for a slot
+	DownloadTimeout time.Duration
 }
 
 func NewProcessor(outputDir string) *Processor {
 	return &Processor{
-		OutputDir: outputDir,
+		OutputDir:        outputDir,
+		Cookies:          cookies.Default(),
+		Proxies:          proxypool.Default(Platforms),
+		Status:           platformstatus.Default(),
+		MaxProxyAttempts: 3,
+		DownloadTimeout:  3 * time.Minute,
 	}
 }
 
-func (p *Processor) DownloadAudio(url, videoID string) (string, error) {
+// DownloadAudio downloads and extracts the audio of url into OutputDir.
+// If ctx is cancelled or the download times out, yt-dlp is killed and any
+// partial files for videoID are removed.
+func (p *Processor) DownloadAudio(ctx context.Context, url, videoID string) (string, error) {
 	const EXT = "mp3"
 	outputFile := fmt.Sprintf("%s/%s.%s", p.OutputDir, videoID, EXT)
 
@@ -44,17 +70,6 @@ func (p *Processor) DownloadAudio(url, videoID string) (string, error) {
 	}
 	log.Printf("DownloadAudio: Found ffmpeg at: %s", ffmpegPath)
 
-	// Setup cookies
-	cookiesPath, err := p.setupCookies()
-	if err != nil {
-		return "", fmt.Errorf("failed to setup cookies: %v", err)
-	}
-	defer func() {
-		if cookiesPath != "" {
-			os.Remove(cookiesPath)
-		}
-	}()
-
 	args := []string{
 		"-x",
 		"--audio-format", "mp3",
@@ -62,25 +77,13 @@ func (p *Processor) DownloadAudio(url, videoID string) (string, error) {
 		"--postprocessor-args", "ffmpeg:-t 180", // Limit to max 3 minutes
 	}
 
-	if cookiesPath != "" {
-		args = append(args, "--cookies", cookiesPath)
-	}
-
-	args = append(args, url)
-	cmd := exec.Command("yt-dlp", args...)
-
-	log.Printf("DownloadAudio: Executing command: %s", cmd.String())
-
-	// Capture both stdout and stderr for debugging
-	output, err := cmd.CombinedOutput()
+	output, err := p.runYtDlp(ctx, url, videoID, args)
 	if err != nil {
-		log.Printf("DownloadAudio: Command failed with error: %v", err)
-		log.Printf("DownloadAudio: Command output: %s", string(output))
-		return "", fmt.Errorf("yt-dlp failed: %v (output: %s)", err, string(output))
+		return "", err
 	}
 
 	log.Printf("DownloadAudio: Command completed successfully")
-	log.Printf("DownloadAudio: Output: %s", string(output))
+	log.Printf("DownloadAudio: Output: %s", output)
 
 	// Verify the output file was created
 	if _, err := os.Stat(outputFile); err != nil {
@@ -91,44 +94,158 @@ func (p *Processor) DownloadAudio(url, videoID string) (string, error) {
 	return outputFile, nil
 }
 
-// setupCookies creates a temporary cookies file from environment variable or uses local file
-func (p *Processor) setupCookies() (string, error) {
-	// Check for cookies in environment variable first
-	cookiesEnv := os.Getenv("YOUTUBE_COOKIES")
-	if cookiesEnv != "" {
-		log.Printf("Using cookies from environment variable")
-		
-		// Create temporary file
-		tmpFile, err := os.CreateTemp("", "cookies_*.txt")
-		if err != nil {
-			return "", fmt.Errorf("failed to create temporary cookies file: %v", err)
+// Probe asks yt-dlp for the metadata of url without downloading anything.
+// It goes through the same cookies and proxies as a real download.
+func (p *Processor) Probe(ctx context.Context, url string) error {
+	_, err := p.runYtDlp(ctx, url, "", []string{"--skip-download", "--no-playlist"})
+	return err
+}
+
+// runYtDlp runs yt-dlp on url with cookies and, if configured, a proxy for the
+// URL's platform. When the platform flags the proxy as a bot or the proxy
+// itself fails, the download is retried through another proxy.
+func (p *Processor) runYtDlp(ctx context.Context, url, videoID string, args []string) (string, error) {
+	output, err := p.runYtDlpAttempts(ctx, url, videoID, args)
+	p.recordStatus(ctx, DetectPlatform(url), output, err)
+	return output, err
+}
+
+// recordStatus feeds the outcome of a download to the platform tracker. Errors
+// about one particular video (private, removed, ...) say nothing about the platform.
+func (p *Processor) recordStatus(ctx context.Context, platform, output string, err error) {
+	if p.Status == nil || ctx.Err() != nil {
+		return
+	}
+
+	var saturated *workerpool.SaturatedError
+	switch {
+	case err == nil:
+		p.Status.RecordSuccess(platform, platformstatus.SourceUser)
+	case errors.As(err, &saturated), isContentError(err.Error()):
+	default:
+		p.Status.RecordFailure(platform, platformstatus.SourceUser, lastLine(err.Error()))
+	}
+}
+
+func (p *Processor) runYtDlpAttempts(ctx context.Context, url, videoID string, args []string) (string, error) {
+	// Setup cookies for the platform the URL belongs to
+	platform := DetectPlatform(url)
+	cookiesPath, err := p.Cookies.WriteTempFile(platform)
+	if err != nil {
+		return "", fmt.Errorf("failed to setup cookies: %v", err)
+	}
+	if cookiesPath == "" {
+		log.Printf("DownloadAudio: No %s cookies found - proceeding without authentication", platform)
+	} else {
+		defer os.Remove(cookiesPath)
+		args = append(args, "--cookies", cookiesPath)
+	}
+
+	// Wait for a free yt-dlp slot so bursts of traffic don't fork unbounded processes
+	release, err := workerpool.YtDlp().Acquire(ctx)
+	if err != nil {
+		return "", fmt.Errorf("waiting for yt-dlp slot: %w", err)
+	}
+	defer release()
+
+	useProxies := p.Proxies != nil && p.Proxies.HasProxies(platform)
+	tried := map[*proxypool.Proxy]bool{}
+	var lastErr error
+
+	for attempt := 1; ; attempt++ {
+		var proxy *proxypool.Proxy
+		if useProxies {
+			proxy = p.Proxies.Next(platform, tried)
+			if proxy == nil {
+				if lastErr != nil {
+					return "", lastErr
+				}
+				return "", fmt.Errorf("no healthy %s proxy available, all are cooling down", platform)
+			}
+			tried[proxy] = true
+		}
+
+		output, timedOut, err := p.execYtDlp(ctx, args, proxy, url)
+		if err == nil {
+			if proxy != nil {
+				p.Proxies.ReportSuccess(proxy)
+			}
+			return output, nil
 		}
-		defer tmpFile.Close()
-
-		// Clean up the cookies string (remove quotes and handle line endings)
-		cleanedCookies := strings.Trim(cookiesEnv, `"`)
-		cleanedCookies = strings.ReplaceAll(cleanedCookies, `\n`, "\n")
-		
-		// Write cookies to temporary file
-		if _, err := tmpFile.WriteString(cleanedCookies); err != nil {
-			os.Remove(tmpFile.Name())
-			return "", fmt.Errorf("failed to write cookies to temporary file: %v", err)
+
+		p.removePartialFiles(videoID)
+		if ctx.Err() != nil {
+			log.Printf("DownloadAudio: Cancelled: %v", ctx.Err())
+			return "", fmt.Errorf("yt-dlp stopped: %w", ctx.Err())
 		}
 
-		return tmpFile.Name(), nil
-	}
+		log.Printf("DownloadAudio: Command failed with error: %v", err)
+		log.Printf("DownloadAudio: Command output: %s", output)
+		lastErr = fmt.Errorf("yt-dlp failed: %v (output: %s)", err, strings.TrimSpace(output))
 
-	// Fallback to local cookies.txt file
-	cookiesFile := "cookies.txt"
-	if _, err := os.Stat(cookiesFile); err == nil {
-		log.Printf("Using local cookies file: %s", cookiesFile)
-		absPath, err := filepath.Abs(cookiesFile)
-		if err != nil {
-			return "", fmt.Errorf("failed to get absolute path for cookies file: %v", err)
+		if proxy == nil {
+			return "", lastErr
 		}
-		return absPath, nil
+
+		botDetected := IsBotDetection(output)
+		retryable := botDetected || timedOut || isProxyError(output)
+		p.Proxies.ReportFailure(proxy, botDetected, lastLine(output))
+
+		if !retryable || attempt >= p.MaxProxyAttempts {
+			return "", lastErr
+		}
+		log.Printf("DownloadAudio: Attempt %d through %s failed, retrying with another proxy", attempt, proxy.Redacted())
+	}
+}
+
+// execYtDlp runs one yt-dlp attempt, bounded by DownloadTimeout. timedOut is
+// true when that deadline, not the caller's context, stopped the process.
+func (p *Processor) execYtDlp(ctx context.Context, args []string, proxy *proxypool.Proxy, url string) (output string, timedOut bool, err error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, p.DownloadTimeout)
+	defer cancel()
+
+	if proxy != nil {
+		args = append(args, "--proxy", proxy.URL)
+	}
+	args = append(args, url)
+
+	cmd := exec.CommandContext(attemptCtx, "yt-dlp", args...)
+	cmd.WaitDelay = 5 * time.Second
+
+	if proxy != nil {
+		log.Printf("DownloadAudio: Executing yt-dlp through proxy %s", proxy.Redacted())
+	} else {
+		log.Printf("DownloadAudio: Executing command: %s", cmd.String())
 	}
 
-	log.Printf("No cookies found - proceeding without authentication")
-	return "", nil
+	// Capture both stdout and stderr for debugging
+	out, err := cmd.CombinedOutput()
+	timedOut = err != nil && ctx.Err() == nil && attemptCtx.Err() != nil
+	return string(out), timedOut, err
+}
+
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	line := lines[len(lines)-1]
+	if len(line) > 200 {
+		line = line[:200]
+	}
+	return line
+}
+
+// removePartialFiles deletes whatever yt-dlp left behind for videoID after a failed run
+// (.part downloads, the unconverted source and a half-written mp3).
+func (p *Processor) removePartialFiles(videoID string) {
+	if videoID == "" {
+		return
+	}
+	matches, err := filepath.Glob(filepath.Join(p.OutputDir, videoID+".*"))
+	if err != nil {
+		return
+	}
+	for _, match := range matches {
+		if err := os.Remove(match); err == nil {
+			log.Printf("DownloadAudio: Removed partial file: %s", match)
+		}
+	}
 }
@@ -0,0 +1,58 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// VideoSuffix tells downloaded videos apart from the audio of the same ID.
+// It must not start with a dot, or cleaning up after a failed audio download
+// (which removes <videoID>.*) would take the video with it.
+const VideoSuffix = "-video"
+
+// DownloadVideo downloads url with picture and sound as mp4 into OutputDir,
+// capped at 720p and the first 3 minutes like DownloadAudio. The file is
+// kept next to the audio under <videoID>-video.mp4.
+func (p *Processor) DownloadVideo(ctx context.Context, url, videoID string) (string, error) {
+	name := videoID + VideoSuffix
+	outputFile := filepath.Join(p.OutputDir, name+".mp4")
+
+	log.Printf("DownloadVideo: Starting download for URL: %s, VideoID: %s, OutputFile: %s", url, videoID, outputFile)
+
+	if _, err := os.Stat(outputFile); err == nil {
+		log.Printf("DownloadVideo: File already exists: %s", outputFile)
+		return outputFile, nil
+	}
+
+	// yt-dlp needs ffmpeg to merge the separate video and audio streams
+	if _, err := exec.LookPath("yt-dlp"); err != nil {
+		return "", fmt.Errorf("yt-dlp not found in PATH: %v", err)
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return "", fmt.Errorf("ffmpeg not found in PATH: %v", err)
+	}
+
+	args := []string{
+		"-f", "bv*[height<=720]+ba/b[height<=720]/b",
+		"--merge-output-format", "mp4",
+		"--no-playlist",
+		"-o", outputFile,
+		"--download-sections", "*0-180", // Limit to max 3 minutes
+		"--force-keyframes-at-cuts",
+	}
+
+	if _, err := p.runYtDlp(ctx, url, name, args); err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(outputFile); err != nil {
+		return "", fmt.Errorf("output file not created: %s (error: %v)", outputFile, err)
+	}
+
+	log.Printf("DownloadVideo: Successfully downloaded to: %s", outputFile)
+	return outputFile, nil
+}
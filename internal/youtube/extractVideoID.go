@@ -4,31 +4,59 @@ import (
 	"regexp"
 )
 
+const (
+	PlatformYouTube   = "youtube"
+	PlatformTikTok    = "tiktok"
+	PlatformInstagram = "instagram"
+)
+
+// Platforms lists every platform we can download from.
+var Platforms = []string{PlatformYouTube, PlatformTikTok, PlatformInstagram}
+
+var patterns = map[string][]*regexp.Regexp{
+	PlatformYouTube: {
+		regexp.MustCompile(`(?:youtube\.com\/(?:[^\/]+\/.+\/|(?:v|e(?:mbed)?)\/|.*[?&]v=)|youtu\.be\/)([^"&?\/\s]{11})`),
+		regexp.MustCompile(`(?:youtube\.com\/shorts\/)([^"&?\/\s]{11})`),
+	},
+	PlatformTikTok: {
+		regexp.MustCompile(`tiktok\.com\/(?:@[\w.-]+\/video\/|v\/)(\d+)`),
+		regexp.MustCompile(`vm\.tiktok\.com\/(\w+)`),
+	},
+	PlatformInstagram: {
+		regexp.MustCompile(`instagram\.com\/(?:reels?|p)\/([A-Za-z0-9_-]+)`),
+	},
+}
+
 func ExtractVideoID(url string) string {
-	patterns := map[string][]string{
-		"YouTube": {
-			`(?:youtube\.com\/(?:[^\/]+\/.+\/|(?:v|e(?:mbed)?)\/|.*[?&]v=)|youtu\.be\/)([^"&?\/\s]{11})`,
-			`(?:youtube\.com\/shorts\/)([^"&?\/\s]{11})`,
-		},
-		"TikTok": {
-			`tiktok\.com\/(?:@[\w.-]+\/video\/|v\/)(\d+)`,
-			`vm\.tiktok\.com\/(\w+)`,
-		},
-		"Instagram": {
-			`instagram\.com\/(?:reels?|p)\/([A-Za-z0-9_-]+)`,
-		},
-	}
+	_, videoID := match(url)
+	return videoID
+}
+
+// validVideoID is what the IDs of every platform look like. It keeps an ID
+// safe to put in a file name.
+var validVideoID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidVideoID reports whether id looks like a video ID ExtractVideoID could
+// have returned, for IDs that come back from a client.
+func ValidVideoID(id string) bool {
+	return validVideoID.MatchString(id)
+}
+
+// DetectPlatform returns which platform url belongs to, or "" if it isn't supported.
+func DetectPlatform(url string) string {
+	platform, _ := match(url)
+	return platform
+}
 
-	// Can also check platform
-	for _, platformPatterns := range patterns {
-		for _, pattern := range platformPatterns {
-			re := regexp.MustCompile(pattern)
+func match(url string) (string, string) {
+	for platform, platformPatterns := range patterns {
+		for _, re := range platformPatterns {
 			matches := re.FindStringSubmatch(url)
 			if len(matches) > 1 {
-				return matches[1]
+				return platform, matches[1]
 			}
 		}
 	}
 
-	return ""
+	return "", ""
 }
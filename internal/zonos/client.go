@@ -14,16 +14,48 @@ import (
 	"strings"
 	"time"
 
+	"github.com/henrik392/youtube-voice-go/internal/audio"
+	"github.com/henrik392/youtube-voice-go/internal/stt"
+	"github.com/henrik392/youtube-voice-go/internal/workerpool"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
+// fal models the client can generate with, chosen by FAL_TTS_MODEL
+const (
+	ModelZonos = "fal-ai/zonos"
+	// ModelDia also takes the reference's transcript, and sounds closer to
+	// the reference with it
+	ModelDia = "fal-ai/dia-tts/voice-clone"
+)
+
+// MaxReferenceSeconds is how much of a recording is sent as the reference
+const MaxReferenceSeconds = 30
+
 type Client struct {
 	APIKey     string
 	BaseURL    string
+	Model      string
 	S3Client   *minio.Client
 	S3Bucket   string
 	S3Endpoint string
+
+	// Per-stage deadlines, applied on top of the caller's context
+	CropTimeout     time.Duration
+	UploadTimeout   time.Duration
+	GenerateTimeout time.Duration
+	DownloadTimeout time.Duration
+
+	// Transcriber fills in Reference.Text when a reference is uploaded; nil
+	// leaves it empty
+	Transcriber stt.Transcriber
+}
+
+// Reference is an uploaded reference clip and, if it could be transcribed,
+// what is said in it.
+type Reference struct {
+	URL  string
+	Text string
 }
 
 func NewClient(apiKey string) *Client {
@@ -49,20 +81,54 @@ func NewClient(apiKey string) *Client {
 		s3Client = nil
 	}
 
+	model := os.Getenv("FAL_TTS_MODEL")
+	switch model {
+	case ModelZonos, ModelDia:
+	case "":
+		model = ModelZonos
+	default:
+		log.Printf("Unknown FAL_TTS_MODEL %q, using %s", model, ModelZonos)
+		model = ModelZonos
+	}
+
+	// Transcripts are optional, so an unconfigured transcriber just means none
+	transcriber, err := stt.Default()
+	if err != nil {
+		transcriber = nil
+	}
+
 	return &Client{
-		APIKey:     apiKey,
-		BaseURL:    "https://fal.run/fal-ai/zonos",
-		S3Client:   s3Client,
-		S3Bucket:   s3Bucket,
-		S3Endpoint: s3Endpoint,
+		APIKey:          apiKey,
+		BaseURL:         "https://fal.run/" + model,
+		Model:           model,
+		S3Client:        s3Client,
+		S3Bucket:        s3Bucket,
+		S3Endpoint:      s3Endpoint,
+		CropTimeout:     60 * time.Second,
+		UploadTimeout:   60 * time.Second,
+		GenerateTimeout: 60 * time.Second,
+		DownloadTimeout: 30 * time.Second,
+		Transcriber:     transcriber,
 	}
 }
 
+// AcceptsReferenceText reports whether the model uses Reference.Text.
+func (c *Client) AcceptsReferenceText() bool {
+	return c.Model == ModelDia
+}
+
 type Request struct {
 	Prompt           string `json:"prompt"`
 	ReferenceAudioURL string `json:"reference_audio_url"`
 }
 
+// diaRequest is ModelDia's input. Dia expects speaker tags in both texts.
+type diaRequest struct {
+	Text        string `json:"text"`
+	RefAudioURL string `json:"ref_audio_url"`
+	RefText     string `json:"ref_text,omitempty"`
+}
+
 type Response struct {
 	Audio struct {
 		URL string `json:"url"`
@@ -70,12 +136,37 @@ type Response struct {
 }
 
 
-func (c *Client) VoiceClone(prompt, refAudioFilePath string) ([]byte, error) {
+// VoiceClone crops refAudioFilePath, uploads it to S3 and generates speech from it.
+func (c *Client) VoiceClone(ctx context.Context, prompt, refAudioFilePath string) ([]byte, error) {
 	log.Printf("Starting Zonos voice cloning for file: %s", refAudioFilePath)
 
-	// First crop the audio to 30 seconds and re-encode to reduce size
-	log.Printf("Cropping and compressing audio to 30 seconds...")
-	croppedFilePath, err := c.CropAndCompressAudio(refAudioFilePath, 30)
+	ref, err := c.UploadReference(ctx, refAudioFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.VoiceCloneWithReference(ctx, prompt, *ref)
+}
+
+// UploadReference crops refAudioFilePath to 30 seconds, transcribes the
+// cropped clip if a Transcriber is set and uploads it to S3. The returned
+// reference can be passed to VoiceCloneWithReference any number of times.
+// A failed transcription is logged and leaves Text empty.
+func (c *Client) UploadReference(ctx context.Context, refAudioFilePath string) (*Reference, error) {
+	return c.UploadReferenceRanges(ctx, refAudioFilePath, nil)
+}
+
+// UploadReferenceRanges is UploadReference with the reference joined from
+// ranges of refAudioFilePath, which should already be validated. No ranges
+// takes the first MaxReferenceSeconds.
+func (c *Client) UploadReferenceRanges(ctx context.Context, refAudioFilePath string, ranges audio.Ranges) (*Reference, error) {
+	if len(ranges) == 0 {
+		ranges = audio.Ranges{{Start: 0, End: MaxReferenceSeconds}}
+	}
+
+	// First crop the audio to the ranges and re-encode to reduce size
+	log.Printf("Cropping and compressing audio to %s...", ranges)
+	croppedFilePath, err := c.CropAndCompressAudio(ctx, refAudioFilePath, ranges)
 	if err != nil {
 		log.Printf("Error cropping audio: %v", err)
 		return nil, fmt.Errorf("error cropping audio: %w", err)
@@ -83,82 +174,49 @@ func (c *Client) VoiceClone(prompt, refAudioFilePath string) ([]byte, error) {
 	defer os.Remove(croppedFilePath) // Clean up the temporary cropped file
 	log.Printf("Audio cropped successfully: %s", croppedFilePath)
 
+	ref := &Reference{}
+	ref.Text = c.transcribeReference(ctx, croppedFilePath)
+
 	// Upload cropped file to S3 and get URL
 	log.Printf("Uploading audio to S3...")
-	refAudioURL, err := c.UploadToS3(croppedFilePath)
+	refAudioURL, err := c.UploadToS3(ctx, croppedFilePath)
 	if err != nil {
 		log.Printf("Error uploading audio to S3: %v", err)
 		return nil, fmt.Errorf("error uploading audio to S3: %w", err)
 	}
 	log.Printf("S3 URL created: %s", refAudioURL)
 
-	payload := Request{
-		Prompt:           prompt,
-		ReferenceAudioURL: refAudioURL,
-	}
-
-	log.Printf("Marshalling request payload...")
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		log.Printf("Error marshalling payload: %v", err)
-		return nil, fmt.Errorf("error marshalling payload: %w", err)
-	}
-	log.Printf("Payload size: %d bytes", len(jsonPayload))
-
-	log.Printf("Creating HTTP request to: %s", c.BaseURL)
-	req, err := http.NewRequest("POST", c.BaseURL, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		return nil, fmt.Errorf("error creating request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Key %s", c.APIKey))
-	log.Printf("Using API key: %s...", c.APIKey[:8]) // Log only first 8 chars for security
+	ref.URL = refAudioURL
+	return ref, nil
+}
 
-	log.Printf("Sending request to Zonos API...")
-	client := &http.Client{
-		Timeout: 60 * time.Second, // 60 second timeout for voice cloning API
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("Error sending request: %v", err)
-		return nil, fmt.Errorf("error sending request: %w", err)
+// transcribeReference returns what is said in the cropped reference, or ""
+// without a Transcriber or if transcription fails
+func (c *Client) transcribeReference(ctx context.Context, croppedFilePath string) string {
+	if c.Transcriber == nil {
+		return ""
 	}
-	defer resp.Body.Close()
 
-	log.Printf("Received response with status code: %d", resp.StatusCode)
-	body, err := io.ReadAll(resp.Body)
+	log.Printf("Transcribing reference audio...")
+	transcript, err := c.Transcriber.Transcribe(ctx, croppedFilePath)
 	if err != nil {
-		log.Printf("Error reading response body: %v", err)
-		return nil, fmt.Errorf("error reading response body: %w", err)
+		log.Printf("Error transcribing reference audio: %v", err)
+		return ""
 	}
-	log.Printf("Response body length: %d bytes", len(body))
+	log.Printf("Reference transcript: %q", transcript.Text)
+	return transcript.Text
+}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("API request failed with status code: %d", resp.StatusCode)
-		log.Printf("Response body: %s", string(body))
-		return nil, fmt.Errorf("API request failed with status code: %d\nBody: %s", resp.StatusCode, body)
-	}
+func (c *Client) downloadAudio(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.DownloadTimeout)
+	defer cancel()
 
-	log.Printf("Parsing response JSON...")
-	var response Response
-	err = json.Unmarshal(body, &response)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		log.Printf("Error unmarshalling response: %v", err)
-		log.Printf("Response body: %s", string(body))
-		return nil, fmt.Errorf("error unmarshalling response: %w", err)
+		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
-	log.Printf("Downloading generated audio from: %s", response.Audio.URL)
-	return c.downloadAudio(response.Audio.URL)
-}
-
-func (c *Client) downloadAudio(url string) ([]byte, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second, // 30 second timeout for audio download
-	}
-	resp, err := client.Get(url)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error downloading audio: %w", err)
 	}
@@ -177,13 +235,28 @@ func (c *Client) SaveAudioFile(audio []byte, filename string) error {
 
 
 
-func (c *Client) VoiceCloneWithURL(prompt, refAudioURL string) ([]byte, error) {
-	log.Printf("Starting Zonos voice cloning with pre-processed URL: %s", refAudioURL)
+// VoiceCloneWithURL generates from an uploaded reference without a transcript.
+func (c *Client) VoiceCloneWithURL(ctx context.Context, prompt, refAudioURL string) ([]byte, error) {
+	return c.VoiceCloneWithReference(ctx, prompt, Reference{URL: refAudioURL})
+}
+
+// VoiceCloneWithReference generates speech from an uploaded reference. Its
+// transcript is sent along if the model takes one.
+func (c *Client) VoiceCloneWithReference(ctx context.Context, prompt string, ref Reference) ([]byte, error) {
+	refAudioURL := ref.URL
+	log.Printf("Starting %s voice cloning with pre-processed URL: %s", c.Model, refAudioURL)
 
-	payload := Request{
-		Prompt:           prompt,
+	var payload interface{} = Request{
+		Prompt:            prompt,
 		ReferenceAudioURL: refAudioURL,
 	}
+	if c.Model == ModelDia {
+		payload = diaRequest{
+			Text:        diaSpeakerText(prompt),
+			RefAudioURL: refAudioURL,
+			RefText:     diaSpeakerText(ref.Text),
+		}
+	}
 
 	log.Printf("Marshalling request payload...")
 	jsonPayload, err := json.Marshal(payload)
@@ -193,8 +266,11 @@ func (c *Client) VoiceCloneWithURL(prompt, refAudioURL string) ([]byte, error) {
 	}
 	log.Printf("Payload size: %d bytes", len(jsonPayload))
 
+	genCtx, cancel := context.WithTimeout(ctx, c.GenerateTimeout)
+	defer cancel()
+
 	log.Printf("Creating HTTP request to: %s", c.BaseURL)
-	req, err := http.NewRequest("POST", c.BaseURL, bytes.NewBuffer(jsonPayload))
+	req, err := http.NewRequestWithContext(genCtx, "POST", c.BaseURL, bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		log.Printf("Error creating request: %v", err)
 		return nil, fmt.Errorf("error creating request: %w", err)
@@ -205,10 +281,7 @@ func (c *Client) VoiceCloneWithURL(prompt, refAudioURL string) ([]byte, error) {
 	log.Printf("Using API key: %s...", c.APIKey[:8])
 
 	log.Printf("Sending request to Zonos API...")
-	client := &http.Client{
-		Timeout: 60 * time.Second, // 60 second timeout for voice cloning API
-	}
-	resp, err := client.Do(req)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		log.Printf("Error sending request: %v", err)
 		return nil, fmt.Errorf("error sending request: %w", err)
@@ -239,22 +312,43 @@ func (c *Client) VoiceCloneWithURL(prompt, refAudioURL string) ([]byte, error) {
 	}
 
 	log.Printf("Downloading generated audio from: %s", response.Audio.URL)
-	return c.downloadAudio(response.Audio.URL)
+	return c.downloadAudio(ctx, response.Audio.URL)
 }
 
-func (c *Client) CropAndCompressAudio(inputPath string, durationSeconds int) (string, error) {
+// CropAndCompressAudio re-encodes ranges of inputPath, joined in order, next
+// to it. Each call writes its own file, so the same input can be cropped
+// differently at once. The half-written output is removed if ffmpeg fails or
+// ctx is cancelled.
+func (c *Client) CropAndCompressAudio(ctx context.Context, inputPath string, ranges audio.Ranges) (string, error) {
 	// Create output path with _compressed suffix
 	dir := filepath.Dir(inputPath)
 	base := filepath.Base(inputPath)
 	name := base[:len(base)-len(filepath.Ext(base))]
-	outputPath := filepath.Join(dir, fmt.Sprintf("%s_compressed.mp3", name))
+	outputFile, err := os.CreateTemp(dir, name+"_compressed_*.mp3")
+	if err != nil {
+		return "", fmt.Errorf("error creating cropped file: %w", err)
+	}
+	outputFile.Close()
+	outputPath := outputFile.Name()
+
+	log.Printf("Cropping and compressing audio: %s -> %s (%s)", inputPath, outputPath, ranges)
+
+	// Wait for a free ffmpeg slot
+	release, err := workerpool.FFmpeg().Acquire(ctx)
+	if err != nil {
+		os.Remove(outputPath)
+		return "", fmt.Errorf("waiting for ffmpeg slot: %w", err)
+	}
+	defer release()
 
-	log.Printf("Cropping and compressing audio: %s -> %s (%d seconds)", inputPath, outputPath, durationSeconds)
+	ctx, cancel := context.WithTimeout(ctx, c.CropTimeout)
+	defer cancel()
 
 	// Use ffmpeg to crop and compress the audio with better quality for voice cloning
-	cmd := exec.Command("ffmpeg",
+	cmd := exec.CommandContext(ctx, "ffmpeg",
 		"-i", inputPath,
-		"-t", fmt.Sprintf("%d", durationSeconds),
+		"-filter_complex", ranges.Filtergraph(),
+		"-map", "[out]",
 		"-acodec", "mp3",        // Ensure MP3 encoding
 		"-ab", "128k",           // Higher bitrate for better quality
 		"-ar", "44100",          // Standard sample rate for better quality
@@ -262,10 +356,15 @@ func (c *Client) CropAndCompressAudio(inputPath string, durationSeconds int) (st
 		"-q:a", "2",             // High quality setting (0-9, lower is better)
 		"-y",                    // Overwrite output file
 		outputPath)
+	cmd.WaitDelay = 5 * time.Second
 
 	// Capture ffmpeg output for debugging
 	output, err := cmd.CombinedOutput()
 	if err != nil {
+		os.Remove(outputPath)
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("ffmpeg stopped: %w", ctx.Err())
+		}
 		log.Printf("FFmpeg command failed: %s", string(output))
 		return "", fmt.Errorf("error running ffmpeg: %w (output: %s)", err, string(output))
 	}
@@ -283,7 +382,7 @@ func (c *Client) CropAndCompressAudio(inputPath string, durationSeconds int) (st
 	return outputPath, nil
 }
 
-func (c *Client) UploadToS3(filePath string) (string, error) {
+func (c *Client) UploadToS3(ctx context.Context, filePath string) (string, error) {
 	if c.S3Client == nil {
 		return "", fmt.Errorf("S3 client not initialized")
 	}
@@ -295,8 +394,11 @@ func (c *Client) UploadToS3(filePath string) (string, error) {
 
 	log.Printf("Uploading file %s to S3 bucket %s as %s", filePath, c.S3Bucket, objectName)
 
+	ctx, cancel := context.WithTimeout(ctx, c.UploadTimeout)
+	defer cancel()
+
 	// Upload file to S3
-	_, err := c.S3Client.FPutObject(context.Background(), c.S3Bucket, objectName, filePath, minio.PutObjectOptions{
+	_, err := c.S3Client.FPutObject(ctx, c.S3Bucket, objectName, filePath, minio.PutObjectOptions{
 		ContentType: "audio/mp3",
 	})
 	if err != nil {
@@ -308,4 +410,13 @@ func (c *Client) UploadToS3(filePath string) (string, error) {
 	log.Printf("File uploaded successfully, public URL: %s", publicURL)
 
 	return publicURL, nil
-}
\ No newline at end of file
+}
+
+// diaSpeakerText marks text as spoken by Dia's first speaker unless it
+// already names speakers
+func diaSpeakerText(text string) string {
+	if text == "" || strings.HasPrefix(text, "[S") {
+		return text
+	}
+	return "[S1] " + text
+}